@@ -0,0 +1,246 @@
+package algo
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Same as Redis, bitmaps are strings where bit 0 is the most significant bit of the first byte. They are
+// passed as byte slices, so that bitmaps stored as bytes are read without copies.
+
+const (
+	BitmapAnd = iota
+	BitmapOr
+	BitmapXor
+	BitmapNot
+)
+
+// bitmapWord loads the 8 bytes at `i` as a big-endian word, so that leading bits of the word
+// are leading bits of the bitmap
+func bitmapWord(s []byte, i int64) uint64 {
+	_ = s[i+7]
+	return uint64(s[i])<<56 | uint64(s[i+1])<<48 | uint64(s[i+2])<<40 | uint64(s[i+3])<<32 |
+		uint64(s[i+4])<<24 | uint64(s[i+5])<<16 | uint64(s[i+6])<<8 | uint64(s[i+7])
+}
+
+// BitmapGetBit returns the bit at `offset`, where bits beyond the bitmap are 0
+func BitmapGetBit(s []byte, offset int64) int {
+	if offset/8 >= int64(len(s)) {
+		return 0
+	}
+	return int(s[offset/8]>>(7-offset%8)) & 1
+}
+
+// BitmapSetBit sets the bit at `offset` in `b`, which must be long enough to hold it
+func BitmapSetBit(b []byte, offset int64, bit int) {
+	mask := byte(1) << (7 - offset%8)
+	if bit == 1 {
+		b[offset/8] |= mask
+	} else {
+		b[offset/8] &^= mask
+	}
+}
+
+// BitmapCount counts the set bits between `startBit` and `endBit` inclusively, which must be
+// within the bitmap. Whole words are counted at once.
+func BitmapCount(s []byte, startBit int64, endBit int64) int {
+	if startBit > endBit {
+		return 0
+	}
+	first, last := startBit/8, endBit/8
+	firstMask := byte(0xff >> (startBit % 8))
+	lastMask := byte(0xff << (7 - endBit%8))
+	if first == last {
+		return bits.OnesCount8(s[first] & firstMask & lastMask)
+	}
+
+	n := bits.OnesCount8(s[first]&firstMask) + bits.OnesCount8(s[last]&lastMask)
+	i := first + 1
+	for ; i+8 <= last; i += 8 {
+		n += bits.OnesCount64(bitmapWord(s, i))
+	}
+	for ; i < last; i++ {
+		n += bits.OnesCount8(s[i])
+	}
+	return n
+}
+
+// BitmapPos returns the position of the first bit equal to `bit` between `startBit` and `endBit`
+// inclusively, which must be within the bitmap, or -1 if there is none
+func BitmapPos(s []byte, bit int, startBit int64, endBit int64) int64 {
+	// Looking for a clear bit is looking for a set bit in the complement
+	var flip byte
+	var flipWord uint64
+	if bit == 0 {
+		flip = 0xff
+		flipWord = ^uint64(0)
+	}
+
+	for pos := startBit; pos <= endBit; {
+		i := pos / 8
+
+		// Skip a whole word if it has no such bit
+		if pos%8 == 0 && pos+63 <= endBit {
+			w := bitmapWord(s, i) ^ flipWord
+			if w != 0 {
+				return pos + int64(bits.LeadingZeros64(w))
+			}
+			pos += 64
+			continue
+		}
+
+		b := (s[i] ^ flip) & byte(0xff>>(pos%8))
+		if i == endBit/8 {
+			b &= byte(0xff << (7 - endBit%8))
+		}
+		if b != 0 {
+			return i*8 + int64(bits.LeadingZeros8(b))
+		}
+		pos = (i + 1) * 8
+	}
+	return -1
+}
+
+// BitmapOp combines bitmaps with a bitwise operation. Shorter bitmaps are zero-extended to the
+// length of the longest one, and BitmapNot only takes one bitmap.
+func BitmapOp(op int, srcs [][]byte) []byte {
+	size := 0
+	for _, s := range srcs {
+		size = max(size, len(s))
+	}
+	res := make([]byte, size)
+
+	if op == BitmapNot {
+		for i := 0; i < len(srcs[0]); i++ {
+			res[i] = ^srcs[0][i]
+		}
+		return res
+	}
+
+	copy(res, srcs[0])
+	if op == BitmapAnd {
+		// Missing bytes are zeros
+		for _, s := range srcs[1:] {
+			for i := len(s); i < size; i++ {
+				res[i] = 0
+			}
+		}
+	}
+	for _, s := range srcs[1:] {
+		for i := 0; i < len(s); i++ {
+			switch op {
+			case BitmapAnd:
+				res[i] &= s[i]
+			case BitmapOr:
+				res[i] |= s[i]
+			case BitmapXor:
+				res[i] ^= s[i]
+			}
+		}
+	}
+	return res
+}
+
+// Overflow behaviors of bitfields, the same as Redis's BITFIELD
+const (
+	BitfieldWrap = iota
+	BitfieldSat
+	BitfieldFail
+)
+
+// BitmapGetField returns the `width` bits at `offset` as an unsigned integer, where bits beyond the
+// bitmap are 0
+func BitmapGetField(b []byte, offset int64, width int) uint64 {
+	var v uint64
+	for i := int64(0); i < int64(width); i++ {
+		pos := offset + i
+		v <<= 1
+		if pos/8 < int64(len(b)) {
+			v |= uint64(b[pos/8]>>(7-pos%8)) & 1
+		}
+	}
+	return v
+}
+
+// BitmapGetSignedField returns the `width` bits at `offset` as a two's complement integer
+func BitmapGetSignedField(b []byte, offset int64, width int) int64 {
+	v := BitmapGetField(b, offset, width)
+	if width < 64 && v&(1<<(width-1)) != 0 {
+		v |= ^uint64(0) << width
+	}
+	return int64(v)
+}
+
+// BitmapSetField sets the `width` bits at `offset` to the lowest bits of `v`. `b` must be long enough
+// to hold them.
+func BitmapSetField(b []byte, offset int64, width int, v uint64) {
+	for i := int64(0); i < int64(width); i++ {
+		BitmapSetBit(b, offset+i, int(v>>(int64(width)-1-i))&1)
+	}
+}
+
+// BitfieldIncrUnsigned adds `incr` to an unsigned field of `width` bits, which is at most 63. The
+// result is false if the field overflows with BitfieldFail.
+func BitfieldIncrUnsigned(value uint64, incr int64, width int, overflow int) (uint64, bool) {
+	maxVal := uint64(1)<<width - 1
+	maxIncr := int64(maxVal - value)
+	minIncr := -int64(value)
+
+	if value > maxVal || (incr > 0 && incr > maxIncr) {
+		if overflow == BitfieldSat {
+			return maxVal, true
+		}
+	} else if incr < 0 && incr < minIncr {
+		if overflow == BitfieldSat {
+			return 0, true
+		}
+	} else {
+		return value + uint64(incr), true
+	}
+
+	if overflow == BitfieldFail {
+		return 0, false
+	}
+	return (value + uint64(incr)) & maxVal, true
+}
+
+// BitfieldIncrSigned adds `incr` to a signed field of `width` bits, which is at most 64. The result
+// is false if the field overflows with BitfieldFail.
+func BitfieldIncrSigned(value int64, incr int64, width int, overflow int) (int64, bool) {
+	maxVal := int64(math.MaxInt64)
+	if width < 64 {
+		maxVal = int64(1)<<(width-1) - 1
+	}
+	minVal := -maxVal - 1
+	maxIncr := maxVal - value
+	minIncr := minVal - value
+
+	// Same as Redis, the increments are compared without overflowing 64 bits
+	if value > maxVal || (width != 64 && incr > maxIncr) || (value >= 0 && incr > 0 && incr > maxIncr) {
+		if overflow == BitfieldSat {
+			return maxVal, true
+		}
+	} else if value < minVal || (width != 64 && incr < minIncr) || (value < 0 && incr < 0 && incr < minIncr) {
+		if overflow == BitfieldSat {
+			return minVal, true
+		}
+	} else {
+		return value + incr, true
+	}
+
+	if overflow == BitfieldFail {
+		return 0, false
+	}
+
+	// Wrap around by sign-extending the lowest bits
+	res := uint64(value) + uint64(incr)
+	if width < 64 {
+		mask := ^uint64(0) << width
+		if res&(1<<(width-1)) != 0 {
+			res |= mask
+		} else {
+			res &^= mask
+		}
+	}
+	return int64(res), true
+}
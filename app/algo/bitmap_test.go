@@ -0,0 +1,102 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitmapCount(t *testing.T) {
+	s := []byte("foobar")
+	assert.Equal(t, 26, BitmapCount(s, 0, 47))
+	assert.Equal(t, 6, BitmapCount(s, 8, 15))
+	assert.Equal(t, 17, BitmapCount(s, 5, 30))
+
+	// Compare against counting bit by bit, across word boundaries
+	b := make([]byte, 100)
+	rand.New(rand.NewSource(0)).Read(b)
+	s = b
+	for _, r := range [][2]int64{{0, 799}, {3, 797}, {9, 700}, {64, 127}, {100, 100}, {7, 8}} {
+		expected := 0
+		for i := r[0]; i <= r[1]; i++ {
+			expected += BitmapGetBit(s, i)
+		}
+		assert.Equal(t, expected, BitmapCount(s, r[0], r[1]), r)
+	}
+}
+
+func TestBitmapPos(t *testing.T) {
+	s := []byte("\xff\xf0\x00")
+	assert.Equal(t, int64(12), BitmapPos(s, 0, 0, 23))
+	assert.Equal(t, int64(0), BitmapPos(s, 1, 0, 23))
+	assert.Equal(t, int64(-1), BitmapPos(s, 1, 12, 23))
+	assert.Equal(t, int64(-1), BitmapPos(s, 0, 0, 11))
+	assert.Equal(t, int64(10), BitmapPos(s, 1, 10, 10))
+
+	// Long runs are skipped a word at a time
+	b := make([]byte, 40)
+	BitmapSetBit(b, 250, 1)
+	assert.Equal(t, int64(250), BitmapPos(b, 1, 0, 319))
+	assert.Equal(t, int64(250), BitmapPos(b, 1, 3, 250))
+	assert.Equal(t, int64(-1), BitmapPos(b, 1, 251, 319))
+	assert.Equal(t, 1, BitmapGetBit(b, 250))
+	BitmapSetBit(b, 250, 0)
+	assert.Equal(t, int64(-1), BitmapPos(b, 1, 0, 319))
+	assert.Equal(t, 0, BitmapGetBit(b, 1000))
+}
+
+func TestBitmapOp(t *testing.T) {
+	assert.Equal(t, []byte{0x01, 0x00}, BitmapOp(BitmapAnd, [][]byte{[]byte("\x0f\xff"), []byte("\x31")}))
+	assert.Equal(t, []byte{0x3f, 0xff}, BitmapOp(BitmapOr, [][]byte{[]byte("\x0f\xff"), []byte("\x31")}))
+	assert.Equal(t, []byte{0x3e, 0xff}, BitmapOp(BitmapXor, [][]byte{[]byte("\x0f\xff"), []byte("\x31")}))
+	assert.Equal(t, []byte{0xf0, 0x00}, BitmapOp(BitmapNot, [][]byte{[]byte("\x0f\xff")}))
+	assert.Equal(t, []byte{0x00, 0x00}, BitmapOp(BitmapAnd, [][]byte{nil, []byte("\x0f\xff")}))
+}
+
+func TestBitmapField(t *testing.T) {
+	b := make([]byte, 3)
+	BitmapSetField(b, 4, 12, 0xabc)
+	assert.Equal(t, []byte{0x0a, 0xbc, 0x00}, b)
+	assert.Equal(t, uint64(0xabc), BitmapGetField(b, 4, 12))
+	assert.Equal(t, int64(-1348), BitmapGetSignedField(b, 4, 12))
+	assert.Equal(t, uint64(0xbc0000), BitmapGetField(b, 8, 32)>>8)
+
+	BitmapSetField(b, 0, 8, 0x1ff)
+	assert.Equal(t, byte(0xff), b[0])
+	assert.Equal(t, int64(-1), BitmapGetSignedField(b, 0, 8))
+}
+
+func TestBitfieldIncr(t *testing.T) {
+	v, ok := BitfieldIncrUnsigned(250, 10, 8, BitfieldWrap)
+	assert.Equal(t, uint64(4), v)
+	assert.True(t, ok)
+	v, _ = BitfieldIncrUnsigned(250, 10, 8, BitfieldSat)
+	assert.Equal(t, uint64(255), v)
+	v, _ = BitfieldIncrUnsigned(5, -10, 8, BitfieldSat)
+	assert.Equal(t, uint64(0), v)
+	v, _ = BitfieldIncrUnsigned(5, -10, 8, BitfieldWrap)
+	assert.Equal(t, uint64(251), v)
+	_, ok = BitfieldIncrUnsigned(250, 10, 8, BitfieldFail)
+	assert.False(t, ok)
+	v, _ = BitfieldIncrUnsigned(1<<62, 1<<62, 63, BitfieldWrap)
+	assert.Equal(t, uint64(0), v)
+
+	s, ok := BitfieldIncrSigned(120, 10, 8, BitfieldWrap)
+	assert.Equal(t, int64(-126), s)
+	assert.True(t, ok)
+	s, _ = BitfieldIncrSigned(120, 10, 8, BitfieldSat)
+	assert.Equal(t, int64(127), s)
+	s, _ = BitfieldIncrSigned(-120, -10, 8, BitfieldSat)
+	assert.Equal(t, int64(-128), s)
+	_, ok = BitfieldIncrSigned(-120, -10, 8, BitfieldFail)
+	assert.False(t, ok)
+	s, _ = BitfieldIncrSigned(math.MaxInt64, 1, 64, BitfieldWrap)
+	assert.Equal(t, int64(math.MinInt64), s)
+	s, _ = BitfieldIncrSigned(math.MaxInt64, 1, 64, BitfieldSat)
+	assert.Equal(t, int64(math.MaxInt64), s)
+	s, ok = BitfieldIncrSigned(-5, 3, 64, BitfieldFail)
+	assert.Equal(t, int64(-2), s)
+	assert.True(t, ok)
+}
@@ -0,0 +1,156 @@
+package algo
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// Each layer added to a scalable Bloom filter halves the error rate, so that the overall error
+	// rate stays below the configured one
+	bloomTighteningRatio = 0.5
+	bloomHashSeed        = 0xc6a4a7935bd1e995
+
+	// Filters are bounded to 128MB of bits over all layers
+	BloomMaxBits = 1 << 30
+)
+
+var (
+	ErrBloomFull     = errors.New("non scaling filter is full")
+	ErrBloomTooLarge = errors.New("filter is too large")
+)
+
+type bloomLayer struct {
+	Bits      []uint64
+	NumBits   uint64
+	NumHashes int
+	Capacity  uint64
+	NumItems  uint64
+	ErrorRate float64
+}
+
+// m = -n * ln(p) / ln(2)^2 bits and k = m / n * ln(2) hashes are optimal for n items
+func bloomBitsPerItem(errorRate float64) float64 {
+	return -math.Log(errorRate) / (math.Ln2 * math.Ln2)
+}
+
+// bloomLayerFits returns true if a layer of this capacity and error rate has at most `maxBits` bits
+func bloomLayerFits(errorRate float64, capacity uint64, maxBits uint64) bool {
+	return math.Ceil(float64(capacity)*bloomBitsPerItem(errorRate)) <= float64(maxBits)
+}
+
+// BloomFilterFits returns true if the first layer of a filter is within `BloomMaxBits`
+func BloomFilterFits(errorRate float64, capacity uint64) bool {
+	return bloomLayerFits(errorRate, capacity, BloomMaxBits)
+}
+
+func makeBloomLayer(errorRate float64, capacity uint64) *bloomLayer {
+	bitsPerItem := bloomBitsPerItem(errorRate)
+	numBits := uint64(math.Ceil(float64(capacity) * bitsPerItem))
+	numBits = (numBits + 63) / 64 * 64
+
+	return &bloomLayer{
+		Bits:      make([]uint64, numBits/64),
+		NumBits:   numBits,
+		NumHashes: int(math.Ceil(math.Ln2 * bitsPerItem)),
+		Capacity:  capacity,
+		ErrorRate: errorRate,
+	}
+}
+
+// Bits are picked by double hashing, the i-th bit being h1 + i * h2
+func (l *bloomLayer) test(h1 uint64, h2 uint64) bool {
+	for i := 0; i < l.NumHashes; i++ {
+		pos := (h1 + uint64(i)*h2) % l.NumBits
+		if l.Bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *bloomLayer) set(h1 uint64, h2 uint64) {
+	for i := 0; i < l.NumHashes; i++ {
+		pos := (h1 + uint64(i)*h2) % l.NumBits
+		l.Bits[pos/64] |= 1 << (pos % 64)
+	}
+	l.NumItems++
+}
+
+// BloomFilter is a scalable Bloom filter. When the last layer reaches its capacity, a layer with
+// `Expansion` times the capacity and half the error rate is added. A filter with an expansion of 0
+// does not scale.
+type BloomFilter struct {
+	Layers    []*bloomLayer
+	Expansion int
+	NumItems  uint64
+}
+
+func MakeBloomFilter(errorRate float64, capacity uint64, expansion int) *BloomFilter {
+	return &BloomFilter{
+		Layers:    []*bloomLayer{makeBloomLayer(errorRate, capacity)},
+		Expansion: expansion,
+	}
+}
+
+func bloomHash(item []byte) (uint64, uint64) {
+	h1 := MurmurHash64A(item, bloomHashSeed)
+	return h1, MurmurHash64A(item, h1)
+}
+
+// Add adds an item, and returns false if it might have been added
+func (f *BloomFilter) Add(item []byte) (bool, error) {
+	h1, h2 := bloomHash(item)
+	for _, l := range f.Layers {
+		if l.test(h1, h2) {
+			return false, nil
+		}
+	}
+
+	last := f.Layers[len(f.Layers)-1]
+	if last.NumItems >= last.Capacity {
+		if f.Expansion == 0 {
+			return false, ErrBloomFull
+		}
+		// Layers stop growing once the filter would exceed `BloomMaxBits`
+		errorRate := last.ErrorRate * bloomTighteningRatio
+		if last.Capacity > math.MaxUint64/uint64(f.Expansion) ||
+			!bloomLayerFits(errorRate, last.Capacity*uint64(f.Expansion), BloomMaxBits-uint64(f.Size())*8) {
+			return false, ErrBloomTooLarge
+		}
+		last = makeBloomLayer(errorRate, last.Capacity*uint64(f.Expansion))
+		f.Layers = append(f.Layers, last)
+	}
+	last.set(h1, h2)
+	f.NumItems++
+	return true, nil
+}
+
+// Exists returns true if the item might have been added, or false if it definitely has not
+func (f *BloomFilter) Exists(item []byte) bool {
+	h1, h2 := bloomHash(item)
+	for _, l := range f.Layers {
+		if l.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capacity returns the number of items the filter holds before adding another layer
+func (f *BloomFilter) Capacity() uint64 {
+	var capacity uint64
+	for _, l := range f.Layers {
+		capacity += l.Capacity
+	}
+	return capacity
+}
+
+// Size returns the number of bytes of all layers
+func (f *BloomFilter) Size() int {
+	size := 0
+	for _, l := range f.Layers {
+		size += len(l.Bits) * 8
+	}
+	return size
+}
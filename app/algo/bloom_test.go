@@ -0,0 +1,57 @@
+package algo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	f := MakeBloomFilter(0.01, 1000, 2)
+	assert.Equal(t, 7, f.Layers[0].NumHashes)
+	assert.Equal(t, uint64(9600), f.Layers[0].NumBits)
+
+	added, err := f.Add([]byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, _ = f.Add([]byte("a"))
+	assert.False(t, added)
+	assert.True(t, f.Exists([]byte("a")))
+	assert.False(t, f.Exists([]byte("b")))
+
+	// Layers are added as the filter fills up, and the error rate stays bounded
+	for i := 0; i < 10000; i++ {
+		f.Add([]byte("item:" + strconv.Itoa(i)))
+	}
+	assert.Equal(t, 4, len(f.Layers))
+	assert.Equal(t, uint64(15000), f.Capacity())
+	for i := 0; i < 10000; i++ {
+		assert.True(t, f.Exists([]byte("item:"+strconv.Itoa(i))))
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Exists([]byte("other:" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 200)
+
+	// Non-scaling filters reject items once they are full
+	f = MakeBloomFilter(0.01, 10, 0)
+	for i := 0; i < 10; i++ {
+		_, err = f.Add([]byte(strconv.Itoa(i)))
+		assert.NoError(t, err)
+	}
+	_, err = f.Add([]byte("full"))
+	assert.Equal(t, ErrBloomFull, err)
+
+	// Scaling filters reject items once a new layer would be too large
+	assert.False(t, BloomFilterFits(0.01, 1<<62))
+	f = MakeBloomFilter(0.01, 1, 1<<62)
+	_, err = f.Add([]byte("a"))
+	assert.NoError(t, err)
+	_, err = f.Add([]byte("b"))
+	assert.Equal(t, ErrBloomTooLarge, err)
+	assert.Equal(t, 1, len(f.Layers))
+}
@@ -0,0 +1,147 @@
+package algo
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// Sketches are bounded to 128MB of counters, and counts are bounded so that they fit in integer replies
+const (
+	CountMinSketchMaxCounters = 1 << 24
+	CountMinSketchMaxCount    = math.MaxInt64
+)
+
+var (
+	ErrCountMinSketchDims     = errors.New("count-min sketches have different dimensions")
+	ErrCountMinSketchOverflow = errors.New("count-min sketch counter overflow")
+)
+
+// CountMinSketch estimates the counts of items with `Depth` rows of `Width` counters. Each row
+// hashes an item to one counter, and the estimate is the minimum of the counters, which never
+// underestimates the count.
+type CountMinSketch struct {
+	Width    uint64
+	Depth    uint64
+	Counters []uint64
+	Count    uint64
+}
+
+func MakeCountMinSketch(width uint64, depth uint64) *CountMinSketch {
+	return &CountMinSketch{
+		Width:    width,
+		Depth:    depth,
+		Counters: make([]uint64, width*depth),
+	}
+}
+
+// CountMinSketchFits returns true if a sketch of these dimensions is not empty and within `CountMinSketchMaxCounters`
+func CountMinSketchFits(width uint64, depth uint64) bool {
+	return width > 0 && depth > 0 && width <= CountMinSketchMaxCounters/depth
+}
+
+// CountMinSketchDimsByProb returns the dimensions of a sketch whose estimates exceed counts by at most `errorRate`
+// of the total count, with a probability of failure of `prob`. Dimensions that are too large to convert to integers
+// are clamped, so that they do not fit.
+func CountMinSketchDimsByProb(errorRate float64, prob float64) (uint64, uint64) {
+	width := math.Min(math.Ceil(2/errorRate), CountMinSketchMaxCounters+1)
+	depth := math.Min(math.Ceil(math.Log10(prob)/math.Log10(0.5)), CountMinSketchMaxCounters+1)
+	return uint64(width), max(uint64(depth), 1)
+}
+
+func MakeCountMinSketchByProb(errorRate float64, prob float64) *CountMinSketch {
+	return MakeCountMinSketch(CountMinSketchDimsByProb(errorRate, prob))
+}
+
+func (s *CountMinSketch) index(item []byte, row uint64) uint64 {
+	return row*s.Width + MurmurHash64A(item, row)%s.Width
+}
+
+// IncrBy increments the count of an item, and returns the estimated count after the increment. Nothing is
+// incremented if a counter would exceed `CountMinSketchMaxCount`.
+func (s *CountMinSketch) IncrBy(item []byte, incr uint64) (uint64, error) {
+	if incr > CountMinSketchMaxCount-s.Count {
+		return 0, ErrCountMinSketchOverflow
+	}
+	for row := uint64(0); row < s.Depth; row++ {
+		if incr > CountMinSketchMaxCount-s.Counters[s.index(item, row)] {
+			return 0, ErrCountMinSketchOverflow
+		}
+	}
+
+	minCount := uint64(math.MaxUint64)
+	for row := uint64(0); row < s.Depth; row++ {
+		i := s.index(item, row)
+		s.Counters[i] += incr
+		minCount = min(minCount, s.Counters[i])
+	}
+	s.Count += incr
+	return minCount, nil
+}
+
+// MultiIncrBy increments the counts of items in order, and returns the estimated count after each increment.
+// Counters never exceed the total count, so nothing is incremented if the total count would exceed
+// `CountMinSketchMaxCount`.
+func (s *CountMinSketch) MultiIncrBy(items [][]byte, incrs []uint64) ([]uint64, error) {
+	var total uint64
+	for _, incr := range incrs {
+		if incr > CountMinSketchMaxCount-s.Count-total {
+			return nil, ErrCountMinSketchOverflow
+		}
+		total += incr
+	}
+
+	counts := make([]uint64, 0, len(items))
+	for i, item := range items {
+		count, _ := s.IncrBy(item, incrs[i])
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// Query returns the estimated count of an item
+func (s *CountMinSketch) Query(item []byte) uint64 {
+	minCount := uint64(math.MaxUint64)
+	for row := uint64(0); row < s.Depth; row++ {
+		minCount = min(minCount, s.Counters[s.index(item, row)])
+	}
+	return minCount
+}
+
+// weightedAdd returns sum + c * weight, or false if it exceeds `CountMinSketchMaxCount`
+func weightedAdd(sum uint64, c uint64, weight uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(c, weight)
+	if hi != 0 || lo > CountMinSketchMaxCount-sum {
+		return 0, false
+	}
+	return sum + lo, true
+}
+
+// Merge sets the sketch to the weighted sum of `sketches`, which must have the same dimensions. The sketch is
+// unchanged if a counter of the sum would exceed `CountMinSketchMaxCount`.
+func (s *CountMinSketch) Merge(sketches []*CountMinSketch, weights []uint64) error {
+	for _, other := range sketches {
+		if other.Width != s.Width || other.Depth != s.Depth {
+			return ErrCountMinSketchDims
+		}
+	}
+
+	counters := make([]uint64, len(s.Counters))
+	var (
+		count uint64
+		ok    bool
+	)
+	for n, other := range sketches {
+		for i, c := range other.Counters {
+			if counters[i], ok = weightedAdd(counters[i], c, weights[n]); !ok {
+				return ErrCountMinSketchOverflow
+			}
+		}
+		if count, ok = weightedAdd(count, other.Count, weights[n]); !ok {
+			return ErrCountMinSketchOverflow
+		}
+	}
+	s.Counters = counters
+	s.Count = count
+	return nil
+}
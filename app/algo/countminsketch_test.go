@@ -0,0 +1,75 @@
+package algo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	s := MakeCountMinSketchByProb(0.001, 0.01)
+	assert.Equal(t, uint64(2000), s.Width)
+	assert.Equal(t, uint64(7), s.Depth)
+
+	count, err := s.IncrBy([]byte("a"), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), count)
+	count, _ = s.IncrBy([]byte("a"), 1)
+	assert.Equal(t, uint64(6), count)
+	assert.Equal(t, uint64(0), s.Query([]byte("b")))
+
+	// Estimates never undercount, and overcount by a fraction of the total count
+	for i := 0; i < 10000; i++ {
+		s.IncrBy([]byte("item:"+strconv.Itoa(i%100)), 1)
+	}
+	for i := 0; i < 100; i++ {
+		count := s.Query([]byte("item:" + strconv.Itoa(i)))
+		assert.GreaterOrEqual(t, count, uint64(100))
+		assert.LessOrEqual(t, count, uint64(111))
+	}
+
+	other := MakeCountMinSketch(2000, 7)
+	other.IncrBy([]byte("a"), 4)
+	merged := MakeCountMinSketch(2000, 7)
+	assert.NoError(t, merged.Merge([]*CountMinSketch{s, other}, []uint64{1, 2}))
+	assert.Equal(t, uint64(14), merged.Query([]byte("a")))
+	assert.Equal(t, uint64(10014), merged.Count)
+	assert.Equal(t, ErrCountMinSketchDims, merged.Merge([]*CountMinSketch{MakeCountMinSketch(10, 7)}, []uint64{1}))
+}
+
+func TestCountMinSketchLimits(t *testing.T) {
+	assert.True(t, CountMinSketchFits(1<<12, 1<<12))
+	assert.False(t, CountMinSketchFits(1<<12, 1<<12+1))
+	assert.False(t, CountMinSketchFits(1<<62, 4))
+	assert.False(t, CountMinSketchFits(0, 4))
+
+	width, depth := CountMinSketchDimsByProb(0.001, 0.01)
+	assert.Equal(t, []uint64{2000, 7}, []uint64{width, depth})
+	width, _ = CountMinSketchDimsByProb(1e-300, 0.01)
+	assert.False(t, CountMinSketchFits(width, 1))
+
+	// Counters never wrap around, and sketches are unchanged by failed increments and merges
+	s := MakeCountMinSketch(10, 2)
+	_, err := s.IncrBy([]byte("a"), CountMinSketchMaxCount)
+	assert.NoError(t, err)
+	_, err = s.IncrBy([]byte("b"), 1)
+	assert.Equal(t, ErrCountMinSketchOverflow, err)
+	assert.Equal(t, uint64(CountMinSketchMaxCount), s.Count)
+	assert.Equal(t, uint64(0), s.Query([]byte("b")))
+
+	multi := MakeCountMinSketch(10, 2)
+	_, err = multi.MultiIncrBy([][]byte{[]byte("a"), []byte("b")}, []uint64{1, CountMinSketchMaxCount})
+	assert.Equal(t, ErrCountMinSketchOverflow, err)
+	assert.Equal(t, uint64(0), multi.Query([]byte("a")))
+	counts, err := multi.MultiIncrBy([][]byte{[]byte("a"), []byte("a")}, []uint64{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3}, counts)
+
+	merged := MakeCountMinSketch(10, 2)
+	assert.Equal(t, ErrCountMinSketchOverflow, merged.Merge([]*CountMinSketch{s}, []uint64{2}))
+	assert.Equal(t, ErrCountMinSketchOverflow, merged.Merge([]*CountMinSketch{s, s}, []uint64{1, 1}))
+	assert.Equal(t, uint64(0), merged.Count)
+	assert.NoError(t, merged.Merge([]*CountMinSketch{s}, []uint64{1}))
+	assert.Equal(t, uint64(CountMinSketchMaxCount), merged.Query([]byte("a")))
+}
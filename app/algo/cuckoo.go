@@ -0,0 +1,219 @@
+package algo
+
+import (
+	"errors"
+	"math/bits"
+	"math/rand"
+)
+
+// Filters are bounded to 128MB of slots over all layers
+const CuckooMaxSlots = 1 << 27
+
+var (
+	ErrCuckooFull     = errors.New("filter is full")
+	ErrCuckooTooLarge = errors.New("filter is too large")
+)
+
+type cuckooLayer struct {
+	// Buckets of fingerprints are stored side by side, where 0 is an empty slot
+	Slots      []uint8
+	NumBuckets uint64
+}
+
+func makeCuckooLayer(numBuckets uint64, bucketSize int) *cuckooLayer {
+	return &cuckooLayer{
+		Slots:      make([]uint8, numBuckets*uint64(bucketSize)),
+		NumBuckets: numBuckets,
+	}
+}
+
+// CuckooFilter stores 8-bit fingerprints of items in one of two buckets, and supports deletion.
+// The false positive rate is about 2 * BucketSize / 255. When an item cannot be inserted after
+// `MaxIterations` evictions, a layer with `Expansion` times the buckets is added. A filter with an
+// expansion of 0 does not scale.
+type CuckooFilter struct {
+	Layers        []*cuckooLayer
+	BucketSize    int
+	MaxIterations int
+	Expansion     int
+	NumItems      uint64
+	NumDeletes    uint64
+}
+
+func cuckooNextPowerOf2(n uint64) uint64 {
+	if n <= 1 {
+		return n
+	}
+	return 1 << bits.Len64(n-1)
+}
+
+// CuckooFilterFits returns true if the first layer of a filter is within `CuckooMaxSlots`
+func CuckooFilterFits(capacity uint64, bucketSize int) bool {
+	if capacity > CuckooMaxSlots {
+		return false
+	}
+	numBuckets := max((capacity+uint64(bucketSize)-1)/uint64(bucketSize), 1)
+	return cuckooNextPowerOf2(numBuckets)*uint64(bucketSize) <= CuckooMaxSlots
+}
+
+// MakeCuckooFilter creates a filter of at least `capacity` slots. The numbers of buckets are rounded
+// up to powers of 2, so that the alternate bucket of an alternate bucket is the original one.
+func MakeCuckooFilter(capacity uint64, bucketSize int, maxIterations int, expansion int) *CuckooFilter {
+	numBuckets := max((capacity+uint64(bucketSize)-1)/uint64(bucketSize), 1)
+
+	return &CuckooFilter{
+		Layers:        []*cuckooLayer{makeCuckooLayer(cuckooNextPowerOf2(numBuckets), bucketSize)},
+		BucketSize:    bucketSize,
+		MaxIterations: maxIterations,
+		Expansion:     int(cuckooNextPowerOf2(uint64(expansion))),
+	}
+}
+
+func cuckooHash(item []byte) (uint64, uint8) {
+	h := MurmurHash64A(item, 0)
+	return h, uint8(h%255 + 1)
+}
+
+func (l *cuckooLayer) altIndex(i uint64, fp uint8) uint64 {
+	return (i ^ (uint64(fp) * 0x5bd1e995)) & (l.NumBuckets - 1)
+}
+
+func (l *cuckooLayer) bucket(i uint64, bucketSize int) []uint8 {
+	return l.Slots[i*uint64(bucketSize) : (i+1)*uint64(bucketSize)]
+}
+
+func (l *cuckooLayer) insertAt(i uint64, fp uint8, bucketSize int) bool {
+	b := l.bucket(i, bucketSize)
+	for j := range b {
+		if b[j] == 0 {
+			b[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// count counts the fingerprint in both buckets of the item
+func (l *cuckooLayer) count(h uint64, fp uint8, bucketSize int) int {
+	i1 := h & (l.NumBuckets - 1)
+	i2 := l.altIndex(i1, fp)
+
+	n := 0
+	for _, slot := range l.bucket(i1, bucketSize) {
+		if slot == fp {
+			n++
+		}
+	}
+	if i2 != i1 {
+		for _, slot := range l.bucket(i2, bucketSize) {
+			if slot == fp {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// kick evicts fingerprints to their alternate buckets to make room. Evictions are rolled back if
+// there is still no room after `maxIterations` evictions.
+func (l *cuckooLayer) kick(i uint64, fp uint8, bucketSize int, maxIterations int) bool {
+	type eviction struct {
+		pos int
+		fp  uint8
+	}
+	evictions := make([]eviction, 0, maxIterations)
+
+	for n := 0; n < maxIterations; n++ {
+		pos := int(i)*bucketSize + rand.Intn(bucketSize)
+		evictions = append(evictions, eviction{pos: pos, fp: l.Slots[pos]})
+		fp, l.Slots[pos] = l.Slots[pos], fp
+
+		i = l.altIndex(i, fp)
+		if l.insertAt(i, fp, bucketSize) {
+			return true
+		}
+	}
+
+	for n := len(evictions) - 1; n >= 0; n-- {
+		l.Slots[evictions[n].pos] = evictions[n].fp
+	}
+	return false
+}
+
+// Add adds an item. An item can be added multiple times.
+func (f *CuckooFilter) Add(item []byte) error {
+	h, fp := cuckooHash(item)
+
+	// Fill empty slots of any layer first
+	for _, l := range f.Layers {
+		i1 := h & (l.NumBuckets - 1)
+		if l.insertAt(i1, fp, f.BucketSize) || l.insertAt(l.altIndex(i1, fp), fp, f.BucketSize) {
+			f.NumItems++
+			return nil
+		}
+	}
+
+	last := f.Layers[len(f.Layers)-1]
+	if !last.kick(h&(last.NumBuckets-1), fp, f.BucketSize, f.MaxIterations) {
+		if f.Expansion == 0 {
+			return ErrCuckooFull
+		}
+		// Layers stop growing once the filter would exceed `CuckooMaxSlots`
+		slots := uint64(f.Expansion) * uint64(f.BucketSize)
+		if last.NumBuckets > (CuckooMaxSlots-f.Capacity())/slots {
+			return ErrCuckooTooLarge
+		}
+		last = makeCuckooLayer(last.NumBuckets*uint64(f.Expansion), f.BucketSize)
+		f.Layers = append(f.Layers, last)
+		last.insertAt(h&(last.NumBuckets-1), fp, f.BucketSize)
+	}
+	f.NumItems++
+	return nil
+}
+
+// Delete deletes one occurrence of an item, and returns false if it is not found. Items that
+// have not been added might share fingerprints with added ones, so only added items should be deleted.
+func (f *CuckooFilter) Delete(item []byte) bool {
+	h, fp := cuckooHash(item)
+
+	// Newer layers are searched first
+	for n := len(f.Layers) - 1; n >= 0; n-- {
+		l := f.Layers[n]
+		i1 := h & (l.NumBuckets - 1)
+		for _, i := range []uint64{i1, l.altIndex(i1, fp)} {
+			b := l.bucket(i, f.BucketSize)
+			for j := range b {
+				if b[j] == fp {
+					b[j] = 0
+					f.NumItems--
+					f.NumDeletes++
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Count returns the number of times an item might have been added
+func (f *CuckooFilter) Count(item []byte) int {
+	h, fp := cuckooHash(item)
+	n := 0
+	for _, l := range f.Layers {
+		n += l.count(h, fp, f.BucketSize)
+	}
+	return n
+}
+
+func (f *CuckooFilter) Exists(item []byte) bool {
+	return f.Count(item) > 0
+}
+
+// Capacity returns the number of slots of all layers
+func (f *CuckooFilter) Capacity() uint64 {
+	var capacity uint64
+	for _, l := range f.Layers {
+		capacity += uint64(len(l.Slots))
+	}
+	return capacity
+}
@@ -0,0 +1,62 @@
+package algo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckooFilter(t *testing.T) {
+	f := MakeCuckooFilter(1000, 2, 20, 1)
+	assert.Equal(t, uint64(512), f.Layers[0].NumBuckets)
+
+	assert.NoError(t, f.Add([]byte("a")))
+	assert.NoError(t, f.Add([]byte("a")))
+	assert.Equal(t, 2, f.Count([]byte("a")))
+	assert.True(t, f.Exists([]byte("a")))
+	assert.False(t, f.Exists([]byte("b")))
+
+	assert.True(t, f.Delete([]byte("a")))
+	assert.Equal(t, 1, f.Count([]byte("a")))
+	assert.True(t, f.Delete([]byte("a")))
+	assert.False(t, f.Exists([]byte("a")))
+	assert.False(t, f.Delete([]byte("a")))
+
+	// Layers are added as the filter fills up, without losing items
+	for i := 0; i < 5000; i++ {
+		assert.NoError(t, f.Add([]byte("item:"+strconv.Itoa(i))))
+	}
+	assert.Greater(t, len(f.Layers), 1)
+	assert.Equal(t, uint64(5000), f.NumItems)
+	for i := 0; i < 5000; i++ {
+		assert.True(t, f.Exists([]byte("item:"+strconv.Itoa(i))))
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Exists([]byte("other:" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 10000*2*2*len(f.Layers)/255)
+
+	// Non-scaling filters reject items once they are full
+	f = MakeCuckooFilter(4, 2, 10, 0)
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = f.Add([]byte(strconv.Itoa(i)))
+	}
+	assert.Equal(t, ErrCuckooFull, err)
+	assert.Equal(t, uint64(4), f.NumItems)
+
+	// Scaling filters reject items once a new layer would be too large
+	assert.False(t, CuckooFilterFits(1<<62, 2))
+	assert.False(t, CuckooFilterFits(CuckooMaxSlots+1, 1))
+	f = MakeCuckooFilter(4, 1, 1, CuckooMaxSlots/4)
+	err = nil
+	for i := 0; i < 100 && err == nil; i++ {
+		err = f.Add([]byte(strconv.Itoa(i)))
+	}
+	assert.Equal(t, ErrCuckooTooLarge, err)
+	assert.Equal(t, 1, len(f.Layers))
+}
@@ -7,6 +7,15 @@ import (
 
 const (
 	GeoMaxPrecision = 12
+
+	// Similar to Redis, members of geo sets are scored by 52-bit geohashes, which interleave
+	// 26 bits of latitude and 26 bits of longitude. Latitudes are limited to the range
+	// of EPSG:3857 (web mercator).
+	GeoStepMax = 26
+	GeoLatMin  = -85.05112878
+	GeoLatMax  = 85.05112878
+	GeoLonMin  = -180.0
+	GeoLonMax  = 180.0
 )
 
 var (
@@ -53,6 +62,31 @@ func (b GeoBoundingBox) LonDelta() float64 {
 	return b.MaxLon - b.MinLon
 }
 
+func (b GeoBoundingBox) Intersects(o GeoBoundingBox) bool {
+	return b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat && b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon
+}
+
+// MakeGeoBoundingBoxAround returns the box that covers all coordinates within `halfWidth` meters
+// east or west and `halfHeight` meters north or south of `center`. Longitudes of the box might
+// go beyond [-180, 180] near the antimeridian.
+func MakeGeoBoundingBoxAround(center GeoCoord, halfWidth float64, halfHeight float64) *GeoBoundingBox {
+	latDelta := halfHeight / geoEarthRadiusInMeters * (180 / math.Pi)
+
+	// A degree of longitude gets shorter towards the poles, so use the edge closer to the pole
+	poleLat := math.Min(math.Abs(center.Lat)+latDelta, 90)
+	lonDelta := 360.0
+	if poleLat < 90 {
+		lonDelta = math.Min(halfWidth/geoEarthRadiusInMeters/math.Cos(poleLat*math.Pi/180)*(180/math.Pi), 360)
+	}
+
+	return &GeoBoundingBox{
+		MinLat: math.Max(center.Lat-latDelta, -90),
+		MaxLat: math.Min(center.Lat+latDelta, 90),
+		MinLon: center.Lon - lonDelta,
+		MaxLon: center.Lon + lonDelta,
+	}
+}
+
 func MakeGeoBoundingBox(geoHash string) *GeoBoundingBox {
 	v := geoBase32Decode(geoHash)
 	precision := len(geoHash)
@@ -177,6 +211,69 @@ func GeoHash(coord GeoCoord, precision int) string {
 	return geoBase32Encode(res, precision)
 }
 
+func geoInterleave(latOffset uint64, lonOffset uint64, step int) int {
+	// Bits of latitude go to even positions, and bits of longitude go to odd positions
+	var res uint64
+	for i := 0; i < step; i++ {
+		res |= (latOffset >> i & 1) << (2 * i)
+		res |= (lonOffset >> i & 1) << (2*i + 1)
+	}
+	return int(res)
+}
+
+func geoDeinterleave(bits int, step int) (uint64, uint64) {
+	var latOffset, lonOffset uint64
+	for i := 0; i < step; i++ {
+		latOffset |= (uint64(bits) >> (2 * i) & 1) << i
+		lonOffset |= (uint64(bits) >> (2*i + 1) & 1) << i
+	}
+	return latOffset, lonOffset
+}
+
+func geoEncodeBits(coord GeoCoord, step int, latMin float64, latMax float64) int {
+	coord.Lat = min(max(coord.Lat, latMin), latMax)
+	coord.Lon = min(max(coord.Lon, GeoLonMin), GeoLonMax)
+
+	cells := float64(uint64(1) << step)
+	latOffset := uint64((coord.Lat - latMin) / (latMax - latMin) * cells)
+	lonOffset := uint64((coord.Lon - GeoLonMin) / (GeoLonMax - GeoLonMin) * cells)
+
+	// Coordinates at the max edges belong to the last cell
+	maxOffset := uint64(1)<<step - 1
+	latOffset = min(latOffset, maxOffset)
+	lonOffset = min(lonOffset, maxOffset)
+	return geoInterleave(latOffset, lonOffset, step)
+}
+
+// GeoEncodeScore encodes a coordinate into the 52-bit geohash used as the score of a geo set member
+func GeoEncodeScore(coord GeoCoord) int {
+	return geoEncodeBits(coord, GeoStepMax, GeoLatMin, GeoLatMax)
+}
+
+// GeoDecodeScore decodes the score of a geo set member into the center of its geohash cell
+func GeoDecodeScore(score int) GeoCoord {
+	latOffset, lonOffset := geoDeinterleave(score, GeoStepMax)
+	cells := float64(uint64(1) << GeoStepMax)
+
+	minLat := GeoLatMin + float64(latOffset)/cells*(GeoLatMax-GeoLatMin)
+	maxLat := GeoLatMin + float64(latOffset+1)/cells*(GeoLatMax-GeoLatMin)
+	minLon := GeoLonMin + float64(lonOffset)/cells*(GeoLonMax-GeoLonMin)
+	maxLon := GeoLonMin + float64(lonOffset+1)/cells*(GeoLonMax-GeoLonMin)
+
+	return GeoCoord{
+		Lat: min(max((minLat+maxLat)/2, GeoLatMin), GeoLatMax),
+		Lon: min(max((minLon+maxLon)/2, GeoLonMin), GeoLonMax),
+	}
+}
+
+// GeoHashFromScore converts the score of a geo set member into the standard 11-character
+// geohash string. Same as Redis, the coordinate is re-encoded with the standard latitude range,
+// and the last character is always '0' as a 52-bit score only covers 10 characters.
+func GeoHashFromScore(score int) string {
+	bits := geoEncodeBits(GeoDecodeScore(score), GeoStepMax, -90, 90)
+	return geoBase32Encode(int64(bits>>2), 10) + "0"
+}
+
 func GeoHaversineDist(coordA GeoCoord, coordB GeoCoord) float64 {
 	// Tutorial: https://www.movable-type.co.uk/scripts/latlong.html
 	latARad := coordA.Lat * math.Pi / 180
@@ -185,39 +282,205 @@ func GeoHaversineDist(coordA GeoCoord, coordB GeoCoord) float64 {
 	deltaLon := (coordB.Lon - coordA.Lon) * math.Pi / 180
 
 	a := math.Pow(math.Sin(deltaLat/2), 2) + math.Cos(latARad)*
-		math.Cos(latBRad)*math.Pow(deltaLon/2, 2)
+		math.Cos(latBRad)*math.Pow(math.Sin(deltaLon/2), 2)
 
 	return geoEarthRadiusInMeters * 2.0 * math.Asin(math.Sqrt(a))
 }
 
-func GeoGetNeighbors(geoHash string) []string {
-	// `geoHash` encodes a box of possible coordinates (aka. bounding box)
-	// The length of `geoHash` determines the precision
-	box := MakeGeoBoundingBox(geoHash)
+func geoWrapLon(lon float64) float64 {
+	if lon > 180 {
+		return lon - 360
+	}
+	if lon < -180 {
+		return lon + 360
+	}
+	return lon
+}
 
+// geoGetNeighborCenters returns the centers of the 8 boxes surrounding `box`
+func geoGetNeighborCenters(box *GeoBoundingBox) []GeoCoord {
 	center := box.Center()
 	latDelta := box.LatDelta()
 	lonDelta := box.LonDelta()
-	precision := len(geoHash)
 
-	return []string{
+	return []GeoCoord{
 		// North west
-		GeoHash(GeoCoord{Lat: center.Lat + latDelta, Lon: center.Lon - lonDelta}, precision),
+		{Lat: center.Lat + latDelta, Lon: geoWrapLon(center.Lon - lonDelta)},
 		// North
-		GeoHash(GeoCoord{Lat: center.Lat + latDelta, Lon: center.Lon}, precision),
+		{Lat: center.Lat + latDelta, Lon: center.Lon},
 		// North east
-		GeoHash(GeoCoord{Lat: center.Lat + latDelta, Lon: center.Lon + lonDelta}, precision),
+		{Lat: center.Lat + latDelta, Lon: geoWrapLon(center.Lon + lonDelta)},
 
 		// West
-		GeoHash(GeoCoord{Lat: center.Lat, Lon: center.Lon - lonDelta}, precision),
+		{Lat: center.Lat, Lon: geoWrapLon(center.Lon - lonDelta)},
 		// East
-		GeoHash(GeoCoord{Lat: center.Lat, Lon: center.Lon + lonDelta}, precision),
+		{Lat: center.Lat, Lon: geoWrapLon(center.Lon + lonDelta)},
 
 		// South west
-		GeoHash(GeoCoord{Lat: center.Lat - latDelta, Lon: center.Lon - lonDelta}, precision),
+		{Lat: center.Lat - latDelta, Lon: geoWrapLon(center.Lon - lonDelta)},
 		// South
-		GeoHash(GeoCoord{Lat: center.Lat - latDelta, Lon: center.Lon}, precision),
+		{Lat: center.Lat - latDelta, Lon: center.Lon},
 		// South east
-		GeoHash(GeoCoord{Lat: center.Lat - latDelta, Lon: center.Lon + lonDelta}, precision),
+		{Lat: center.Lat - latDelta, Lon: geoWrapLon(center.Lon + lonDelta)},
+	}
+}
+
+func GeoGetNeighbors(geoHash string) []string {
+	// `geoHash` encodes a box of possible coordinates (aka. bounding box)
+	// The length of `geoHash` determines the precision
+	box := MakeGeoBoundingBox(geoHash)
+	precision := len(geoHash)
+
+	neighbors := make([]string, 0, 8)
+	for _, c := range geoGetNeighborCenters(box) {
+		neighbors = append(neighbors, GeoHash(c, precision))
+	}
+	return neighbors
+}
+
+// GeoCell is a cell of geo set scores, encoding `Step` bits of latitude and `Step` bits of longitude.
+// All members inside the cell have scores within a contiguous range.
+type GeoCell struct {
+	Bits int
+	Step int
+}
+
+func MakeGeoCell(coord GeoCoord, step int) GeoCell {
+	return GeoCell{
+		Bits: geoEncodeBits(coord, step, GeoLatMin, GeoLatMax),
+		Step: step,
+	}
+}
+
+func (c GeoCell) BoundingBox() *GeoBoundingBox {
+	latOffset, lonOffset := geoDeinterleave(c.Bits, c.Step)
+	cells := float64(uint64(1) << c.Step)
+
+	return &GeoBoundingBox{
+		MinLat: GeoLatMin + float64(latOffset)/cells*(GeoLatMax-GeoLatMin),
+		MaxLat: GeoLatMin + float64(latOffset+1)/cells*(GeoLatMax-GeoLatMin),
+		MinLon: GeoLonMin + float64(lonOffset)/cells*(GeoLonMax-GeoLonMin),
+		MaxLon: GeoLonMin + float64(lonOffset+1)/cells*(GeoLonMax-GeoLonMin),
+	}
+}
+
+// ScoreRange returns the smallest and the largest scores of members inside the cell
+func (c GeoCell) ScoreRange() (int, int) {
+	shift := (GeoStepMax - c.Step) * 2
+	return c.Bits << shift, (c.Bits+1)<<shift - 1
+}
+
+func GeoGetCellNeighbors(cell GeoCell) []GeoCell {
+	neighbors := make([]GeoCell, 0, 8)
+	for _, c := range geoGetNeighborCenters(cell.BoundingBox()) {
+		neighbors = append(neighbors, MakeGeoCell(c, cell.Step))
+	}
+	return neighbors
+}
+
+// GeoEstimateStep estimates the step of cells such that the cell containing a point and its
+// neighbors cover a circle with `radius` meters around the point
+func GeoEstimateStep(radius float64, lat float64) int {
+	// Same as Redis's `geohashEstimateStepsByRadius`
+	const mercatorMax = 20037726.37
+	if radius == 0 {
+		return GeoStepMax
+	}
+
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	step -= 2
+
+	// Cells get narrower towards the poles
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	return min(max(step, 1), GeoStepMax)
+}
+
+func geoCellsIntersect(cell GeoCell, box *GeoBoundingBox) bool {
+	// `box` might go beyond the antimeridian
+	cellBox := cell.BoundingBox()
+	for _, lonShift := range []float64{0, 360, -360} {
+		shifted := GeoBoundingBox{
+			MinLat: cellBox.MinLat,
+			MaxLat: cellBox.MaxLat,
+			MinLon: cellBox.MinLon + lonShift,
+			MaxLon: cellBox.MaxLon + lonShift,
+		}
+		if shifted.Intersects(*box) {
+			return true
+		}
+	}
+	return false
+}
+
+// GeoGetSearchCells returns the cell containing `center` and its neighbors, at the finest step where
+// they still cover `box`. `radius` is the distance in meters from `center` to the farthest corner of
+// `box`. Cells that do not intersect `box` are excluded.
+func GeoGetSearchCells(center GeoCoord, radius float64, box *GeoBoundingBox) []GeoCell {
+	var cell GeoCell
+	var neighbors []GeoCell
+
+	for step := GeoEstimateStep(radius, center.Lat); step >= 1; step-- {
+		cell = MakeGeoCell(center, step)
+		neighbors = GeoGetCellNeighbors(cell)
+
+		// The 3x3 cells cover one more cell in each direction
+		cellBox := cell.BoundingBox()
+		covered := cellBox.MinLat-cellBox.LatDelta() <= box.MinLat || cellBox.MinLat <= GeoLatMin
+		covered = covered && (cellBox.MaxLat+cellBox.LatDelta() >= box.MaxLat || cellBox.MaxLat >= GeoLatMax)
+		covered = covered && cellBox.MinLon-cellBox.LonDelta() <= box.MinLon
+		covered = covered && cellBox.MaxLon+cellBox.LonDelta() >= box.MaxLon
+		if covered {
+			break
+		}
+	}
+
+	// Neighbors might be the same cell near the poles or at very coarse steps
+	cells := []GeoCell{cell}
+	seen := map[int]bool{cell.Bits: true}
+	for _, n := range neighbors {
+		if !seen[n.Bits] && geoCellsIntersect(n, box) {
+			seen[n.Bits] = true
+			cells = append(cells, n)
+		}
+	}
+	return cells
+}
+
+// GeoCellBlockMinDist returns a lower bound of the distance in meters from `coord` inside `cell` to any
+// coordinate outside the block of cells within `r` cells of `cell`
+func GeoCellBlockMinDist(coord GeoCoord, cell GeoCell, r int) float64 {
+	cellBox := cell.BoundingBox()
+	latDelta, lonDelta := cellBox.LatDelta(), cellBox.LonDelta()
+
+	// Distances to the northern and southern edges are along the meridian
+	maxLat := math.Min(cellBox.MaxLat+float64(r)*latDelta, GeoLatMax)
+	minLat := math.Max(cellBox.MinLat-float64(r)*latDelta, GeoLatMin)
+	dist := math.Min(
+		GeoHaversineDist(coord, GeoCoord{Lat: maxLat, Lon: coord.Lon}),
+		GeoHaversineDist(coord, GeoCoord{Lat: minLat, Lon: coord.Lon}),
+	)
+
+	// The block wraps around the globe
+	if float64(2*r+1)*lonDelta >= GeoLonMax-GeoLonMin {
+		return dist
+	}
+
+	// Distances to the western and eastern edges are at least the cross-track distances to
+	// the great circles of their meridians
+	latRad := coord.Lat * math.Pi / 180
+	for _, edgeLon := range []float64{cellBox.MinLon - float64(r)*lonDelta, cellBox.MaxLon + float64(r)*lonDelta} {
+		deltaLon := (coord.Lon - edgeLon) * math.Pi / 180
+		crossTrack := math.Asin(math.Min(math.Cos(latRad)*math.Abs(math.Sin(deltaLon)), 1))
+		dist = math.Min(dist, geoEarthRadiusInMeters*crossTrack)
 	}
+	return dist
 }
@@ -1,6 +1,8 @@
 package algo
 
 import (
+	"math"
+	"math/rand"
 	"strconv"
 	"testing"
 
@@ -18,6 +20,10 @@ func TestGeoDist(t *testing.T) {
 		GeoCoord{Lat: 38.504048, Lon: -98.315949},
 	)
 	assert.Equal(t, strconv.FormatFloat(res/1000, 'f', 2, 64), "347.33")
+
+	// A quarter of the equator, where the sine of the longitude difference is far from the difference
+	res = GeoHaversineDist(GeoCoord{Lat: 0, Lon: 0}, GeoCoord{Lat: 0, Lon: 90})
+	assert.InDelta(t, geoEarthRadiusInMeters*math.Pi/2, res, 1e-6)
 }
 
 func TestGeoGetNeighbors(t *testing.T) {
@@ -29,3 +35,104 @@ func TestGeoGetNeighbors(t *testing.T) {
 		n,
 	)
 }
+
+func TestGeoScore(t *testing.T) {
+	// Reference values are taken from a real Redis server
+	palermo := GeoCoord{Lat: 38.115556, Lon: 13.361389}
+	catania := GeoCoord{Lat: 37.502669, Lon: 15.087269}
+	assert.Equal(t, 3479099956230698, GeoEncodeScore(palermo))
+	assert.Equal(t, 3479447370796909, GeoEncodeScore(catania))
+
+	coord := GeoDecodeScore(3479099956230698)
+	assert.Equal(t, "13.36138933897018433", strconv.FormatFloat(coord.Lon, 'f', 17, 64))
+	assert.Equal(t, "38.11555639549629859", strconv.FormatFloat(coord.Lat, 'f', 17, 64))
+
+	// Decoding and re-encoding should land in the same cell
+	assert.Equal(t, 3479099956230698, GeoEncodeScore(coord))
+
+	assert.Equal(t, "sqc8b49rny0", GeoHashFromScore(3479099956230698))
+	assert.Equal(t, "sqdtr74hyu0", GeoHashFromScore(3479447370796909))
+}
+
+func TestGeoCell(t *testing.T) {
+	palermo := GeoCoord{Lat: 38.115556, Lon: 13.361389}
+	score := GeoEncodeScore(palermo)
+
+	for step := 1; step <= GeoStepMax; step++ {
+		cell := MakeGeoCell(palermo, step)
+		assert.True(t, cell.BoundingBox().Contains(palermo))
+
+		minScore, maxScore := cell.ScoreRange()
+		assert.LessOrEqual(t, minScore, score)
+		assert.GreaterOrEqual(t, maxScore, score)
+	}
+
+	neighbors := GeoGetCellNeighbors(MakeGeoCell(palermo, 20))
+	seen := make(map[int]bool)
+	for _, n := range neighbors {
+		seen[n.Bits] = true
+	}
+	assert.Equal(t, 8, len(seen))
+}
+
+func TestGeoGetSearchCells(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		center := GeoCoord{Lat: r.Float64()*160 - 80, Lon: r.Float64()*360 - 180}
+		radius := math.Pow(10, r.Float64()*6)
+		cells := GeoGetSearchCells(center, radius, MakeGeoBoundingBoxAround(center, radius, radius))
+
+		// Points within the radius must be inside one of the cells
+		for j := 0; j < 20; j++ {
+			bearing := r.Float64() * 2 * math.Pi
+			dist := r.Float64() * radius
+			p := GeoCoord{
+				Lat: center.Lat + dist*math.Cos(bearing)/geoEarthRadiusInMeters*180/math.Pi,
+				Lon: center.Lon + dist*math.Sin(bearing)/geoEarthRadiusInMeters*180/math.Pi/math.Cos(center.Lat*math.Pi/180),
+			}
+			p.Lon = geoWrapLon(p.Lon)
+			if GeoHaversineDist(center, p) > radius || p.Lat < GeoLatMin || p.Lat > GeoLatMax {
+				continue
+			}
+
+			score := GeoEncodeScore(p)
+			found := false
+			for _, c := range cells {
+				minScore, maxScore := c.ScoreRange()
+				found = found || (minScore <= score && score <= maxScore)
+			}
+			assert.True(t, found, "center %v, radius %f, point %v", center, radius, p)
+		}
+	}
+}
+
+func TestGeoCellBlockMinDist(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		center := GeoCoord{Lat: r.Float64()*160 - 80, Lon: r.Float64()*360 - 180}
+		step := 1 + r.Intn(GeoStepMax)
+		rings := r.Intn(4)
+		cell := MakeGeoCell(center, step)
+		bound := GeoCellBlockMinDist(center, cell, rings)
+		latOffset, lonOffset := geoDeinterleave(cell.Bits, step)
+		numCells := int64(1) << step
+
+		// Points outside the block must be farther than the bound
+		for j := 0; j < 20; j++ {
+			p := GeoCoord{Lat: r.Float64()*2*GeoLatMax - GeoLatMax, Lon: r.Float64()*360 - 180}
+			if j%2 == 0 {
+				// Nearby points are more likely to be close to the bound
+				p = GeoCoord{Lat: center.Lat + r.NormFloat64()*bound/1e5, Lon: geoWrapLon(center.Lon + r.NormFloat64()*bound/1e5)}
+				p.Lat = math.Max(math.Min(p.Lat, GeoLatMax), GeoLatMin)
+			}
+			pLatOffset, pLonOffset := geoDeinterleave(MakeGeoCell(p, step).Bits, step)
+			latDiff := int64(pLatOffset) - int64(latOffset)
+			lonDiff := (int64(pLonOffset) - int64(lonOffset) + numCells) % numCells
+			lonDiff = min(lonDiff, numCells-lonDiff)
+			if max(latDiff, -latDiff, lonDiff) <= int64(rings) {
+				continue
+			}
+			assert.GreaterOrEqual(t, GeoHaversineDist(center, p)+1e-6, bound, "center %v, step %d, rings %d, point %v", center, step, rings, p)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package algo
+
+import "math"
+
+// GeoPolygon is a simple polygon on the lon/lat plane. Edges are straight lines between
+// consecutive vertices, and the last vertex connects back to the first one.
+type GeoPolygon struct {
+	Vertices []GeoCoord
+}
+
+// MakeGeoPolygon creates a polygon from `vertices`. Each edge goes the shorter way around the globe,
+// so longitudes of the polygon might go beyond [-180, 180] near the antimeridian.
+func MakeGeoPolygon(vertices []GeoCoord) *GeoPolygon {
+	p := &GeoPolygon{Vertices: make([]GeoCoord, len(vertices))}
+	copy(p.Vertices, vertices)
+
+	for i := 1; i < len(p.Vertices); i++ {
+		prevLon := p.Vertices[i-1].Lon
+		for p.Vertices[i].Lon-prevLon > 180 {
+			p.Vertices[i].Lon -= 360
+		}
+		for p.Vertices[i].Lon-prevLon < -180 {
+			p.Vertices[i].Lon += 360
+		}
+	}
+	return p
+}
+
+func (p *GeoPolygon) BoundingBox() *GeoBoundingBox {
+	box := &GeoBoundingBox{
+		MinLat: math.Inf(1),
+		MaxLat: math.Inf(-1),
+		MinLon: math.Inf(1),
+		MaxLon: math.Inf(-1),
+	}
+	for _, v := range p.Vertices {
+		box.MinLat = math.Min(box.MinLat, v.Lat)
+		box.MaxLat = math.Max(box.MaxLat, v.Lat)
+		box.MinLon = math.Min(box.MinLon, v.Lon)
+		box.MaxLon = math.Max(box.MaxLon, v.Lon)
+	}
+	return box
+}
+
+// Center returns the center of the bounding box of the polygon
+func (p *GeoPolygon) Center() GeoCoord {
+	center := p.BoundingBox().Center()
+	center.Lon = geoWrapLon(center.Lon)
+	return center
+}
+
+// Contains checks if `c` is inside the polygon or on its edges
+func (p *GeoPolygon) Contains(c GeoCoord) bool {
+	// The polygon might go beyond the antimeridian
+	for _, lonShift := range []float64{0, 360, -360} {
+		if p.containsPlanar(GeoCoord{Lat: c.Lat, Lon: c.Lon + lonShift}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *GeoPolygon) containsPlanar(c GeoCoord) bool {
+	// Tutorial: https://wrfranklin.org/Research/Short_Notes/pnpoly.html
+	inside := false
+	n := len(p.Vertices)
+
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := p.Vertices[j], p.Vertices[i]
+		if geoOnSegment(a, b, c) {
+			return true
+		}
+
+		// Count crossings of a ray going east from `c`
+		if (b.Lat > c.Lat) != (a.Lat > c.Lat) {
+			crossLon := (a.Lon-b.Lon)*(c.Lat-b.Lat)/(a.Lat-b.Lat) + b.Lon
+			if c.Lon < crossLon {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func geoOnSegment(a GeoCoord, b GeoCoord, c GeoCoord) bool {
+	cross := (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+	if math.Abs(cross) > 1e-12 {
+		return false
+	}
+	return math.Min(a.Lon, b.Lon) <= c.Lon && c.Lon <= math.Max(a.Lon, b.Lon) &&
+		math.Min(a.Lat, b.Lat) <= c.Lat && c.Lat <= math.Max(a.Lat, b.Lat)
+}
@@ -0,0 +1,47 @@
+package algo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoPolygonContains(t *testing.T) {
+	// A concave "L" shape
+	p := MakeGeoPolygon([]GeoCoord{
+		{Lat: 0, Lon: 0},
+		{Lat: 0, Lon: 2},
+		{Lat: 1, Lon: 2},
+		{Lat: 1, Lon: 1},
+		{Lat: 2, Lon: 1},
+		{Lat: 2, Lon: 0},
+	})
+	assert.True(t, p.Contains(GeoCoord{Lat: 0.5, Lon: 0.5}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 0.5, Lon: 1.5}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 1.5, Lon: 0.5}))
+	assert.False(t, p.Contains(GeoCoord{Lat: 1.5, Lon: 1.5}))
+	assert.False(t, p.Contains(GeoCoord{Lat: -0.5, Lon: 0.5}))
+
+	// Edges and vertices
+	assert.True(t, p.Contains(GeoCoord{Lat: 0, Lon: 1}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 1, Lon: 1.5}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 2, Lon: 0}))
+
+	assert.Equal(t, &GeoBoundingBox{MinLat: 0, MaxLat: 2, MinLon: 0, MaxLon: 2}, p.BoundingBox())
+}
+
+func TestGeoPolygonAcrossAntimeridian(t *testing.T) {
+	p := MakeGeoPolygon([]GeoCoord{
+		{Lat: -1, Lon: 179},
+		{Lat: -1, Lon: -179},
+		{Lat: 1, Lon: -179},
+		{Lat: 1, Lon: 179},
+	})
+	assert.Equal(t, &GeoBoundingBox{MinLat: -1, MaxLat: 1, MinLon: 179, MaxLon: 181}, p.BoundingBox())
+
+	assert.True(t, p.Contains(GeoCoord{Lat: 0, Lon: 179.5}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 0, Lon: -179.5}))
+	assert.True(t, p.Contains(GeoCoord{Lat: 0, Lon: 180}))
+	assert.False(t, p.Contains(GeoCoord{Lat: 0, Lon: 0}))
+	assert.False(t, p.Contains(GeoCoord{Lat: 0, Lon: -178}))
+}
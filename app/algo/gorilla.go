@@ -0,0 +1,235 @@
+package algo
+
+import (
+	"math"
+	"math/bits"
+)
+
+type Sample struct {
+	Timestamp int64
+	Value     float64
+}
+
+type bitWriter struct {
+	Buf     []byte
+	NumBits int
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.NumBits%8 == 0 {
+			w.Buf = append(w.Buf, 0)
+		}
+		// Fill the free bits of the last byte with the most significant bits of `v`
+		free := 8 - w.NumBits%8
+		take := min(free, n)
+		chunk := v >> (n - take) & (1<<take - 1)
+		w.Buf[len(w.Buf)-1] |= byte(chunk << (free - take))
+		w.NumBits += take
+		n -= take
+	}
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+type bitReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for n > 0 {
+		avail := 8 - r.pos%8
+		take := min(avail, n)
+		b := uint64(r.buf[r.pos/8]) >> (avail - take) & (1<<take - 1)
+		v = v<<take | b
+		r.pos += take
+		n -= take
+	}
+	return v
+}
+
+func (r *bitReader) readBit() bool {
+	return r.readBits(1) == 1
+}
+
+/*
+GorillaChunk compresses samples ordered by timestamps as described by Facebook's Gorilla paper.
+The first sample is stored as is. Each following timestamp is stored as the difference between
+its delta and the previous delta, which is 0 for regular intervals:
+
+	'0'                          delta of delta = 0
+	'10'   + 7 bits              delta of delta in [-64, 63]
+	'110'  + 9 bits              delta of delta in [-256, 255]
+	'1110' + 12 bits             delta of delta in [-2048, 2047]
+	'1111' + 64 bits             otherwise
+
+Each following value is XORed with the previous value, which has few meaningful bits for
+slowly changing values:
+
+	'0'                          same value
+	'10' + meaningful bits       the meaningful bits fit in the previous window
+	'11' + 5 bits of leading zeros + 6 bits of meaningful bit count + meaningful bits
+*/
+type GorillaChunk struct {
+	bitWriter
+	NumSamples     int
+	FirstTimestamp int64
+	LastTimestamp  int64
+
+	lastValue    uint64
+	lastDelta    int64
+	lastLeading  int
+	lastTrailing int
+}
+
+func MakeGorillaChunk() *GorillaChunk {
+	return &GorillaChunk{}
+}
+
+// Size returns the number of bytes taken by the compressed samples
+func (c *GorillaChunk) Size() int {
+	return len(c.Buf)
+}
+
+func (c *GorillaChunk) writeTimestamp(ts int64) {
+	delta := ts - c.LastTimestamp
+	dod := delta - c.lastDelta
+	c.lastDelta = delta
+
+	switch {
+	case dod == 0:
+		c.writeBit(false)
+	case dod >= -64 && dod <= 63:
+		c.writeBits(0b10, 2)
+		c.writeBits(uint64(dod), 7)
+	case dod >= -256 && dod <= 255:
+		c.writeBits(0b110, 3)
+		c.writeBits(uint64(dod), 9)
+	case dod >= -2048 && dod <= 2047:
+		c.writeBits(0b1110, 4)
+		c.writeBits(uint64(dod), 12)
+	default:
+		c.writeBits(0b1111, 4)
+		c.writeBits(uint64(dod), 64)
+	}
+}
+
+func (c *GorillaChunk) writeValue(v uint64) {
+	xor := v ^ c.lastValue
+	c.lastValue = v
+	if xor == 0 {
+		c.writeBit(false)
+		return
+	}
+	c.writeBit(true)
+
+	// Leading zeros are capped to fit in 5 bits
+	leading := min(bits.LeadingZeros64(xor), 31)
+	trailing := bits.TrailingZeros64(xor)
+
+	if leading >= c.lastLeading && trailing >= c.lastTrailing {
+		c.writeBit(false)
+		c.writeBits(xor>>c.lastTrailing, 64-c.lastLeading-c.lastTrailing)
+		return
+	}
+
+	// A meaningful bit count of 64 is stored as 0, since it never is 0
+	meaningful := 64 - leading - trailing
+	c.writeBit(true)
+	c.writeBits(uint64(leading), 5)
+	c.writeBits(uint64(meaningful), 6)
+	c.writeBits(xor>>trailing, meaningful)
+	c.lastLeading, c.lastTrailing = leading, trailing
+}
+
+func (c *GorillaChunk) LastValue() float64 {
+	return math.Float64frombits(c.lastValue)
+}
+
+// Append appends a sample. The caller must make sure the timestamp is greater than the last one.
+func (c *GorillaChunk) Append(ts int64, v float64) {
+	if c.NumSamples == 0 {
+		c.writeBits(uint64(ts), 64)
+		c.writeBits(math.Float64bits(v), 64)
+		c.FirstTimestamp = ts
+		c.lastValue = math.Float64bits(v)
+		// No previous window exists until a value changes
+		c.lastLeading = 64
+	} else {
+		c.writeTimestamp(ts)
+		c.writeValue(math.Float64bits(v))
+	}
+	c.LastTimestamp = ts
+	c.NumSamples++
+}
+
+// ForEach decodes samples in order, and stops as soon as `fn` returns false
+func (c *GorillaChunk) ForEach(fn func(s Sample) bool) bool {
+	if c.NumSamples == 0 {
+		return true
+	}
+	r := &bitReader{buf: c.Buf}
+	ts := int64(r.readBits(64))
+	v := r.readBits(64)
+	if !fn(Sample{Timestamp: ts, Value: math.Float64frombits(v)}) {
+		return false
+	}
+
+	var delta int64
+	var leading, trailing int
+	for n := 1; n < c.NumSamples; n++ {
+		var dod int64
+		switch {
+		case !r.readBit():
+		case !r.readBit():
+			dod = signExtend(r.readBits(7), 7)
+		case !r.readBit():
+			dod = signExtend(r.readBits(9), 9)
+		case !r.readBit():
+			dod = signExtend(r.readBits(12), 12)
+		default:
+			dod = int64(r.readBits(64))
+		}
+		delta += dod
+		ts += delta
+
+		if r.readBit() {
+			if r.readBit() {
+				leading = int(r.readBits(5))
+				meaningful := int(r.readBits(6))
+				if meaningful == 0 {
+					meaningful = 64
+				}
+				trailing = 64 - leading - meaningful
+			}
+			v ^= r.readBits(64-leading-trailing) << trailing
+		}
+
+		if !fn(Sample{Timestamp: ts, Value: math.Float64frombits(v)}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Samples decodes all samples
+func (c *GorillaChunk) Samples() []Sample {
+	samples := make([]Sample, 0, c.NumSamples)
+	c.ForEach(func(s Sample) bool {
+		samples = append(samples, s)
+		return true
+	})
+	return samples
+}
+
+func signExtend(v uint64, n int) int64 {
+	return int64(v<<(64-n)) >> (64 - n)
+}
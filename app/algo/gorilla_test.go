@@ -0,0 +1,50 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGorillaChunk(t *testing.T) {
+	c := MakeGorillaChunk()
+	assert.Empty(t, c.Samples())
+
+	// Regular intervals and repeated values take about a bit each
+	for i := 0; i < 100; i++ {
+		c.Append(int64(1700000000000+i*1000), 20)
+	}
+	assert.Equal(t, 100, c.NumSamples)
+	assert.Equal(t, int64(1700000000000), c.FirstTimestamp)
+	assert.Equal(t, int64(1700000099000), c.LastTimestamp)
+	// The first delta of delta is the first delta
+	assert.Equal(t, 16+(17+98*2+7)/8, c.Size())
+
+	samples := c.Samples()
+	assert.Len(t, samples, 100)
+	assert.Equal(t, Sample{Timestamp: 1700000099000, Value: 20}, samples[99])
+}
+
+func TestGorillaChunkRoundTrip(t *testing.T) {
+	c := MakeGorillaChunk()
+	expected := make([]Sample, 0)
+
+	ts := int64(-5)
+	for i := 0; i < 2000; i++ {
+		// Deltas of deltas of every width, and values that change by various amounts
+		ts += []int64{1, 1, 50, 200, 1500, 100000, 1 << 40}[rand.Intn(7)]
+		v := []float64{1.5, -1.5, rand.Float64(), rand.NormFloat64() * 1e10, math.Inf(1), 0, float64(i)}[rand.Intn(7)]
+		c.Append(ts, v)
+		expected = append(expected, Sample{Timestamp: ts, Value: v})
+	}
+	assert.Equal(t, expected, c.Samples())
+
+	n := 0
+	assert.False(t, c.ForEach(func(s Sample) bool {
+		n++
+		return n < 10
+	}))
+	assert.Equal(t, 10, n)
+}
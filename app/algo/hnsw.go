@@ -0,0 +1,362 @@
+package algo
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	VectorMetricCosine = iota
+	VectorMetricL2
+	VectorMetricIP
+)
+
+type hnswNode struct {
+	Name   string
+	Vector []float32
+	Norm   float64
+	// Neighbors[l] are the neighbors of the node at level l
+	Neighbors [][]*hnswNode
+}
+
+func (n *hnswNode) Level() int {
+	return len(n.Neighbors) - 1
+}
+
+type VectorResult struct {
+	Name string
+	// Distances are smaller for closer vectors: 1 - cosine similarity for COSINE, the squared euclidean
+	// distance for L2 and the negative inner product for IP
+	Distance float64
+}
+
+type hnswCandidate struct {
+	Node     *hnswNode
+	Distance float64
+}
+
+// hnswHeap is a min heap of candidates by distance, or a max heap if `Max` is true
+type hnswHeap struct {
+	Items []hnswCandidate
+	Max   bool
+}
+
+func (h *hnswHeap) Len() int { return len(h.Items) }
+func (h *hnswHeap) Less(i, j int) bool {
+	return (h.Items[i].Distance < h.Items[j].Distance) != h.Max
+}
+func (h *hnswHeap) Swap(i, j int) { h.Items[i], h.Items[j] = h.Items[j], h.Items[i] }
+func (h *hnswHeap) Push(x any)    { h.Items = append(h.Items, x.(hnswCandidate)) }
+func (h *hnswHeap) Pop() any {
+	last := h.Items[len(h.Items)-1]
+	h.Items = h.Items[:len(h.Items)-1]
+	return last
+}
+
+/*
+HNSW (Hierarchical Navigable Small World) is a graph index for approximate nearest neighbor search.
+Each node is placed on levels 0 to a random level, which decreases exponentially, so that upper levels
+are sparse graphs with long links, and level 0 links all nodes to their nearest neighbors.
+
+Searches greedily walk from the entry point on the top level down to level 1, and then explore level
+0 with a candidate list of size `ef`. Nodes have at most `M` neighbors on upper levels and `2M` on
+level 0. Larger `M` and `EfConstruction` make better graphs at the cost of memory and insertion time.
+*/
+type HNSW struct {
+	Dim            int
+	Metric         int
+	M              int
+	EfConstruction int
+
+	Nodes      map[string]*hnswNode
+	EntryPoint *hnswNode
+	levelMult  float64
+	rand       *rand.Rand
+}
+
+func MakeHNSW(dim int, metric int, m int, efConstruction int) *HNSW {
+	return &HNSW{
+		Dim:            dim,
+		Metric:         metric,
+		M:              m,
+		EfConstruction: efConstruction,
+		Nodes:          make(map[string]*hnswNode),
+		levelMult:      1 / math.Log(float64(m)),
+		// A fixed seed makes the graph deterministic for the same insertions
+		rand: rand.New(rand.NewSource(0)),
+	}
+}
+
+func vectorNorm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+func (h *HNSW) distance(a []float32, aNorm float64, b *hnswNode) float64 {
+	var res float64
+	switch h.Metric {
+	case VectorMetricL2:
+		for i := range a {
+			d := float64(a[i]) - float64(b.Vector[i])
+			res += d * d
+		}
+	default:
+		for i := range a {
+			res += float64(a[i]) * float64(b.Vector[i])
+		}
+		if h.Metric == VectorMetricIP {
+			return -res
+		}
+		if aNorm == 0 || b.Norm == 0 {
+			return 1
+		}
+		res = 1 - res/(aNorm*b.Norm)
+	}
+	return res
+}
+
+func (h *HNSW) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * h.M
+	}
+	return h.M
+}
+
+func (h *HNSW) Len() int {
+	return len(h.Nodes)
+}
+
+func (h *HNSW) Get(name string) ([]float32, bool) {
+	n, found := h.Nodes[name]
+	if !found {
+		return nil, false
+	}
+	return n.Vector, true
+}
+
+// greedySearch walks to the closest node to the query on a level
+func (h *HNSW) greedySearch(query []float32, norm float64, entry hnswCandidate, level int) hnswCandidate {
+	for changed := true; changed; {
+		changed = false
+		for _, n := range entry.Node.Neighbors[level] {
+			if d := h.distance(query, norm, n); d < entry.Distance {
+				entry = hnswCandidate{Node: n, Distance: d}
+				changed = true
+			}
+		}
+	}
+	return entry
+}
+
+// searchLevel returns the `ef` closest nodes to the query found on a level, from the closest to the farthest
+func (h *HNSW) searchLevel(query []float32, norm float64, entry hnswCandidate, ef int, level int) []hnswCandidate {
+	visited := map[*hnswNode]bool{entry.Node: true}
+	candidates := &hnswHeap{Items: []hnswCandidate{entry}}
+	results := &hnswHeap{Items: []hnswCandidate{entry}, Max: true}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.Distance > results.Items[0].Distance {
+			break
+		}
+		for _, n := range c.Node.Neighbors[level] {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			d := h.distance(query, norm, n)
+			if results.Len() < ef || d < results.Items[0].Distance {
+				heap.Push(candidates, hnswCandidate{Node: n, Distance: d})
+				heap.Push(results, hnswCandidate{Node: n, Distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sort.Slice(results.Items, func(i, j int) bool {
+		return results.Items[i].Distance < results.Items[j].Distance
+	})
+	return results.Items
+}
+
+// selectNeighbors keeps the closest `max` nodes to a node, with ties broken by names to be deterministic
+func (h *HNSW) selectNeighbors(node *hnswNode, nodes []*hnswNode, max int) []*hnswNode {
+	candidates := make([]hnswCandidate, 0, len(nodes))
+	for _, n := range nodes {
+		candidates = append(candidates, hnswCandidate{Node: n, Distance: h.distance(node.Vector, node.Norm, n)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Distance != candidates[j].Distance {
+			return candidates[i].Distance < candidates[j].Distance
+		}
+		return candidates[i].Node.Name < candidates[j].Node.Name
+	})
+
+	res := make([]*hnswNode, 0, max)
+	for i := 0; i < len(candidates) && i < max; i++ {
+		res = append(res, candidates[i].Node)
+	}
+	return res
+}
+
+// connect links a node to a neighbor on a level, and prunes the farthest neighbors if there are too many
+func (h *HNSW) connect(node *hnswNode, neighbor *hnswNode, level int) {
+	for _, n := range node.Neighbors[level] {
+		if n == neighbor {
+			return
+		}
+	}
+	node.Neighbors[level] = append(node.Neighbors[level], neighbor)
+	if len(node.Neighbors[level]) > h.maxNeighbors(level) {
+		node.Neighbors[level] = h.selectNeighbors(node, node.Neighbors[level], h.maxNeighbors(level))
+	}
+}
+
+func (h *HNSW) randomLevel() int {
+	return int(-math.Log(1-h.rand.Float64()) * h.levelMult)
+}
+
+// Insert adds a vector, or replaces the vector of an existing name. Returns true if the name is new.
+func (h *HNSW) Insert(name string, vector []float32) bool {
+	_, found := h.Nodes[name]
+	if found {
+		h.Remove(name)
+	}
+
+	node := &hnswNode{
+		Name:      name,
+		Vector:    vector,
+		Norm:      vectorNorm(vector),
+		Neighbors: make([][]*hnswNode, h.randomLevel()+1),
+	}
+	h.Nodes[name] = node
+	if h.EntryPoint == nil {
+		h.EntryPoint = node
+		return !found
+	}
+
+	entry := hnswCandidate{Node: h.EntryPoint, Distance: h.distance(vector, node.Norm, h.EntryPoint)}
+	for level := h.EntryPoint.Level(); level > node.Level(); level-- {
+		entry = h.greedySearch(vector, node.Norm, entry, level)
+	}
+	for level := min(node.Level(), h.EntryPoint.Level()); level >= 0; level-- {
+		results := h.searchLevel(vector, node.Norm, entry, h.EfConstruction, level)
+
+		nodes := make([]*hnswNode, 0, len(results))
+		for _, r := range results {
+			nodes = append(nodes, r.Node)
+		}
+		node.Neighbors[level] = h.selectNeighbors(node, nodes, h.maxNeighbors(level))
+		for _, n := range node.Neighbors[level] {
+			h.connect(n, node, level)
+		}
+		entry = results[0]
+	}
+
+	if node.Level() > h.EntryPoint.Level() {
+		h.EntryPoint = node
+	}
+	return !found
+}
+
+/*
+Remove deletes a vector. Links are not always bidirectional after pruning, so all nodes are scanned for links
+to the removed node. Nodes that lose a link are reconnected to the closest neighbors of the removed node, so
+that the graph stays connected.
+*/
+func (h *HNSW) Remove(name string) bool {
+	node, found := h.Nodes[name]
+	if !found {
+		return false
+	}
+	delete(h.Nodes, name)
+
+	for _, n := range h.Nodes {
+		for level := 0; level <= min(n.Level(), node.Level()); level++ {
+			idx := -1
+			for i, neighbor := range n.Neighbors[level] {
+				if neighbor == node {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				continue
+			}
+
+			candidates := append(n.Neighbors[level][:idx:idx], n.Neighbors[level][idx+1:]...)
+			for _, neighbor := range node.Neighbors[level] {
+				if neighbor != n && !containsNode(candidates, neighbor) {
+					candidates = append(candidates, neighbor)
+				}
+			}
+			n.Neighbors[level] = h.selectNeighbors(n, candidates, h.maxNeighbors(level))
+		}
+	}
+
+	if h.EntryPoint == node {
+		h.EntryPoint = nil
+		for _, n := range h.Nodes {
+			if h.EntryPoint == nil || n.Level() > h.EntryPoint.Level() || (n.Level() == h.EntryPoint.Level() && n.Name < h.EntryPoint.Name) {
+				h.EntryPoint = n
+			}
+		}
+	}
+	return true
+}
+
+func containsNode(nodes []*hnswNode, node *hnswNode) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the approximate `k` nearest vectors to the query, exploring `max(ef, k)` candidates on level 0.
+// Both are capped by the number of vectors.
+func (h *HNSW) Search(query []float32, k int, ef int) []VectorResult {
+	if h.EntryPoint == nil || k <= 0 {
+		return []VectorResult{}
+	}
+	k = min(k, len(h.Nodes))
+	ef = min(max(ef, k), len(h.Nodes))
+	norm := vectorNorm(query)
+
+	entry := hnswCandidate{Node: h.EntryPoint, Distance: h.distance(query, norm, h.EntryPoint)}
+	for level := h.EntryPoint.Level(); level > 0; level-- {
+		entry = h.greedySearch(query, norm, entry, level)
+	}
+	results := h.searchLevel(query, norm, entry, ef, 0)
+
+	res := make([]VectorResult, 0, k)
+	for i := 0; i < len(results) && i < k; i++ {
+		res = append(res, VectorResult{Name: results[i].Node.Name, Distance: results[i].Distance})
+	}
+	return res
+}
+
+// SearchExact returns the exact `k` nearest vectors to the query by comparing it with all vectors
+func (h *HNSW) SearchExact(query []float32, k int) []VectorResult {
+	norm := vectorNorm(query)
+	res := make([]VectorResult, 0, len(h.Nodes))
+	for _, n := range h.Nodes {
+		res = append(res, VectorResult{Name: n.Name, Distance: h.distance(query, norm, n)})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Distance != res[j].Distance {
+			return res[i].Distance < res[j].Distance
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res[:min(k, len(res))]
+}
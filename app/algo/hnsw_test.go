@@ -0,0 +1,99 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomVector(r *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(r.NormFloat64())
+	}
+	return v
+}
+
+// recall returns the fraction of the exact results that are found by the approximate search
+func recall(h *HNSW, r *rand.Rand, queries int, k int) float64 {
+	found := 0
+	for i := 0; i < queries; i++ {
+		q := randomVector(r, h.Dim)
+		exact := make(map[string]bool)
+		for _, res := range h.SearchExact(q, k) {
+			exact[res.Name] = true
+		}
+		for _, res := range h.Search(q, k, 50) {
+			if exact[res.Name] {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(queries*k)
+}
+
+func TestHNSWSmall(t *testing.T) {
+	for _, metric := range []int{VectorMetricCosine, VectorMetricL2, VectorMetricIP} {
+		h := MakeHNSW(2, metric, 4, 20)
+		assert.True(t, h.Insert("a", []float32{1, 0}))
+		assert.True(t, h.Insert("b", []float32{0, 1}))
+		assert.True(t, h.Insert("c", []float32{-2, 0}))
+		assert.False(t, h.Insert("b", []float32{0, 3}))
+		assert.Equal(t, 3, h.Len())
+
+		v, found := h.Get("b")
+		assert.True(t, found)
+		assert.Equal(t, []float32{0, 3}, v)
+
+		res := h.Search([]float32{2, 0.1}, 2, 10)
+		assert.Equal(t, h.SearchExact([]float32{2, 0.1}, 2), res)
+		assert.Equal(t, "a", res[0].Name)
+	}
+
+	h := MakeHNSW(2, VectorMetricCosine, 4, 20)
+	h.Insert("a", []float32{1, 0})
+	h.Insert("b", []float32{1, 1})
+	res := h.SearchExact([]float32{2, 0}, 5)
+	assert.Equal(t, "a", res[0].Name)
+	assert.InDelta(t, 0, res[0].Distance, 1e-9)
+	assert.InDelta(t, 1-0.7071067811865476, res[1].Distance, 1e-6)
+
+	h = MakeHNSW(2, VectorMetricL2, 4, 20)
+	h.Insert("a", []float32{1, 0})
+	h.Insert("b", []float32{4, 4})
+	assert.Equal(t, []VectorResult{{"a", 0}, {"b", 25}}, h.SearchExact([]float32{1, 0}, 5))
+
+	// Huge k and ef are capped by the number of vectors
+	assert.Equal(t, []VectorResult{{"a", 0}, {"b", 25}}, h.Search([]float32{1, 0}, math.MaxInt, math.MaxInt))
+
+	assert.True(t, h.Remove("a"))
+	assert.False(t, h.Remove("a"))
+	assert.True(t, h.Remove("b"))
+	assert.Nil(t, h.EntryPoint)
+	assert.Empty(t, h.Search([]float32{1, 0}, 5, 10))
+}
+
+func TestHNSWRecall(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, metric := range []int{VectorMetricCosine, VectorMetricL2} {
+		h := MakeHNSW(16, metric, 16, 200)
+		for i := 0; i < 2000; i++ {
+			h.Insert(strconv.Itoa(i), randomVector(r, 16))
+		}
+		assert.Greater(t, recall(h, r, 100, 10), 0.9)
+
+		// Removed vectors are never returned, and the graph still finds the rest
+		for i := 0; i < 2000; i += 2 {
+			assert.True(t, h.Remove(strconv.Itoa(i)))
+		}
+		assert.Equal(t, 1000, h.Len())
+		for _, res := range h.Search(randomVector(r, 16), 100, 100) {
+			n, _ := strconv.Atoi(res.Name)
+			assert.Equal(t, 1, n%2)
+		}
+		assert.Greater(t, recall(h, r, 100, 10), 0.9)
+	}
+}
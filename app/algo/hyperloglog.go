@@ -0,0 +1,392 @@
+package algo
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog estimates the number of distinct elements with 16384 registers of 6 bits each, with a
+// standard error of 0.81%. It is serialized exactly like Redis's HLL strings, so that values are
+// interchangeable with Redis:
+//
+//   - A 16-byte header of the "HYLL" magic, an encoding byte, 3 unused bytes, and the cached
+//     cardinality as a little-endian uint64. The most significant bit of the last byte marks the
+//     cache as invalid.
+//   - Dense encoding: registers packed 6 bits each, starting from the least significant bits.
+//   - Sparse encoding: run-length opcodes, where ZERO (00xxxxxx) is a run of 1 to 64 zero registers,
+//     XZERO (01xxxxxx yyyyyyyy) is a run of 1 to 16384 zero registers, and VAL (1vvvvvxx) is a run of
+//     1 to 4 registers of value 1 to 32.
+//
+// HLLs start sparse, and are converted to dense once a register exceeds 32 or the sparse encoding
+// grows too large.
+type HyperLogLog struct {
+	Buf []byte
+}
+
+const (
+	HllP            = 14
+	HllQ            = 64 - HllP
+	HllRegisters    = 1 << HllP
+	HllBits         = 6
+	HllHeaderSize   = 16
+	HllDenseSize    = HllHeaderSize + (HllRegisters*HllBits+7)/8
+	HllDense        = 0
+	HllSparse       = 1
+	HllSparseMaxVal = 32
+
+	// Same as Redis's default hll-sparse-max-bytes
+	HllSparseMaxBytes = 3000
+
+	hllRegisterMax = 1<<HllBits - 1
+	hllAlphaInf    = 0.721347520444481703680
+	hllHashSeed    = 0xadc83b19
+)
+
+var (
+	ErrHllInvalid   = errors.New("not a valid HyperLogLog")
+	ErrHllCorrupted = errors.New("corrupted HyperLogLog")
+)
+
+// MakeHyperLogLog creates an empty HLL, which is a sparse run of zero registers
+func MakeHyperLogLog() *HyperLogLog {
+	h := &HyperLogLog{Buf: make([]byte, HllHeaderSize, HllHeaderSize+2)}
+	copy(h.Buf, "HYLL")
+	h.Buf[4] = HllSparse
+	h.Buf = hllAppendZeros(h.Buf, HllRegisters)
+	return h
+}
+
+// LoadHyperLogLog checks the header of a serialized HLL. The sparse encoding is only checked when
+// it is decoded.
+func LoadHyperLogLog(buf []byte) (*HyperLogLog, error) {
+	if len(buf) < HllHeaderSize || string(buf[:4]) != "HYLL" {
+		return nil, ErrHllInvalid
+	}
+	switch buf[4] {
+	case HllDense:
+		if len(buf) != HllDenseSize {
+			return nil, ErrHllInvalid
+		}
+	case HllSparse:
+	default:
+		return nil, ErrHllInvalid
+	}
+	return &HyperLogLog{Buf: buf}, nil
+}
+
+func (h *HyperLogLog) Encoding() int {
+	return int(h.Buf[4])
+}
+
+// MurmurHash64A is the hash function Redis uses for HLLs, reading 8-byte blocks in little-endian order
+func MurmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ (uint64(len(key)) * m)
+	n := len(key) - len(key)%8
+	for i := 0; i < n; i += 8 {
+		k := binary.LittleEndian.Uint64(key[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+	}
+
+	tail := key[n:]
+	if len(tail) > 0 {
+		for i := len(tail) - 1; i >= 0; i-- {
+			h ^= uint64(tail[i]) << (8 * i)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen returns the register of `elem`, and the length of the run of zeros ending the rest
+// of its hash plus 1
+func hllPatLen(elem []byte) (int, uint8) {
+	hash := MurmurHash64A(elem, hllHashSeed)
+	index := int(hash & (HllRegisters - 1))
+	hash >>= HllP
+	// Make sure the count is at most Q + 1
+	hash |= 1 << HllQ
+	return index, uint8(bits.TrailingZeros64(hash) + 1)
+}
+
+func hllDenseGet(regs []byte, i int) uint8 {
+	pos := i * HllBits
+	b, fb := pos/8, uint(pos%8)
+	v := uint(regs[b]) >> fb
+	if b+1 < len(regs) {
+		v |= uint(regs[b+1]) << (8 - fb)
+	}
+	return uint8(v & hllRegisterMax)
+}
+
+func hllDenseSet(regs []byte, i int, val uint8) {
+	pos := i * HllBits
+	b, fb := pos/8, uint(pos%8)
+	regs[b] &^= hllRegisterMax << fb
+	regs[b] |= val << fb
+	if b+1 < len(regs) {
+		regs[b+1] &^= hllRegisterMax >> (8 - fb)
+		regs[b+1] |= val >> (8 - fb)
+	}
+}
+
+func hllAppendZeros(buf []byte, n int) []byte {
+	for n > 0 {
+		run := min(n, HllRegisters)
+		if run <= 64 {
+			buf = append(buf, byte(run-1))
+		} else {
+			buf = append(buf, byte(0x40|(run-1)>>8), byte((run-1)&0xff))
+		}
+		n -= run
+	}
+	return buf
+}
+
+func hllAppendVals(buf []byte, val uint8, n int) []byte {
+	for n > 0 {
+		run := min(n, 4)
+		buf = append(buf, 0x80|(val-1)<<2|byte(run-1))
+		n -= run
+	}
+	return buf
+}
+
+// Registers decodes all registers
+func (h *HyperLogLog) Registers() ([]uint8, error) {
+	regs := make([]uint8, HllRegisters)
+	data := h.Buf[HllHeaderSize:]
+
+	if h.Encoding() == HllDense {
+		for i := range regs {
+			regs[i] = hllDenseGet(data, i)
+		}
+		return regs, nil
+	}
+
+	idx := 0
+	for p := 0; p < len(data); {
+		op := data[p]
+		var run int
+		var val uint8
+
+		switch op & 0xc0 {
+		case 0x00:
+			run = int(op&0x3f) + 1
+			p++
+		case 0x40:
+			if p+1 >= len(data) {
+				return nil, ErrHllCorrupted
+			}
+			run = (int(op&0x3f)<<8 | int(data[p+1])) + 1
+			p += 2
+		default:
+			run = int(op&0x3) + 1
+			val = (op>>2)&0x1f + 1
+			p++
+		}
+
+		if idx+run > HllRegisters {
+			return nil, ErrHllCorrupted
+		}
+		for i := idx; i < idx+run; i++ {
+			regs[i] = val
+		}
+		idx += run
+	}
+	if idx != HllRegisters {
+		return nil, ErrHllCorrupted
+	}
+	return regs, nil
+}
+
+// SetRegisters encodes all registers, keeping the HLL sparse if possible. Dense HLLs are never
+// converted back to sparse, the same as Redis.
+func (h *HyperLogLog) SetRegisters(regs []uint8) {
+	h.invalidateCache()
+	header := h.Buf[:HllHeaderSize]
+
+	if h.Encoding() == HllSparse {
+		buf := append(make([]byte, 0, HllSparseMaxBytes), header...)
+		for i := 0; i < HllRegisters; {
+			run := 1
+			for i+run < HllRegisters && regs[i+run] == regs[i] {
+				run++
+			}
+			if regs[i] > HllSparseMaxVal {
+				break
+			}
+			if regs[i] == 0 {
+				buf = hllAppendZeros(buf, run)
+			} else {
+				buf = hllAppendVals(buf, regs[i], run)
+			}
+			if len(buf) > HllSparseMaxBytes {
+				break
+			}
+			i += run
+			if i == HllRegisters {
+				h.Buf = buf
+				return
+			}
+		}
+	}
+
+	buf := make([]byte, HllDenseSize)
+	copy(buf, header)
+	buf[4] = HllDense
+	for i, val := range regs {
+		hllDenseSet(buf[HllHeaderSize:], i, val)
+	}
+	h.Buf = buf
+}
+
+// ToDense converts a sparse HLL to the dense encoding
+func (h *HyperLogLog) ToDense() error {
+	if h.Encoding() == HllDense {
+		return nil
+	}
+	regs, err := h.Registers()
+	if err != nil {
+		return err
+	}
+	h.Buf[4] = HllDense
+	h.SetRegisters(regs)
+	return nil
+}
+
+func (h *HyperLogLog) invalidateCache() {
+	h.Buf[15] |= 1 << 7
+}
+
+// Add adds elements, and returns true if any register is updated
+func (h *HyperLogLog) Add(elems ...[]byte) (bool, error) {
+	if len(elems) == 0 {
+		return false, nil
+	}
+
+	// Dense registers are updated in place
+	if h.Encoding() == HllDense {
+		updated := false
+		for _, elem := range elems {
+			index, count := hllPatLen(elem)
+			if count > hllDenseGet(h.Buf[HllHeaderSize:], index) {
+				hllDenseSet(h.Buf[HllHeaderSize:], index, count)
+				updated = true
+			}
+		}
+		if updated {
+			h.invalidateCache()
+		}
+		return updated, nil
+	}
+
+	regs, err := h.Registers()
+	if err != nil {
+		return false, err
+	}
+	updated := false
+	for _, elem := range elems {
+		index, count := hllPatLen(elem)
+		if count > regs[index] {
+			regs[index] = count
+			updated = true
+		}
+	}
+	if updated {
+		h.SetRegisters(regs)
+	}
+	return updated, nil
+}
+
+// Merge sets each register of `regs` to the max of itself and the same register of the HLL
+func (h *HyperLogLog) Merge(regs []uint8) error {
+	other, err := h.Registers()
+	if err != nil {
+		return err
+	}
+	for i := range regs {
+		regs[i] = max(regs[i], other[i])
+	}
+	return nil
+}
+
+// Count returns the estimated cardinality, using the cached value if it is valid. Otherwise the
+// cache is updated.
+func (h *HyperLogLog) Count() (uint64, error) {
+	if h.Buf[15]&(1<<7) == 0 {
+		return binary.LittleEndian.Uint64(h.Buf[8:HllHeaderSize]), nil
+	}
+	regs, err := h.Registers()
+	if err != nil {
+		return 0, err
+	}
+	card := HllCount(regs)
+	binary.LittleEndian.PutUint64(h.Buf[8:HllHeaderSize], card)
+	return card, nil
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
+
+// HllCount estimates the cardinality of registers with the improved estimator by Otmar Ertl, the
+// same as Redis
+func HllCount(regs []uint8) uint64 {
+	m := float64(HllRegisters)
+	var histogram [hllRegisterMax + 1]int
+	for _, val := range regs {
+		histogram[val]++
+	}
+
+	z := m * hllTau((m-float64(histogram[HllQ+1]))/m)
+	for j := HllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
@@ -0,0 +1,94 @@
+package algo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMurmurHash64A(t *testing.T) {
+	// Same as Redis's MurmurHash64A with the HLL seed
+	for s, expected := range map[string]uint64{
+		"":                  15627466953755236146,
+		"a":                 6039968161137406375,
+		"foo":               16592960565925911732,
+		"hello world":       12184977182547125431,
+		"abcdefgh":          17556823505701520743,
+		"abcdefghijklmnopq": 9758969025913573551,
+	} {
+		assert.Equal(t, expected, MurmurHash64A([]byte(s), hllHashSeed), s)
+	}
+}
+
+func TestHyperLogLogEncoding(t *testing.T) {
+	h := MakeHyperLogLog()
+	assert.Equal(t, []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"), h.Buf)
+
+	// Sparse runs
+	regs := make([]uint8, HllRegisters)
+	regs[0] = 3
+	regs[1] = 3
+	regs[100] = 32
+	h.SetRegisters(regs)
+	assert.Equal(t, []byte{0x89, 0x40, 97, 0xfc, 0x7f, 0x9a}, h.Buf[HllHeaderSize:])
+	decoded, err := h.Registers()
+	assert.NoError(t, err)
+	assert.Equal(t, regs, decoded)
+
+	// Values over 32 are only held in the dense encoding
+	regs[200] = 33
+	h.SetRegisters(regs)
+	assert.Equal(t, HllDense, h.Encoding())
+	assert.Equal(t, HllDenseSize, len(h.Buf))
+	decoded, err = h.Registers()
+	assert.NoError(t, err)
+	assert.Equal(t, regs, decoded)
+
+	// Dense registers are packed 6 bits each from the least significant bits
+	assert.Equal(t, byte(0xc3), h.Buf[HllHeaderSize])
+	assert.Equal(t, byte(0x00), h.Buf[HllHeaderSize+1])
+
+	_, err = LoadHyperLogLog([]byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xfe"))
+	assert.NoError(t, err)
+	h, _ = LoadHyperLogLog([]byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xfe"))
+	_, err = h.Registers()
+	assert.Equal(t, ErrHllCorrupted, err)
+	_, err = LoadHyperLogLog([]byte("HYLL\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"))
+	assert.Equal(t, ErrHllInvalid, err)
+	_, err = LoadHyperLogLog([]byte("hello"))
+	assert.Equal(t, ErrHllInvalid, err)
+}
+
+func TestHyperLogLogCount(t *testing.T) {
+	h := MakeHyperLogLog()
+	count, err := h.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	for _, n := range []int{10, 1000, 100000} {
+		h = MakeHyperLogLog()
+		for i := 0; i < n; i++ {
+			_, err = h.Add([]byte("elem:" + strconv.Itoa(i)))
+			assert.NoError(t, err)
+		}
+		count, err = h.Count()
+		assert.NoError(t, err)
+		assert.InEpsilon(t, n, count, 0.03, n)
+
+		// The cache is valid until registers are updated
+		assert.Equal(t, byte(0), h.Buf[15]&0x80)
+		updated, _ := h.Add([]byte("elem:0"))
+		assert.False(t, updated)
+		assert.Equal(t, byte(0), h.Buf[15]&0x80)
+	}
+	assert.Equal(t, HllDense, h.Encoding())
+
+	// Sparse and dense HLLs merge into the same registers
+	sparse := MakeHyperLogLog()
+	sparse.Add([]byte("a"), []byte("b"), []byte("elem:0"))
+	regs := make([]uint8, HllRegisters)
+	assert.NoError(t, sparse.Merge(regs))
+	assert.NoError(t, h.Merge(regs))
+	assert.InEpsilon(t, 100002, HllCount(regs), 0.03)
+}
@@ -0,0 +1,149 @@
+package algo
+
+import "strings"
+
+type LcsMatch struct {
+	AStart int
+	AEnd   int
+	BStart int
+	BEnd   int
+}
+
+func (m LcsMatch) Len() int {
+	return m.AEnd - m.AStart + 1
+}
+
+// lcsRow returns the lengths of the longest common subsequences of `a` and every prefix of `b`, which is the last
+// row of the dynamic programming table. Only two rows are kept, so the memory is linear in the length of `b`.
+func lcsRow(a string, b string) []uint32 {
+	prev := make([]uint32, len(b)+1)
+	curr := make([]uint32, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				curr[j] = prev[j-1] + 1
+			} else {
+				curr[j] = max(prev[j], curr[j-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// lcsRowReverse is the same as lcsRow, but matches `a` with every suffix of `b` from the end, so that the length
+// for b[j:] is at j
+func lcsRowReverse(a string, b string) []uint32 {
+	prev := make([]uint32, len(b)+1)
+	curr := make([]uint32, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				curr[j] = prev[j+1] + 1
+			} else {
+				curr[j] = max(prev[j], curr[j+1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// LcsLength returns the length of the longest common subsequence of two strings
+func LcsLength(a string, b string) int {
+	return int(lcsRow(a, b)[len(b)])
+}
+
+/*
+Lcs returns the longest common subsequence of two strings with the algorithm of Hirschberg. `a` is split in halves,
+and `b` is split where the lengths of the two halves add up to the longest, so that the halves can be solved on their
+own. The memory is linear in the length of `b`, while the time is still quadratic. Among the splits with the same
+length, the first one is taken, which picks the same subsequence as the walk back of LcsIdx and Redis.
+*/
+func Lcs(a string, b string) string {
+	res := make([]byte, 0, min(len(a), len(b)))
+	return string(lcsHirschberg(a, b, res))
+}
+
+func lcsHirschberg(a string, b string, res []byte) []byte {
+	if len(a) == 0 || len(b) == 0 {
+		return res
+	}
+	if len(a) == 1 {
+		if strings.IndexByte(b, a[0]) >= 0 {
+			res = append(res, a[0])
+		}
+		return res
+	}
+
+	mid := len(a) / 2
+	left := lcsRow(a[:mid], b)
+	right := lcsRowReverse(a[mid:], b)
+
+	split := 0
+	for j := 1; j <= len(b); j++ {
+		if left[j]+right[j] > left[split]+right[split] {
+			split = j
+		}
+	}
+	res = lcsHirschberg(a[:mid], b[:split], res)
+	return lcsHirschberg(a[mid:], b[split:], res)
+}
+
+/*
+LcsIdx returns the length of the longest common subsequence of two strings, and the ranges of the matching substrings
+which are at least `minMatchLen` long. Same as Redis, the whole table of (len(a) + 1) * (len(b) + 1) cells is built,
+and walked back from the end of both strings, so the matches are ordered from the last to the first.
+*/
+func LcsIdx(a string, b string, minMatchLen int) (int, []LcsMatch) {
+	width := len(b) + 1
+	table := make([]uint32, (len(a)+1)*width)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				table[i*width+j] = table[(i-1)*width+j-1] + 1
+			} else {
+				table[i*width+j] = max(table[(i-1)*width+j], table[i*width+j-1])
+			}
+		}
+	}
+
+	matches := make([]LcsMatch, 0)
+
+	// A range is being extended while `inRange` is true
+	var (
+		m       LcsMatch
+		inRange bool
+	)
+	for i, j := len(a), len(b); i > 0 && j > 0; {
+		emit := false
+		if a[i-1] == b[j-1] {
+			if !inRange {
+				m = LcsMatch{AStart: i - 1, AEnd: i - 1, BStart: j - 1, BEnd: j - 1}
+				inRange = true
+			} else {
+				// Matches right after a match always extend its range
+				m.AStart--
+				m.BStart--
+			}
+			emit = m.AStart == 0 || m.BStart == 0
+			i--
+			j--
+		} else {
+			if table[(i-1)*width+j] > table[i*width+j-1] {
+				i--
+			} else {
+				j--
+			}
+			emit = inRange
+		}
+
+		if emit {
+			if m.Len() >= minMatchLen {
+				matches = append(matches, m)
+			}
+			inRange = false
+		}
+	}
+	return int(table[len(a)*width+len(b)]), matches
+}
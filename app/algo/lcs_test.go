@@ -0,0 +1,75 @@
+package algo
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLcs(t *testing.T) {
+	// The examples of the Redis documentation
+	a, b := "ohmytext", "mynewtext"
+	assert.Equal(t, 6, LcsLength(a, b))
+	assert.Equal(t, "mytext", Lcs(a, b))
+	n, matches := LcsIdx(a, b, 0)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, []LcsMatch{{4, 7, 5, 8}, {2, 3, 0, 1}}, matches)
+	_, matches = LcsIdx(a, b, 4)
+	assert.Equal(t, []LcsMatch{{4, 7, 5, 8}}, matches)
+
+	assert.Equal(t, 0, LcsLength("", "abc"))
+	assert.Equal(t, "", Lcs("abc", ""))
+	n, matches = LcsIdx("abc", "", 0)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, matches)
+	assert.Equal(t, "", Lcs("abc", "xyz"))
+	_, matches = LcsIdx("abc", "xyz", 0)
+	assert.Empty(t, matches)
+
+	assert.Equal(t, "abcdef", Lcs("abcdef", "abcdef"))
+	n, matches = LcsIdx("abcdef", "abcdef", 0)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, []LcsMatch{{0, 5, 0, 5}}, matches)
+	assert.Equal(t, 6, matches[0].Len())
+
+	for _, pair := range [][2]string{{"ACCGGTCGAGTGCGCGGAAGCCGGCCGAA", "GTCGTTCGGAATGCCGTTGCTCTGTAAA"}, {"xaxbxc", "abc"}, {"aaaa", "aa"}} {
+		lcs := Lcs(pair[0], pair[1])
+		assert.Equal(t, LcsLength(pair[0], pair[1]), len(lcs))
+		assert.True(t, isSubsequence(lcs, pair[0]) && isSubsequence(lcs, pair[1]))
+		n, _ := LcsIdx(pair[0], pair[1], 0)
+		assert.Equal(t, len(lcs), n)
+	}
+}
+
+func TestLcsSameAsRedis(t *testing.T) {
+	// The matches of IDX cover the subsequence picked by the walk back of Redis
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		gen := func() string {
+			s := make([]byte, r.Intn(40))
+			for i := range s {
+				s[i] = byte('a' + r.Intn(3))
+			}
+			return string(s)
+		}
+		a, b := gen(), gen()
+
+		_, matches := LcsIdx(a, b, 0)
+		expected := ""
+		for _, m := range matches {
+			expected = a[m.AStart:m.AEnd+1] + expected
+		}
+		assert.Equal(t, expected, Lcs(a, b), a+" "+b)
+	}
+}
+
+func isSubsequence(s string, of string) bool {
+	i := 0
+	for j := 0; i < len(s) && j < len(of); j++ {
+		if s[i] == of[j] {
+			i++
+		}
+	}
+	return i == len(s)
+}
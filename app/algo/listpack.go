@@ -0,0 +1,64 @@
+package algo
+
+import (
+	"encoding/binary"
+)
+
+// ListPack serializes a sequence of integers and strings into one contiguous byte slice,
+// similar to Redis's listpack. Integers are varint encoded so that small deltas only take
+// a byte or two, and strings are prefixed by their lengths.
+//
+// A ListPack carries no type information, so readers must consume the elements in the
+// same order and types as they were appended.
+type ListPack struct {
+	Buf []byte
+}
+
+func MakeListPack() *ListPack {
+	return &ListPack{Buf: make([]byte, 0, 64)}
+}
+
+func (lp *ListPack) Size() int {
+	return len(lp.Buf)
+}
+
+func (lp *ListPack) AppendInt(v int64) {
+	lp.Buf = binary.AppendVarint(lp.Buf, v)
+}
+
+func (lp *ListPack) AppendString(s string) {
+	lp.Buf = binary.AppendUvarint(lp.Buf, uint64(len(s)))
+	lp.Buf = append(lp.Buf, s...)
+}
+
+type ListPackIterator struct {
+	lp  *ListPack
+	pos int
+}
+
+func (lp *ListPack) Iterator() *ListPackIterator {
+	return &ListPackIterator{lp: lp}
+}
+
+func (it *ListPackIterator) HasNext() bool {
+	return it.pos < len(it.lp.Buf)
+}
+
+func (it *ListPackIterator) NextInt() int64 {
+	v, n := binary.Varint(it.lp.Buf[it.pos:])
+	it.pos += n
+	return v
+}
+
+func (it *ListPackIterator) NextString() string {
+	l, n := binary.Uvarint(it.lp.Buf[it.pos:])
+	it.pos += n
+	s := string(it.lp.Buf[it.pos : it.pos+int(l)])
+	it.pos += int(l)
+	return s
+}
+
+func (it *ListPackIterator) SkipString() {
+	l, n := binary.Uvarint(it.lp.Buf[it.pos:])
+	it.pos += n + int(l)
+}
@@ -0,0 +1,27 @@
+package algo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPackBasic(t *testing.T) {
+	lp := MakeListPack()
+	lp.AppendInt(0)
+	lp.AppendString("field")
+	lp.AppendInt(-300)
+	lp.AppendString("")
+	lp.AppendInt(1 << 40)
+
+	// Small integers should only take a single byte
+	assert.Equal(t, 1+6+2+1+6, lp.Size())
+
+	it := lp.Iterator()
+	assert.Equal(t, int64(0), it.NextInt())
+	assert.Equal(t, "field", it.NextString())
+	assert.Equal(t, int64(-300), it.NextInt())
+	it.SkipString()
+	assert.Equal(t, int64(1<<40), it.NextInt())
+	assert.False(t, it.HasNext())
+}
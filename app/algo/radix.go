@@ -147,6 +147,9 @@ func (r *RadixTree) searchByRange(startId string, endId string, limit int, curr
 		if nextPrefix > endIdPrefix {
 			break
 		}
+		if len(*results) >= limit {
+			break
+		}
 		r.searchByRange(startId, endId, limit, edge.DestNode, nextPrefix, results)
 	}
 }
@@ -158,6 +161,79 @@ func (r *RadixTree) SearchByRange(startId string, endId string, limit int) []*Ra
 	return results
 }
 
+// First returns the entry with the smallest id, or nil if the tree is empty
+func (r *RadixTree) First() *RadixSearchResult {
+	curr := r.Head
+	prefix := ""
+	for curr.Value == nil {
+		if len(curr.Edges) == 0 {
+			return nil
+		}
+		// A value node always comes before its children in lexical order
+		edge := curr.Edges[0]
+		prefix += edge.Prefix
+		curr = edge.DestNode
+	}
+	return &RadixSearchResult{Id: prefix, Node: curr}
+}
+
+// Last returns the entry with the largest id, or nil if the tree is empty
+func (r *RadixTree) Last() *RadixSearchResult {
+	curr := r.Head
+	prefix := ""
+	for len(curr.Edges) > 0 {
+		edge := curr.Edges[len(curr.Edges)-1]
+		prefix += edge.Prefix
+		curr = edge.DestNode
+	}
+	if curr.Value == nil {
+		return nil
+	}
+	return &RadixSearchResult{Id: prefix, Node: curr}
+}
+
+func (r *RadixTree) floor(id string, curr *RaxNode, currPrefix string) *RadixSearchResult {
+	// Visit the children from the largest to the smallest, a value node always comes
+	// before its children in lexical order
+	for i := len(curr.Edges) - 1; i >= 0; i-- {
+		edge := curr.Edges[i]
+		nextPrefix := currPrefix + edge.Prefix
+
+		idPrefix := id
+		if len(nextPrefix) < len(id) {
+			idPrefix = id[:len(nextPrefix)]
+		}
+		if nextPrefix > idPrefix {
+			continue
+		}
+		if res := r.floor(id, edge.DestNode, nextPrefix); res != nil {
+			return res
+		}
+	}
+	if curr.Value != nil {
+		return &RadixSearchResult{Id: currPrefix, Node: curr}
+	}
+	return nil
+}
+
+// Floor returns the entry with the largest id that is smaller than or equal to `id`
+func (r *RadixTree) Floor(id string) *RadixSearchResult {
+	return r.floor(id, r.Head, "")
+}
+
+func (r *RadixTree) countNodes(n *RaxNode) int {
+	numNodes := 1
+	for _, edge := range n.Edges {
+		numNodes += r.countNodes(edge.DestNode)
+	}
+	return numNodes
+}
+
+// NumNodes returns the number of nodes in the tree, including the head node
+func (r *RadixTree) NumNodes() int {
+	return r.countNodes(r.Head)
+}
+
 func (r *RadixTree) searchNode(id string) *RaxNode {
 	curr := r.Head
 	for len(id) > 0 {
@@ -179,7 +255,7 @@ func (r *RadixTree) searchNode(id string) *RaxNode {
 
 func (r *RadixTree) Search(id string) interface{} {
 	curr := r.searchNode(id)
-	if curr.Value == nil {
+	if curr == nil || curr.Value == nil {
 		return nil
 	}
 	return curr.Value
@@ -187,7 +263,7 @@ func (r *RadixTree) Search(id string) interface{} {
 
 func (r *RadixTree) Remove(id string) bool {
 	curr := r.searchNode(id)
-	if curr.Value == nil {
+	if curr == nil || curr.Value == nil {
 		// Nothing to remove if this not a value node
 		return false
 	}
@@ -122,4 +122,54 @@ func TestRadixSearchByRange(t *testing.T) {
 
 	res = r.SearchByRange("A-1", "ZZ-9", math.MaxInt)
 	assert.Equal(t, 5, len(res))
+
+	res = r.SearchByRange("A-1", "ZZ-9", 3)
+	assert.Equal(t, 3, len(res))
+	assert.Equal(t, "BB-9", res[2].Id)
+}
+
+func TestRadixFirstLastAndNumNodes(t *testing.T) {
+	r := MakeRadixTree()
+	assert.Nil(t, r.First())
+	assert.Nil(t, r.Last())
+	assert.Equal(t, 1, r.NumNodes())
+
+	r.Insert("AB", []string{})
+	r.Insert("ABC", []string{})
+	r.Insert("ABD", []string{})
+	r.Insert("B", []string{})
+
+	assert.Equal(t, "AB", r.First().Id)
+	assert.Equal(t, "B", r.Last().Id)
+
+	// * - AB - [*] - C - [*]
+	//   |         \
+	//   B          D - [*]
+	//   |
+	//  [*]
+	assert.Equal(t, 5, r.NumNodes())
+
+	r.Remove("B")
+	assert.Equal(t, "ABD", r.Last().Id)
+	assert.Equal(t, 4, r.NumNodes())
+}
+
+func TestRadixFloor(t *testing.T) {
+	r := MakeRadixTree()
+	assert.Nil(t, r.Floor("B"))
+
+	r.Insert("AA-3", []string{})
+	r.Insert("BB-9", []string{})
+	r.Insert("AC-2", []string{})
+	r.Insert("AC-20", []string{})
+	r.Insert("CC-1", []string{})
+
+	assert.Nil(t, r.Floor("AA-2"))
+	assert.Equal(t, "AA-3", r.Floor("AA-3").Id)
+	assert.Equal(t, "AA-3", r.Floor("AB").Id)
+	assert.Equal(t, "AC-2", r.Floor("AC-2").Id)
+	assert.Equal(t, "AC-20", r.Floor("AC-200").Id)
+	assert.Equal(t, "AC-2", r.Floor("AC-2-").Id)
+	assert.Equal(t, "AC-20", r.Floor("AC-3").Id)
+	assert.Equal(t, "CC-1", r.Floor("ZZ").Id)
 }
@@ -34,12 +34,20 @@ func MakeNode(member string, score int, height int) *Node {
 	return node
 }
 
+// less orders nodes by score, and nodes with the same score by member
+func (n *Node) less(score int, member string) bool {
+	return n.Score < score || (n.Score == score && n.Member < member)
+}
+
 type SkipList struct {
 	MemberMap map[string]*Node
 	Rand      *rand.Rand
 	NumElems  int
 	Head      *Node
 	Tail      *Node
+	// Scores are integers. Sets of fractional scores store them in units of 1/ScoreScale, and sets
+	// of integer scores have a scale of 0.
+	ScoreScale int
 }
 
 func MakeSkipList(seed int64) *SkipList {
@@ -171,7 +179,8 @@ func (l *SkipList) CountByRange(min int, max int) int {
 	start := l.findNodeAtLeftRange(min)
 	end := l.findNodeAtRightRange(max)
 
-	if start == nil || end == nil {
+	// The range falls in between two adjacent nodes
+	if start == nil || end == nil || start.Score > end.Score {
 		return 0
 	}
 
@@ -191,7 +200,8 @@ func (l *SkipList) FindByRange(min int, max int) []*Node {
 	start := l.findNodeAtLeftRange(min)
 	end := l.findNodeAtRightRange(max)
 
-	if start == nil || end == nil {
+	// The range falls in between two adjacent nodes
+	if start == nil || end == nil || start.Score > end.Score {
 		return nil
 	}
 
@@ -214,7 +224,7 @@ func (l *SkipList) GetRank(member string) (*Node, int) {
 	curr := l.Head
 	for curr != target {
 		next := curr.NextNodes[h]
-		if next == nil || next.Score > target.Score {
+		if next == nil || next == l.Tail || target.less(next.Score, next.Member) {
 			h--
 		} else {
 			rank += curr.Spans[h]
@@ -286,7 +296,7 @@ func (l *SkipList) Remove(member string) bool {
 	curr := l.Head
 	for h >= target.Height {
 		next := curr.NextNodes[h]
-		if next == nil || next.Score > target.Score {
+		if next == nil || next == l.Tail || !next.less(target.Score, target.Member) {
 			prevs[h] = curr
 			h--
 		} else {
@@ -322,35 +332,33 @@ func (l *SkipList) Remove(member string) bool {
 	return true
 }
 
-func (l *SkipList) findInsertionPos(score int, height int) ([]*Node, []int) {
+func (l *SkipList) findInsertionPos(member string, score int, height int) ([]*Node, []int) {
 	prevs := make([]*Node, height)
 	prevSpans := make([]int, height)
 
 	h := l.Head.Height - 1
 	curr := l.Head
 	for h >= 0 {
-		if score > curr.Score {
-			// Track nodes at each level that comes before the current node
-			if h < height {
-				prevs[h] = curr
-			}
+		// Track nodes at each level that comes before the current node
+		if h < height {
+			prevs[h] = curr
+		}
 
-			// Keep searching
-			next := curr.NextNodes[h]
-			if next == nil || score < next.Score {
-				// No need to track the distance traversed for `h > height`
-				// For `h >= height`, incr the span as we traverse down
-				if h >= height {
-					curr.Spans[h]++
-				}
-				h--
-			} else {
-				// For `h < height`, calculate the paths traversed from `prevs[h]`
-				if h+1 < height {
-					prevSpans[h+1] += curr.Spans[h]
-				}
-				curr = next
+		// Keep searching
+		next := curr.NextNodes[h]
+		if next == nil || next == l.Tail || !next.less(score, member) {
+			// No need to track the distance traversed for `h > height`
+			// For `h >= height`, incr the span as we traverse down
+			if h >= height {
+				curr.Spans[h]++
 			}
+			h--
+		} else {
+			// For `h < height`, calculate the paths traversed from `prevs[h]`
+			if h+1 < height {
+				prevSpans[h+1] += curr.Spans[h]
+			}
+			curr = next
 		}
 	}
 	return prevs, prevSpans
@@ -375,7 +383,7 @@ func (l *SkipList) Add(member string, score int, insertOnly bool) bool {
 	l.NumElems++
 	l.MemberMap[member] = newNode
 
-	prevs, prevSpans := l.findInsertionPos(score, newHeight)
+	prevs, prevSpans := l.findInsertionPos(member, score, newHeight)
 
 	for i := 0; i < newHeight; i++ {
 		var prev *Node = prevs[i]
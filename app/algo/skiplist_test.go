@@ -83,6 +83,10 @@ func TestRangeBasic(t *testing.T) {
 	assert.Equal(t, 0, sl.CountByRange(15, 100))
 	assert.Equal(t, 0, sl.CountByRange(-15, 0))
 	assert.Equal(t, 0, sl.CountByRange(15, 14))
+
+	// Test where ranges fall in between two nodes
+	assert.Equal(t, 0, sl.CountByRange(9, 11))
+	assert.Nil(t, sl.FindByRange(9, 11))
 }
 
 func TestRankBasic(t *testing.T) {
@@ -167,3 +171,30 @@ func TestRankScale(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("%03d", members[i]), n.Member)
 	}
 }
+
+func TestSameScores(t *testing.T) {
+	sl := MakeSkipList(1)
+
+	// Members with the same score are ordered by member
+	sl.Add("c", 1, true)
+	sl.Add("a", 1, true)
+	sl.Add("d", 0, true)
+	sl.Add("b", 1, true)
+	sl.Add("e", 2, true)
+
+	nodes := sl.FindByRanks(0, 4)
+	members := make([]string, 0)
+	for _, n := range nodes {
+		members = append(members, n.Member)
+	}
+	assert.Equal(t, []string{"d", "a", "b", "c", "e"}, members)
+
+	_, r := sl.GetRank("c")
+	assert.Equal(t, 3, r)
+	assert.Equal(t, 3, sl.CountByRange(1, 1))
+
+	sl.Remove("b")
+	_, r = sl.GetRank("c")
+	assert.Equal(t, 2, r)
+	assert.Equal(t, "c", sl.FindByRank(2).Member)
+}
@@ -0,0 +1,139 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const topKFingerprintSeed = 0x9e3779b97f4a7c15
+
+// TopK structures are bounded to 100000 items and 128MB of buckets
+const (
+	TopKMaxK       = 100000
+	TopKMaxBuckets = 1 << 23
+)
+
+type topKBucket struct {
+	Fingerprint uint64
+	Count       uint64
+}
+
+type TopKItem struct {
+	Item  string
+	Count uint64
+}
+
+// TopK tracks the `K` heaviest items with HeavyKeeper. Each of the `Depth` rows hashes an item to a
+// bucket holding a fingerprint and a count. A bucket held by another item decays with a probability
+// of `Decay` to the power of its count, so that light items rarely replace heavy ones. Items whose
+// estimated counts are larger than the lightest of the top K replace it.
+type TopK struct {
+	K     int
+	Width uint64
+	Depth uint64
+	Decay float64
+
+	Buckets []topKBucket
+	Items   []TopKItem
+}
+
+// TopKFits returns true if a TopK of these dimensions is not empty and within `TopKMaxK` and `TopKMaxBuckets`
+func TopKFits(k int, width uint64, depth uint64) bool {
+	return k > 0 && k <= TopKMaxK && width > 0 && depth > 0 && width <= TopKMaxBuckets/depth
+}
+
+func MakeTopK(k int, width uint64, depth uint64, decay float64) *TopK {
+	return &TopK{
+		K:       k,
+		Width:   width,
+		Depth:   depth,
+		Decay:   decay,
+		Buckets: make([]topKBucket, width*depth),
+		Items:   make([]TopKItem, 0, k),
+	}
+}
+
+// estimate updates the buckets of an item and returns its estimated count
+func (t *TopK) estimate(item []byte, incr uint64) uint64 {
+	fp := MurmurHash64A(item, topKFingerprintSeed)
+	var maxCount uint64
+
+	for row := uint64(0); row < t.Depth; row++ {
+		b := &t.Buckets[row*t.Width+MurmurHash64A(item, row)%t.Width]
+
+		if b.Count == 0 {
+			b.Fingerprint = fp
+			b.Count = incr
+		} else if b.Fingerprint == fp {
+			b.Count += incr
+		} else {
+			// Decay the bucket once per increment, and take it over once it decays to 0
+			for n := incr; n > 0; n-- {
+				if rand.Float64() < math.Pow(t.Decay, float64(b.Count)) {
+					b.Count--
+					if b.Count == 0 {
+						b.Fingerprint = fp
+						b.Count = n
+						break
+					}
+				}
+			}
+		}
+
+		if b.Fingerprint == fp {
+			maxCount = max(maxCount, b.Count)
+		}
+	}
+	return maxCount
+}
+
+// IncrBy increments the count of an item, and returns the item expelled from the top K if any
+func (t *TopK) IncrBy(item []byte, incr uint64) (string, bool) {
+	count := t.estimate(item, incr)
+	if count == 0 {
+		return "", false
+	}
+
+	for i := range t.Items {
+		if t.Items[i].Item == string(item) {
+			t.Items[i].Count = count
+			return "", false
+		}
+	}
+	if len(t.Items) < t.K {
+		t.Items = append(t.Items, TopKItem{Item: string(item), Count: count})
+		return "", false
+	}
+
+	// K is usually small, so the lightest item is found by a linear scan
+	lightest := 0
+	for i := range t.Items {
+		if t.Items[i].Count < t.Items[lightest].Count {
+			lightest = i
+		}
+	}
+	if count <= t.Items[lightest].Count {
+		return "", false
+	}
+	expelled := t.Items[lightest].Item
+	t.Items[lightest] = TopKItem{Item: string(item), Count: count}
+	return expelled, true
+}
+
+// Contains returns true if the item is in the top K
+func (t *TopK) Contains(item []byte) bool {
+	for _, i := range t.Items {
+		if i.Item == string(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the top K items ordered by counts, from the heaviest
+func (t *TopK) List() []TopKItem {
+	items := append([]TopKItem{}, t.Items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Count > items[j].Count })
+	return items
+}
@@ -0,0 +1,44 @@
+package algo
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopK(t *testing.T) {
+	topK := MakeTopK(3, 50, 5, 0.9)
+
+	_, expelled := topK.IncrBy([]byte("a"), 10)
+	assert.False(t, expelled)
+	topK.IncrBy([]byte("b"), 5)
+	topK.IncrBy([]byte("c"), 1)
+	assert.Equal(t, []TopKItem{{"a", 10}, {"b", 5}, {"c", 1}}, topK.List())
+
+	item, expelled := topK.IncrBy([]byte("d"), 2)
+	assert.True(t, expelled)
+	assert.Equal(t, "c", item)
+	assert.False(t, topK.Contains([]byte("c")))
+	assert.True(t, topK.Contains([]byte("d")))
+
+	// Heavy hitters stand out from a long tail of light items
+	topK = MakeTopK(3, 100, 5, 0.9)
+	for i := 0; i < 20000; i++ {
+		topK.IncrBy([]byte("light:"+strconv.Itoa(i)), 1)
+		if i%10 == 0 {
+			topK.IncrBy([]byte("heavy:"+strconv.Itoa(i%30)), 1)
+		}
+	}
+	for _, item := range topK.List() {
+		assert.Contains(t, item.Item, "heavy:")
+		assert.InDelta(t, 667, item.Count, 20)
+	}
+}
+
+func TestTopKFits(t *testing.T) {
+	assert.True(t, TopKFits(10, 8, 7))
+	assert.False(t, TopKFits(0, 8, 7))
+	assert.False(t, TopKFits(TopKMaxK+1, 8, 7))
+	assert.False(t, TopKFits(10, 1<<62, 4))
+}
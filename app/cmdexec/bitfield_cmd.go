@@ -0,0 +1,218 @@
+package cmdexec
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrBitfieldType     = errors.New("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+	ErrBitfieldOverflow = errors.New("ERR Invalid OVERFLOW type specified")
+	ErrBitfieldReadOnly = errors.New("ERR BITFIELD_RO only supports the GET subcommand")
+)
+
+type bitfieldOp struct {
+	SubCmd   string
+	Signed   bool
+	Width    int
+	Offset   int64
+	Value    int64
+	Overflow int
+}
+
+// parseBitfieldType parses types like i16 and u8
+func (e bitmapCmdExecutor) parseBitfieldType(arg string, op *bitfieldOp) error {
+	if len(arg) < 2 || (arg[0] != 'i' && arg[0] != 'I' && arg[0] != 'u' && arg[0] != 'U') {
+		return ErrBitfieldType
+	}
+	op.Signed = arg[0] == 'i' || arg[0] == 'I'
+
+	width, err := strconv.Atoi(arg[1:])
+	if err != nil || width < 1 || (op.Signed && width > 64) || (!op.Signed && width > 63) {
+		return ErrBitfieldType
+	}
+	op.Width = width
+	return nil
+}
+
+// parseBitfieldOffset parses offsets in bits, or in multiples of the type width when prefixed by #
+func (e bitmapCmdExecutor) parseBitfieldOffset(arg string, op *bitfieldOp) error {
+	multiplier := int64(1)
+	if strings.HasPrefix(arg, "#") {
+		multiplier = int64(op.Width)
+		arg = arg[1:]
+	}
+
+	offset, err := parseInt(arg)
+	if err != nil || offset < 0 || offset > (BitmapMaxOffset+1)/multiplier {
+		return ErrBitOffset
+	}
+	op.Offset = offset * multiplier
+	if op.Offset+int64(op.Width)-1 > BitmapMaxOffset {
+		return ErrBitOffset
+	}
+	return nil
+}
+
+/*
+Syntax:
+  - BITFIELD key [GET encoding offset | [OVERFLOW <WRAP | SAT | FAIL>] <SET encoding offset value | INCRBY encoding offset increment> [...]]
+  - BITFIELD_RO key [GET encoding offset [...]]
+
+Reply:
+  - Array reply: a list of results in the order of subcommands. GET replies the value, SET replies the old value,
+    and INCRBY replies the new value. An overflow with FAIL replies nil and leaves the field untouched.
+
+An encoding is i or u followed by the width, up to 64 bits for signed integers and 63 bits for unsigned integers.
+An offset prefixed by # is multiplied by the width.
+*/
+func (e bitmapCmdExecutor) parseBitfieldCmdArgs(cmdArgs []*resp.RespValue, readOnly bool, key *string, ops *[]*bitfieldOp) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+
+	// OVERFLOW applies to subsequent SET and INCRBY
+	overflow := algo.BitfieldWrap
+	for i := 1; i < len(cmdArgs); {
+		subCmd := strings.ToUpper(cmdArgs[i].BulkStr)
+
+		switch subCmd {
+		case "OVERFLOW":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			switch strings.ToUpper(cmdArgs[i+1].BulkStr) {
+			case "WRAP":
+				overflow = algo.BitfieldWrap
+			case "SAT":
+				overflow = algo.BitfieldSat
+			case "FAIL":
+				overflow = algo.BitfieldFail
+			default:
+				return ErrBitfieldOverflow
+			}
+			i += 2
+			continue
+		case "GET":
+			if i+2 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+		case "SET", "INCRBY":
+			if i+3 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+		default:
+			return ErrSyntax
+		}
+		if readOnly && subCmd != "GET" {
+			return ErrBitfieldReadOnly
+		}
+
+		op := &bitfieldOp{SubCmd: subCmd, Overflow: overflow}
+		err := e.parseBitfieldType(cmdArgs[i+1].BulkStr, op)
+		if err != nil {
+			return err
+		}
+		err = e.parseBitfieldOffset(cmdArgs[i+2].BulkStr, op)
+		if err != nil {
+			return err
+		}
+		i += 3
+
+		if subCmd != "GET" {
+			op.Value, err = parseInt(cmdArgs[i].BulkStr)
+			if err != nil {
+				return err
+			}
+			i++
+		}
+		*ops = append(*ops, op)
+	}
+	return nil
+}
+
+// executeBitfieldOp runs a subcommand on `b`, and returns false if the field overflows with FAIL
+func (e bitmapCmdExecutor) executeBitfieldOp(b []byte, op *bitfieldOp) (int64, bool) {
+	if op.Signed {
+		old := algo.BitmapGetSignedField(b, op.Offset, op.Width)
+		switch op.SubCmd {
+		case "GET":
+			return old, true
+		case "SET":
+			// Values that do not fit are handled the same as overflows
+			v, ok := algo.BitfieldIncrSigned(op.Value, 0, op.Width, op.Overflow)
+			if ok {
+				algo.BitmapSetField(b, op.Offset, op.Width, uint64(v))
+			}
+			return old, ok
+		default:
+			v, ok := algo.BitfieldIncrSigned(old, op.Value, op.Width, op.Overflow)
+			if ok {
+				algo.BitmapSetField(b, op.Offset, op.Width, uint64(v))
+			}
+			return v, ok
+		}
+	}
+
+	old := algo.BitmapGetField(b, op.Offset, op.Width)
+	switch op.SubCmd {
+	case "GET":
+		return int64(old), true
+	case "SET":
+		v, ok := algo.BitfieldIncrUnsigned(uint64(op.Value), 0, op.Width, op.Overflow)
+		if ok {
+			algo.BitmapSetField(b, op.Offset, op.Width, v)
+		}
+		return int64(old), ok
+	default:
+		v, ok := algo.BitfieldIncrUnsigned(old, op.Value, op.Width, op.Overflow)
+		if ok {
+			algo.BitmapSetField(b, op.Offset, op.Width, v)
+		}
+		return int64(v), ok
+	}
+}
+
+func (e bitmapCmdExecutor) executeBitfieldCmd(c *ClientInfo, cmdArgs []*resp.RespValue, readOnly bool) {
+	var (
+		key string
+		ops []*bitfieldOp
+	)
+	err := e.parseBitfieldCmdArgs(cmdArgs, readOnly, &key, &ops)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// Same as Redis, the string is zero-extended to hold all fields to be written, even if some
+	// writes fail
+	size := -1
+	for _, op := range ops {
+		if op.SubCmd != "GET" {
+			size = max(size, int((op.Offset+int64(op.Width)-1)/8)+1)
+		}
+	}
+	var b []byte
+	if size != -1 {
+		b = e.grow(key, size)
+	} else {
+		b, _ = e.lookUp(key)
+	}
+
+	res := make([]*resp.RespValue, 0, len(ops))
+	for _, op := range ops {
+		v, ok := e.executeBitfieldOp(b, op)
+		if ok {
+			res = append(res, resp.MakeInt(int(v)))
+		} else {
+			res = append(res, resp.MakeNilBulkString())
+		}
+	}
+
+	AddArrayReplyEvent(c, res)
+}
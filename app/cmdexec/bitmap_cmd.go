@@ -0,0 +1,335 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// Same as Redis, bit offsets are limited by the maximum size of strings
+const BitmapMaxOffset = StringMaxBytes*8 - 1
+
+var (
+	ErrBitOffset    = errors.New("ERR bit offset is not an integer or out of range")
+	ErrBitValue     = errors.New("ERR bit is not an integer or out of range")
+	ErrBitPosValue  = errors.New("ERR The bit argument must be 1 or 0.")
+	ErrBitOpNotSrcs = errors.New("ERR BITOP NOT must be called with a single source key.")
+)
+
+// Bitmaps are strings in the dict store. Writes convert them to bytes once, and then update them in place.
+type bitmapCmdExecutor struct{}
+
+// lookUp returns the bitmap at `key`, or an empty bitmap if it does not exist. The bitmap must not be modified.
+func (e bitmapCmdExecutor) lookUp(key string) ([]byte, bool) {
+	val, found := setCmdExecutor{}.lookUp(key)
+	if !found {
+		return nil, false
+	}
+	if val.Encoding == EncodingBytes {
+		return val.Bytes, true
+	}
+	return []byte(val.String()), true
+}
+
+// grow returns the bitmap at `key` to be updated in place, zero-extended to at least `size` bytes.
+// A missing key is created, and the TTL of an existing key is kept.
+func (e bitmapCmdExecutor) grow(key string, size int) []byte {
+	val, found := setCmdExecutor{}.lookUp(key)
+	if !found {
+		val = &DictStoreValue{}
+		db.DictStore[key] = val
+	}
+	return val.GrowBytes(size)
+}
+
+func (e bitmapCmdExecutor) parseBitOffset(arg string) (int64, error) {
+	offset, err := parseInt(arg)
+	if err != nil || offset < 0 || offset > BitmapMaxOffset {
+		return 0, ErrBitOffset
+	}
+	return offset, nil
+}
+
+// parseRange parses the optional `start end [BYTE | BIT]` range of BITCOUNT and BITPOS
+func (e bitmapCmdExecutor) parseRange(cmdArgs []*resp.RespValue, start *int64, end *int64, endGiven *bool, isBit *bool) error {
+	var err error
+
+	if len(cmdArgs) > 3 {
+		return ErrSyntax
+	}
+	if len(cmdArgs) >= 1 {
+		*start, err = parseInt(cmdArgs[0].BulkStr)
+		if err != nil {
+			return err
+		}
+	}
+	if len(cmdArgs) >= 2 {
+		*end, err = parseInt(cmdArgs[1].BulkStr)
+		if err != nil {
+			return err
+		}
+		*endGiven = true
+	}
+	if len(cmdArgs) == 3 {
+		switch strings.ToUpper(cmdArgs[2].BulkStr) {
+		case "BYTE":
+		case "BIT":
+			*isBit = true
+		default:
+			return ErrSyntax
+		}
+	}
+	return nil
+}
+
+// normalizeRange converts a range of byte or bit offsets of a bitmap of `size` bytes into a range of
+// bit offsets. Same as GETRANGE, negative offsets count from the end. The range is empty if start > end.
+func (e bitmapCmdExecutor) normalizeRange(start int64, end int64, isBit bool, size int64) (int64, int64) {
+	if isBit {
+		size *= 8
+	}
+	if start < 0 {
+		start = max(size+start, 0)
+	}
+	if end < 0 {
+		end = max(size+end, 0)
+	}
+	end = min(end, size-1)
+
+	if isBit {
+		return start, end
+	}
+	return start * 8, end*8 + 7
+}
+
+/*
+Syntax: SETBIT key offset value
+Reply:
+  - Integer reply: the original bit value at offset
+*/
+func (e bitmapCmdExecutor) parseSetBitCmdArgs(cmdArgs []*resp.RespValue, key *string, offset *int64, bit *int) error {
+	if len(cmdArgs) != 3 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*key = cmdArgs[0].BulkStr
+	*offset, err = e.parseBitOffset(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	switch cmdArgs[2].BulkStr {
+	case "0":
+		*bit = 0
+	case "1":
+		*bit = 1
+	default:
+		return ErrBitValue
+	}
+	return nil
+}
+
+func (e bitmapCmdExecutor) executeSetBitCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key    string
+		offset int64
+		bit    int
+	)
+	err := e.parseSetBitCmdArgs(cmdArgs, &key, &offset, &bit)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// The bitmap is zero-extended to hold the offset
+	b := e.grow(key, int(offset/8)+1)
+	old := algo.BitmapGetBit(b, offset)
+	algo.BitmapSetBit(b, offset, bit)
+	AddIntegerReplyEvent(c, old)
+}
+
+/*
+Syntax: GETBIT key offset
+Reply:
+  - Integer reply: the bit value at offset, or 0 if offset is beyond the string or key does not exist
+*/
+func (e bitmapCmdExecutor) executeGetBitCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	offset, err := e.parseBitOffset(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	b, _ := e.lookUp(cmdArgs[0].BulkStr)
+	AddIntegerReplyEvent(c, algo.BitmapGetBit(b, offset))
+}
+
+/*
+Syntax: BITCOUNT key [start end [BYTE | BIT]]
+Reply:
+  - Integer reply: the number of set bits in the range, or in the whole string by default
+*/
+func (e bitmapCmdExecutor) executeBitCountCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		start    int64
+		end      int64 = -1
+		endGiven bool
+		isBit    bool
+	)
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	err := e.parseRange(cmdArgs[1:], &start, &end, &endGiven, &isBit)
+	if err == nil && len(cmdArgs) == 2 {
+		err = ErrSyntax
+	}
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	b, _ := e.lookUp(cmdArgs[0].BulkStr)
+	startBit, endBit := e.normalizeRange(start, end, isBit, int64(len(b)))
+	AddIntegerReplyEvent(c, algo.BitmapCount(b, startBit, endBit))
+}
+
+/*
+Syntax: BITPOS key bit [start [end [BYTE | BIT]]]
+Reply:
+  - Integer reply: the position of the first bit set to 1 or 0 in the range, or -1 if there is none.
+    Same as Redis, when looking for 0 without an end, the string is considered padded with zeros on the right.
+*/
+func (e bitmapCmdExecutor) executeBitPosCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		start    int64
+		end      int64 = -1
+		endGiven bool
+		isBit    bool
+		bit      int
+	)
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	switch cmdArgs[1].BulkStr {
+	case "0":
+		bit = 0
+	case "1":
+		bit = 1
+	default:
+		AddErrorReplyEvent(c, ErrBitPosValue)
+		return
+	}
+	err := e.parseRange(cmdArgs[2:], &start, &end, &endGiven, &isBit)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	b, found := e.lookUp(cmdArgs[0].BulkStr)
+	if !found {
+		AddIntegerReplyEvent(c, -bit)
+		return
+	}
+
+	startBit, endBit := e.normalizeRange(start, end, isBit, int64(len(b)))
+	if startBit > endBit {
+		AddIntegerReplyEvent(c, -1)
+		return
+	}
+	pos := algo.BitmapPos(b, bit, startBit, endBit)
+	if pos == -1 && bit == 0 && !endGiven {
+		pos = int64(len(b)) * 8
+	}
+	AddIntegerReplyEvent(c, int(pos))
+}
+
+/*
+Syntax: BITOP <AND | OR | XOR | NOT> destkey key [key ...]
+Reply:
+  - Integer reply: the size of the string stored at destkey, which is the size of the longest input string
+*/
+func (e bitmapCmdExecutor) parseBitOpCmdArgs(cmdArgs []*resp.RespValue, op *int, destKey *string, keys *[]string) error {
+	if len(cmdArgs) < 3 {
+		return ErrInvalidArgs
+	}
+
+	switch strings.ToUpper(cmdArgs[0].BulkStr) {
+	case "AND":
+		*op = algo.BitmapAnd
+	case "OR":
+		*op = algo.BitmapOr
+	case "XOR":
+		*op = algo.BitmapXor
+	case "NOT":
+		*op = algo.BitmapNot
+		if len(cmdArgs) != 3 {
+			return ErrBitOpNotSrcs
+		}
+	default:
+		return ErrSyntax
+	}
+
+	*destKey = cmdArgs[1].BulkStr
+	for _, arg := range cmdArgs[2:] {
+		*keys = append(*keys, arg.BulkStr)
+	}
+	return nil
+}
+
+func (e bitmapCmdExecutor) executeBitOpCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		op      int
+		destKey string
+		keys    []string
+	)
+	err := e.parseBitOpCmdArgs(cmdArgs, &op, &destKey, &keys)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// Missing keys are empty strings
+	srcs := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		b, _ := e.lookUp(key)
+		srcs = append(srcs, b)
+	}
+	res := algo.BitmapOp(op, srcs)
+
+	// An empty result deletes the destination, and the TTL of the destination is discarded
+	if len(res) == 0 {
+		delete(db.DictStore, destKey)
+	} else {
+		val := &DictStoreValue{}
+		val.SetBytes(res)
+		db.DictStore[destKey] = val
+	}
+	AddIntegerReplyEvent(c, len(res))
+}
+
+func (e bitmapCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "SETBIT":
+		e.executeSetBitCmd(c, cmdArgs)
+	case "GETBIT":
+		e.executeGetBitCmd(c, cmdArgs)
+	case "BITCOUNT":
+		e.executeBitCountCmd(c, cmdArgs)
+	case "BITPOS":
+		e.executeBitPosCmd(c, cmdArgs)
+	case "BITOP":
+		e.executeBitOpCmd(c, cmdArgs)
+	case "BITFIELD":
+		e.executeBitfieldCmd(c, cmdArgs, false)
+	case "BITFIELD_RO":
+		e.executeBitfieldCmd(c, cmdArgs, true)
+	}
+}
@@ -0,0 +1,146 @@
+package cmdexec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitmapCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := bitmapCmdExecutor{}
+
+	// Strings are zero-extended
+	assert.Equal(t, ":0\r\n", runCmd(e, "SETBIT", "bits", "7", "1"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "SETBIT", "bits", "7", "0"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "SETBIT", "bits", "100", "1"))
+	assert.Equal(t, 13, len(db.DictStore["bits"].String()))
+	assert.Equal(t, ":1\r\n", runCmd(e, "GETBIT", "bits", "100"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "GETBIT", "bits", "10000"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "GETBIT", "missing", "0"))
+	assert.Equal(t, "-ERR bit offset is not an integer or out of range\r\n", runCmd(e, "SETBIT", "bits", "4294967296", "1"))
+	assert.Equal(t, "-ERR bit is not an integer or out of range\r\n", runCmd(e, "SETBIT", "bits", "0", "2"))
+
+	// The TTL is kept
+	db.DictStore["bits"].WillExpire = true
+	db.DictStore["bits"].ExpireTime = time.Now().Add(time.Hour)
+	runCmd(e, "SETBIT", "bits", "0", "1")
+	assert.True(t, db.DictStore["bits"].WillExpire)
+
+	db.DictStore["foo"] = MakeDictStoreValue("foobar")
+	assert.Equal(t, ":26\r\n", runCmd(e, "BITCOUNT", "foo"))
+	assert.Equal(t, ":4\r\n", runCmd(e, "BITCOUNT", "foo", "0", "0"))
+	assert.Equal(t, ":6\r\n", runCmd(e, "BITCOUNT", "foo", "1", "1"))
+	assert.Equal(t, ":7\r\n", runCmd(e, "BITCOUNT", "foo", "-2", "-1"))
+	assert.Equal(t, ":17\r\n", runCmd(e, "BITCOUNT", "foo", "5", "30", "BIT"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BITCOUNT", "foo", "3", "1"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BITCOUNT", "missing"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BITCOUNT", "foo", "0"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BITCOUNT", "foo", "0", "1", "BITS"))
+
+	db.DictStore["pos"] = MakeDictStoreValue("\xff\xf0\x00")
+	assert.Equal(t, ":12\r\n", runCmd(e, "BITPOS", "pos", "0"))
+	assert.Equal(t, ":16\r\n", runCmd(e, "BITPOS", "pos", "0", "2"))
+	assert.Equal(t, ":7\r\n", runCmd(e, "BITPOS", "pos", "1", "7", "15", "BIT"))
+	assert.Equal(t, ":-1\r\n", runCmd(e, "BITPOS", "pos", "1", "2", "-1"))
+	db.DictStore["pos"] = MakeDictStoreValue("\xff\xff")
+	assert.Equal(t, ":16\r\n", runCmd(e, "BITPOS", "pos", "0"))
+	assert.Equal(t, ":-1\r\n", runCmd(e, "BITPOS", "pos", "0", "0", "-1"))
+	assert.Equal(t, ":-1\r\n", runCmd(e, "BITPOS", "missing", "1"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BITPOS", "missing", "0"))
+	assert.Equal(t, "-ERR The bit argument must be 1 or 0.\r\n", runCmd(e, "BITPOS", "pos", "2"))
+
+	db.DictStore["a"] = MakeDictStoreValue("\x0f\xff")
+	db.DictStore["b"] = MakeDictStoreValue("\x31")
+	assert.Equal(t, ":2\r\n", runCmd(e, "BITOP", "AND", "dest", "a", "b"))
+	assert.Equal(t, "\x01\x00", db.DictStore["dest"].String())
+	assert.Equal(t, ":2\r\n", runCmd(e, "BITOP", "OR", "dest", "a", "b", "missing"))
+	assert.Equal(t, "\x3f\xff", db.DictStore["dest"].String())
+	assert.Equal(t, ":2\r\n", runCmd(e, "BITOP", "NOT", "dest", "a"))
+	assert.Equal(t, "\xf0\x00", db.DictStore["dest"].String())
+	assert.Equal(t, ":0\r\n", runCmd(e, "BITOP", "XOR", "dest", "missing"))
+	assert.NotContains(t, db.DictStore, "dest")
+	assert.Equal(t, "-ERR BITOP NOT must be called with a single source key.\r\n", runCmd(e, "BITOP", "NOT", "dest", "a", "b"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BITOP", "NAND", "dest", "a", "b"))
+}
+
+func TestBitfieldCmd(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := bitmapCmdExecutor{}
+
+	assert.Equal(t, "*2\r\n:1\r\n:0\r\n", runCmd(e, "BITFIELD", "bf", "INCRBY", "i5", "100", "1", "GET", "u4", "0"))
+	assert.Equal(t, 14, len(db.DictStore["bf"].String()))
+
+	// Counters at # offsets are packed side by side
+	assert.Equal(t, "*2\r\n:0\r\n:0\r\n", runCmd(e, "BITFIELD", "counters", "SET", "u8", "#0", "200", "SET", "u8", "#1", "100"))
+	assert.Equal(t, "\xc8\x64", db.DictStore["counters"].String())
+	assert.Equal(t, "*2\r\n:200\r\n:100\r\n", runCmd(e, "BITFIELD_RO", "counters", "GET", "u8", "#0", "GET", "u8", "8"))
+
+	// Overflows
+	assert.Equal(t, "*3\r\n:44\r\n:255\r\n$-1\r\n", runCmd(e, "BITFIELD", "counters",
+		"INCRBY", "u8", "#0", "100",
+		"OVERFLOW", "SAT", "INCRBY", "u8", "#1", "200",
+		"OVERFLOW", "FAIL", "INCRBY", "u8", "#1", "1"))
+	assert.Equal(t, "*2\r\n:44\r\n:255\r\n", runCmd(e, "BITFIELD", "counters", "GET", "u8", "0", "GET", "u8", "8"))
+	assert.Equal(t, "*2\r\n:0\r\n:-1\r\n", runCmd(e, "BITFIELD", "signed", "SET", "i8", "0", "-1", "OVERFLOW", "SAT", "SET", "i8", "0", "1000"))
+	assert.Equal(t, "*1\r\n:127\r\n", runCmd(e, "BITFIELD", "signed", "GET", "i8", "0"))
+	assert.Equal(t, "*2\r\n:0\r\n:-9223372036854775808\r\n", runCmd(e, "BITFIELD", "i64", "SET", "i64", "0", "9223372036854775807", "INCRBY", "i64", "0", "1"))
+
+	// FAIL does not write the field, but the string is still extended
+	assert.Equal(t, "*1\r\n$-1\r\n", runCmd(e, "BITFIELD", "fail", "OVERFLOW", "FAIL", "SET", "u2", "8", "4"))
+	assert.Equal(t, "\x00\x00", db.DictStore["fail"].String())
+
+	// GET does not create the key
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "BITFIELD", "missing", "GET", "i64", "0"))
+	assert.NotContains(t, db.DictStore, "missing")
+	assert.Equal(t, "*0\r\n", runCmd(e, "BITFIELD", "missing"))
+
+	assert.Equal(t, "-ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n", runCmd(e, "BITFIELD", "bf", "GET", "u64", "0"))
+	assert.Equal(t, "-ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.\r\n", runCmd(e, "BITFIELD", "bf", "GET", "x8", "0"))
+	assert.Equal(t, "-ERR bit offset is not an integer or out of range\r\n", runCmd(e, "BITFIELD", "bf", "GET", "u8", "-1"))
+	assert.Equal(t, "-ERR bit offset is not an integer or out of range\r\n", runCmd(e, "BITFIELD", "bf", "GET", "u8", "#a"))
+	assert.Equal(t, "-ERR Invalid OVERFLOW type specified\r\n", runCmd(e, "BITFIELD", "bf", "OVERFLOW", "BOUNCE"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BITFIELD", "bf", "SET", "u8", "0"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BITFIELD", "bf", "DEL", "u8", "0"))
+	assert.Equal(t, "-ERR BITFIELD_RO only supports the GET subcommand\r\n", runCmd(e, "BITFIELD_RO", "bf", "SET", "u8", "0", "1"))
+}
+
+func TestBitmapCmdsInPlace(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := bitmapCmdExecutor{}
+	s := setCmdExecutor{}
+
+	// Writes convert the string to bytes once, and later writes update the same bytes
+	runCmd(s, "SET", "bm", "foo", "EX", "100")
+	assert.Equal(t, ":0\r\n", runCmd(e, "SETBIT", "bm", "7", "1"))
+	val := db.DictStore["bm"]
+	assert.Equal(t, EncodingBytes, val.Encoding)
+	assert.True(t, val.WillExpire)
+	b := val.Bytes
+	assert.Equal(t, "*1\r\n:103\r\n", runCmd(e, "BITFIELD", "bm", "INCRBY", "u8", "0", "0"))
+	runCmd(e, "SETBIT", "bm", "15", "0")
+	assert.Same(t, &b[0], &db.DictStore["bm"].Bytes[0])
+	assert.Equal(t, "$3\r\ngno\r\n", runCmd(s, "GET", "bm"))
+
+	// Bytes are still strings for other commands
+	assert.Equal(t, ":3\r\n", runCmd(s, "STRLEN", "bm"))
+	assert.Equal(t, ":4\r\n", runCmd(s, "APPEND", "bm", "!"))
+	assert.Equal(t, "$4\r\ngno!\r\n", runCmd(s, "GET", "bm"))
+
+	// Bitmaps can still spell integers
+	runCmd(e, "SETBIT", "num", "2", "1")
+	runCmd(e, "SETBIT", "num", "3", "1")
+	assert.Equal(t, ":1\r\n", runCmd(s, "INCR", "num"))
+	runCmd(e, "SETBIT", "float", "2", "1")
+	runCmd(e, "SETBIT", "float", "3", "1")
+	assert.Equal(t, "$3\r\n0.5\r\n", runCmd(s, "INCRBYFLOAT", "float", "0.5"))
+
+	// BITOP stores its result as bytes
+	assert.Equal(t, ":4\r\n", runCmd(e, "BITOP", "NOT", "dest", "bm"))
+	assert.Equal(t, EncodingBytes, db.DictStore["dest"].Encoding)
+	assert.Equal(t, ":0\r\n", runCmd(e, "GETBIT", "dest", "1"))
+}
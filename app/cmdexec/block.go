@@ -23,16 +23,23 @@ type BlockKey struct {
 // many blocking clients.
 var clientsTimeoutTable *algo.RadixTree
 
-// but once a queue has a message, the client is removed
+// A client stays blocked on all of its keys until it is served or times out.
+// Clients blocked forever have an empty timeout id.
 var blockClients map[int]string
+var blockClientKeys map[int][]BlockKey
 var blockClientsOnKeySpace map[BlockKey][]*ClientInfo
+
+// Blocked clients whose keys have received data, and need to re-execute their commands
 var pendingClients []*ClientInfo
+var pendingClientsSet map[int]bool
 
 func MakeBlockList() {
 	clientsTimeoutTable = algo.MakeRadixTree()
 	blockClients = make(map[int]string)
+	blockClientKeys = make(map[int][]BlockKey)
 	blockClientsOnKeySpace = make(map[BlockKey][]*ClientInfo)
 	pendingClients = make([]*ClientInfo, 0)
+	pendingClientsSet = make(map[int]bool)
 }
 
 func HandleBlockedClientsTimeout() {
@@ -45,7 +52,7 @@ func HandleBlockedClientsTimeout() {
 		// Check if client is still being blocked
 		c := r.Node.Value.(*ClientInfo)
 		// Send a null reply when a timeout occurs
-		AddNullBulkStringReplyEvent(c)
+		AddNullArrayReplyEvent(c)
 		UnblockClient(c)
 		log.Println("A client timeout occurs:", c.ConnFd)
 	}
@@ -75,14 +82,51 @@ func ReprocessPendingClients() {
 		log.Println("A client is reprocessed:", c.ConnFd)
 	}
 	pendingClients = make([]*ClientInfo, 0)
+	pendingClientsSet = make(map[int]bool)
 }
 
 func UnblockClient(c *ClientInfo) {
 	timeoutId, found := blockClients[c.ConnFd]
-	if found {
-		delete(blockClients, c.ConnFd)
+	if !found {
+		return
+	}
+	delete(blockClients, c.ConnFd)
+	if timeoutId != "" {
 		clientsTimeoutTable.Remove(timeoutId)
 	}
+
+	// Remove client from all key spaces' block list
+	for _, bkey := range blockClientKeys[c.ConnFd] {
+		keyBlockList := blockClientsOnKeySpace[bkey]
+		for i, blocked := range keyBlockList {
+			if blocked.ConnFd == c.ConnFd {
+				keyBlockList = append(keyBlockList[:i], keyBlockList[i+1:]...)
+				break
+			}
+		}
+		if len(keyBlockList) == 0 {
+			delete(blockClientsOnKeySpace, bkey)
+		} else {
+			blockClientsOnKeySpace[bkey] = keyBlockList
+		}
+	}
+	delete(blockClientKeys, c.ConnFd)
+}
+
+// RemoveBlockedClient is called when the connection of a client closes, so that the client is neither
+// reprocessed nor served on a connection reusing its fd
+func RemoveBlockedClient(connFd int) {
+	UnblockClient(&ClientInfo{ConnFd: connFd})
+	if !pendingClientsSet[connFd] {
+		return
+	}
+	delete(pendingClientsSet, connFd)
+	for i, c := range pendingClients {
+		if c.ConnFd == connFd {
+			pendingClients = append(pendingClients[:i], pendingClients[i+1:]...)
+			break
+		}
+	}
 }
 
 func NotifyBlockedClientsOnKeySpace(bkey *BlockKey) {
@@ -90,14 +134,14 @@ func NotifyBlockedClientsOnKeySpace(bkey *BlockKey) {
 	if !found {
 		return
 	}
-	// Remove clients from key space block list
+	// Clients stay in the block lists until they are unblocked, so that a client that still
+	// finds nothing to read after being reprocessed is notified again later on
 	for _, c := range keyBlockList {
-		_, found := blockClients[c.ConnFd]
-		if found {
+		if !pendingClientsSet[c.ConnFd] {
+			pendingClientsSet[c.ConnFd] = true
 			pendingClients = append(pendingClients, c)
 		}
 	}
-	delete(blockClientsOnKeySpace, *bkey)
 }
 
 func BlockClientForKeys(c *ClientInfo, bkeys []*BlockKey, timeoutMs int) {
@@ -114,6 +158,13 @@ func BlockClientForKeys(c *ClientInfo, bkeys []*BlockKey, timeoutMs int) {
 			blockClientsOnKeySpace[*bkey] = []*ClientInfo{}
 		}
 		blockClientsOnKeySpace[*bkey] = append(blockClientsOnKeySpace[*bkey], c)
+		blockClientKeys[c.ConnFd] = append(blockClientKeys[c.ConnFd], *bkey)
+	}
+
+	// A timeout of zero blocks the client forever
+	if timeoutMs == 0 {
+		blockClients[c.ConnFd] = ""
+		return
 	}
 
 	// Add client to timeout table
@@ -121,3 +172,8 @@ func BlockClientForKeys(c *ClientInfo, bkeys []*BlockKey, timeoutMs int) {
 	blockClients[c.ConnFd] = timeoutId
 	clientsTimeoutTable.Insert(timeoutId, c)
 }
+
+func IsClientBlocked(connfd int) bool {
+	_, found := blockClients[connfd]
+	return found
+}
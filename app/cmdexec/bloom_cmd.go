@@ -0,0 +1,245 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// Same as RedisBloom, filters created by BF.ADD and BF.MADD use the default parameters
+const (
+	BloomDefaultErrorRate = 0.01
+	BloomDefaultCapacity  = 100
+	BloomDefaultExpansion = 2
+	BloomMaxExpansion     = 32768
+)
+
+var (
+	ErrFilterExists       = errors.New("ERR item exists")
+	ErrFilterNotFound     = errors.New("ERR not found")
+	ErrBloomErrorRate     = errors.New("ERR (0 < error rate range < 1)")
+	ErrBloomCapacity      = errors.New("ERR (capacity should be larger than 0)")
+	ErrBloomExpansion     = errors.New("ERR expansion should be greater or equal to 1")
+	ErrBloomNonScalingExp = errors.New("ERR nonscaling filters cannot expand")
+	ErrBloomFull          = errors.New("ERR non scaling filter is full")
+	ErrBloomTooLarge      = errors.New("ERR filter is too large")
+)
+
+type bloomCmdExecutor struct{}
+
+/*
+Syntax: BF.RESERVE key error_rate capacity [EXPANSION expansion] [NONSCALING]
+Reply:
+  - Simple string reply: OK
+*/
+func (e bloomCmdExecutor) parseBfReserveCmdArgs(cmdArgs []*resp.RespValue, key *string, errorRate *float64, capacity *int64, expansion *int64) error {
+	if len(cmdArgs) < 3 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	*errorRate, err = parseFloat(cmdArgs[1].BulkStr)
+	if err != nil || *errorRate <= 0 || *errorRate >= 1 {
+		return ErrBloomErrorRate
+	}
+	*capacity, err = parseInt(cmdArgs[2].BulkStr)
+	if err != nil || *capacity <= 0 {
+		return ErrBloomCapacity
+	}
+
+	*expansion = BloomDefaultExpansion
+	expansionGiven, nonScaling := false, false
+	for i := 3; i < len(cmdArgs); i++ {
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "EXPANSION":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			*expansion, err = parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil || *expansion < 1 || *expansion > BloomMaxExpansion {
+				return ErrBloomExpansion
+			}
+			expansionGiven = true
+			i++
+		case "NONSCALING":
+			nonScaling = true
+		default:
+			return ErrSyntax
+		}
+	}
+	if nonScaling {
+		if expansionGiven {
+			return ErrBloomNonScalingExp
+		}
+		*expansion = 0
+	}
+	if !algo.BloomFilterFits(*errorRate, uint64(*capacity)) {
+		return ErrBloomTooLarge
+	}
+	return nil
+}
+
+func (e bloomCmdExecutor) executeBfReserveCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key       string
+		errorRate float64
+		capacity  int64
+		expansion int64
+	)
+	err := e.parseBfReserveCmdArgs(cmdArgs, &key, &errorRate, &capacity, &expansion)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if _, found := db.BloomStore[key]; found {
+		AddErrorReplyEvent(c, ErrFilterExists)
+		return
+	}
+	db.BloomStore[key] = algo.MakeBloomFilter(errorRate, uint64(capacity), int(expansion))
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax:
+  - BF.ADD key item
+  - BF.MADD key item [item ...]
+
+Reply:
+  - Integer reply: 1 if the item is added, or 0 if it might have been added, for BF.ADD
+  - Array reply: a list of the integer replies for each item, or errors if the filter is full, for BF.MADD
+
+The filter is created with the default parameters if it does not exist.
+*/
+func (e bloomCmdExecutor) executeBfAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue, multi bool) {
+	if len(cmdArgs) < 2 || (!multi && len(cmdArgs) != 2) {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	filter, found := db.BloomStore[key]
+	if !found {
+		filter = algo.MakeBloomFilter(BloomDefaultErrorRate, BloomDefaultCapacity, BloomDefaultExpansion)
+		db.BloomStore[key] = filter
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[1:] {
+		added, err := filter.Add([]byte(arg.BulkStr))
+		if err == algo.ErrBloomTooLarge {
+			res = append(res, resp.MakeErorr(ErrBloomTooLarge.Error()))
+		} else if err != nil {
+			res = append(res, resp.MakeErorr(ErrBloomFull.Error()))
+		} else if added {
+			res = append(res, resp.MakeInt(1))
+		} else {
+			res = append(res, resp.MakeInt(0))
+		}
+	}
+
+	if multi {
+		AddArrayReplyEvent(c, res)
+	} else {
+		AddReplyEvent(c, res[0])
+	}
+}
+
+/*
+Syntax:
+  - BF.EXISTS key item
+  - BF.MEXISTS key item [item ...]
+
+Reply:
+  - Integer reply: 1 if the item might have been added, or 0 if it definitely has not, for BF.EXISTS
+  - Array reply: a list of the integer replies for each item for BF.MEXISTS
+*/
+func (e bloomCmdExecutor) executeBfExistsCmd(c *ClientInfo, cmdArgs []*resp.RespValue, multi bool) {
+	if len(cmdArgs) < 2 || (!multi && len(cmdArgs) != 2) {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	filter, found := db.BloomStore[cmdArgs[0].BulkStr]
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[1:] {
+		if found && filter.Exists([]byte(arg.BulkStr)) {
+			res = append(res, resp.MakeInt(1))
+		} else {
+			res = append(res, resp.MakeInt(0))
+		}
+	}
+
+	if multi {
+		AddArrayReplyEvent(c, res)
+	} else {
+		AddReplyEvent(c, res[0])
+	}
+}
+
+/*
+Syntax: BF.INFO key [CAPACITY | SIZE | FILTERS | ITEMS | EXPANSION]
+Reply:
+  - Array reply: a list of field names and values, or only the value of the requested field.
+    Expansion is nil for non-scaling filters.
+*/
+func (e bloomCmdExecutor) executeBfInfoCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	filter, found := db.BloomStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrFilterNotFound)
+		return
+	}
+
+	expansion := resp.MakeNilBulkString()
+	if filter.Expansion != 0 {
+		expansion = resp.MakeInt(filter.Expansion)
+	}
+	fields := []struct {
+		option string
+		name   string
+		value  *resp.RespValue
+	}{
+		{"CAPACITY", "Capacity", resp.MakeInt(int(filter.Capacity()))},
+		{"SIZE", "Size", resp.MakeInt(filter.Size())},
+		{"FILTERS", "Number of filters", resp.MakeInt(len(filter.Layers))},
+		{"ITEMS", "Number of items inserted", resp.MakeInt(int(filter.NumItems))},
+		{"EXPANSION", "Expansion rate", expansion},
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, field := range fields {
+		if len(cmdArgs) == 1 {
+			res = append(res, resp.MakeBulkString(field.name), field.value)
+		} else if strings.ToUpper(cmdArgs[1].BulkStr) == field.option {
+			res = append(res, field.value)
+		}
+	}
+	if len(res) == 0 {
+		AddErrorReplyEvent(c, ErrSyntax)
+		return
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func (e bloomCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "BF.RESERVE":
+		e.executeBfReserveCmd(c, cmdArgs)
+	case "BF.ADD":
+		e.executeBfAddCmd(c, cmdArgs, false)
+	case "BF.MADD":
+		e.executeBfAddCmd(c, cmdArgs, true)
+	case "BF.EXISTS":
+		e.executeBfExistsCmd(c, cmdArgs, false)
+	case "BF.MEXISTS":
+		e.executeBfExistsCmd(c, cmdArgs, true)
+	case "BF.INFO":
+		e.executeBfInfoCmd(c, cmdArgs)
+	}
+}
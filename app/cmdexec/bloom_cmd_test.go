@@ -0,0 +1,48 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := bloomCmdExecutor{}
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "BF.ADD", "bf", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BF.ADD", "bf", "a"))
+	assert.Equal(t, "*3\r\n:0\r\n:1\r\n:1\r\n", runCmd(e, "BF.MADD", "bf", "a", "b", "c"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "BF.EXISTS", "bf", "b"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BF.EXISTS", "bf", "d"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "BF.EXISTS", "missing", "a"))
+	assert.Equal(t, "*2\r\n:1\r\n:0\r\n", runCmd(e, "BF.MEXISTS", "bf", "c", "d"))
+	assert.Equal(t, "*10\r\n$8\r\nCapacity\r\n:100\r\n$4\r\nSize\r\n:120\r\n$17\r\nNumber of filters\r\n:1\r\n"+
+		"$24\r\nNumber of items inserted\r\n:3\r\n$14\r\nExpansion rate\r\n:2\r\n", runCmd(e, "BF.INFO", "bf"))
+	assert.Equal(t, "*1\r\n:3\r\n", runCmd(e, "BF.INFO", "bf", "items"))
+	assert.Equal(t, "-ERR not found\r\n", runCmd(e, "BF.INFO", "missing"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "BF.RESERVE", "small", "0.001", "2", "NONSCALING"))
+	assert.Equal(t, "-ERR item exists\r\n", runCmd(e, "BF.RESERVE", "small", "0.001", "2"))
+	assert.Equal(t, "*3\r\n:1\r\n:1\r\n-ERR non scaling filter is full\r\n", runCmd(e, "BF.MADD", "small", "a", "b", "c"))
+	assert.Equal(t, "*1\r\n$-1\r\n", runCmd(e, "BF.INFO", "small", "EXPANSION"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "BF.RESERVE", "scaling", "0.01", "2", "EXPANSION", "3"))
+	runCmd(e, "BF.MADD", "scaling", "a", "b", "c")
+	assert.Equal(t, "*1\r\n:8\r\n", runCmd(e, "BF.INFO", "scaling", "CAPACITY"))
+	assert.Equal(t, "*1\r\n:2\r\n", runCmd(e, "BF.INFO", "scaling", "FILTERS"))
+
+	assert.Equal(t, "-ERR (0 < error rate range < 1)\r\n", runCmd(e, "BF.RESERVE", "new", "1", "100"))
+	assert.Equal(t, "-ERR (capacity should be larger than 0)\r\n", runCmd(e, "BF.RESERVE", "new", "0.1", "0"))
+	assert.Equal(t, "-ERR expansion should be greater or equal to 1\r\n", runCmd(e, "BF.RESERVE", "new", "0.1", "10", "EXPANSION", "0"))
+	assert.Equal(t, "-ERR nonscaling filters cannot expand\r\n", runCmd(e, "BF.RESERVE", "new", "0.1", "10", "EXPANSION", "2", "NONSCALING"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "BF.RESERVE", "new", "0.1", "10", "SCALING"))
+	assert.Equal(t, "-ERR filter is too large\r\n", runCmd(e, "BF.RESERVE", "new", "0.01", "9223372036854775807"))
+	assert.Equal(t, "-ERR expansion should be greater or equal to 1\r\n", runCmd(e, "BF.RESERVE", "new", "0.01", "1", "EXPANSION", "4611686018427387904"))
+
+	// Filters stop scaling before they exceed the maximum size
+	assert.Equal(t, "+OK\r\n", runCmd(e, "BF.RESERVE", "huge", "0.01", "10000", "EXPANSION", "32768"))
+	db.BloomStore["huge"].Layers[0].NumItems = 10000
+	assert.Equal(t, "-ERR filter is too large\r\n", runCmd(e, "BF.ADD", "huge", "a"))
+}
@@ -0,0 +1,256 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrCmsKeyExists     = errors.New("CMS: key already exists")
+	ErrCmsKeyNotFound   = errors.New("CMS: key does not exist")
+	ErrCmsWidth         = errors.New("CMS: invalid width")
+	ErrCmsDepth         = errors.New("CMS: invalid depth")
+	ErrCmsErrorRate     = errors.New("CMS: invalid overestimation value")
+	ErrCmsProb          = errors.New("CMS: invalid prob value")
+	ErrCmsNumber        = errors.New("CMS: Cannot parse number")
+	ErrCmsNumKeys       = errors.New("CMS: invalid numkeys")
+	ErrCmsWeight        = errors.New("CMS: invalid weight value")
+	ErrCmsDimsDifferent = errors.New("CMS: width/depth is not equal")
+	ErrCmsTooLarge      = errors.New("CMS: width*depth is too large")
+	ErrCmsIncrOverflow  = errors.New("CMS: INCRBY overflow")
+	ErrCmsMergeOverflow = errors.New("CMS: MERGE overflow")
+)
+
+type cmsCmdExecutor struct{}
+
+func (e cmsCmdExecutor) reserve(c *ClientInfo, key string, sketch *algo.CountMinSketch) {
+	if _, found := db.CmsStore[key]; found {
+		AddErrorReplyEvent(c, ErrCmsKeyExists)
+		return
+	}
+	db.CmsStore[key] = sketch
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax: CMS.INITBYDIM key width depth
+Reply:
+  - Simple string reply: OK
+*/
+func (e cmsCmdExecutor) executeCmsInitByDimCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	width, err := parseInt(cmdArgs[1].BulkStr)
+	if err != nil || width <= 0 {
+		AddErrorReplyEvent(c, ErrCmsWidth)
+		return
+	}
+	depth, err := parseInt(cmdArgs[2].BulkStr)
+	if err != nil || depth <= 0 {
+		AddErrorReplyEvent(c, ErrCmsDepth)
+		return
+	}
+	if !algo.CountMinSketchFits(uint64(width), uint64(depth)) {
+		AddErrorReplyEvent(c, ErrCmsTooLarge)
+		return
+	}
+	e.reserve(c, cmdArgs[0].BulkStr, algo.MakeCountMinSketch(uint64(width), uint64(depth)))
+}
+
+/*
+Syntax: CMS.INITBYPROB key error probability
+Reply:
+  - Simple string reply: OK
+
+Estimates exceed counts by at most `error` of the total count, with a probability of failure of `probability`.
+*/
+func (e cmsCmdExecutor) executeCmsInitByProbCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	errorRate, err := parseFloat(cmdArgs[1].BulkStr)
+	if err != nil || errorRate <= 0 || errorRate >= 1 {
+		AddErrorReplyEvent(c, ErrCmsErrorRate)
+		return
+	}
+	prob, err := parseFloat(cmdArgs[2].BulkStr)
+	if err != nil || prob <= 0 || prob >= 1 {
+		AddErrorReplyEvent(c, ErrCmsProb)
+		return
+	}
+	width, depth := algo.CountMinSketchDimsByProb(errorRate, prob)
+	if !algo.CountMinSketchFits(width, depth) {
+		AddErrorReplyEvent(c, ErrCmsTooLarge)
+		return
+	}
+	e.reserve(c, cmdArgs[0].BulkStr, algo.MakeCountMinSketch(width, depth))
+}
+
+/*
+Syntax: CMS.INCRBY key item increment [item increment ...]
+Reply:
+  - Array reply: a list of the estimated counts of each item after the increments
+*/
+func (e cmsCmdExecutor) executeCmsIncrByCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 3 || len(cmdArgs)%2 != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	sketch, found := db.CmsStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrCmsKeyNotFound)
+		return
+	}
+
+	// Validate all increments before counting any item
+	incrs := make([]uint64, 0, len(cmdArgs)/2)
+	for i := 2; i < len(cmdArgs); i += 2 {
+		incr, err := parseInt(cmdArgs[i].BulkStr)
+		if err != nil || incr < 0 {
+			AddErrorReplyEvent(c, ErrCmsNumber)
+			return
+		}
+		incrs = append(incrs, uint64(incr))
+	}
+
+	items := make([][]byte, 0, len(incrs))
+	for i := 1; i < len(cmdArgs); i += 2 {
+		items = append(items, []byte(cmdArgs[i].BulkStr))
+	}
+	counts, err := sketch.MultiIncrBy(items, incrs)
+	if err != nil {
+		AddErrorReplyEvent(c, ErrCmsIncrOverflow)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(counts))
+	for _, count := range counts {
+		res = append(res, resp.MakeInt(int(count)))
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: CMS.QUERY key item [item ...]
+Reply:
+  - Array reply: a list of the estimated counts of each item
+*/
+func (e cmsCmdExecutor) executeCmsQueryCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	sketch, found := db.CmsStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrCmsKeyNotFound)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[1:] {
+		res = append(res, resp.MakeInt(int(sketch.Query([]byte(arg.BulkStr)))))
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: CMS.MERGE destination numKeys source [source ...] [WEIGHTS weight [weight ...]]
+Reply:
+  - Simple string reply: OK
+
+The destination must exist, and all sketches must have the same width and depth. The destination
+is overwritten by the weighted sum of the sources, and it can be one of the sources.
+*/
+func (e cmsCmdExecutor) parseCmsMergeCmdArgs(cmdArgs []*resp.RespValue, dest *string, srcs *[]string, weights *[]uint64) error {
+	if len(cmdArgs) < 3 {
+		return ErrInvalidArgs
+	}
+	*dest = cmdArgs[0].BulkStr
+
+	numKeys, err := parseInt(cmdArgs[1].BulkStr)
+	if err != nil || numKeys <= 0 || int(numKeys) > len(cmdArgs)-2 {
+		return ErrCmsNumKeys
+	}
+	for _, arg := range cmdArgs[2 : 2+numKeys] {
+		*srcs = append(*srcs, arg.BulkStr)
+	}
+
+	rest := cmdArgs[2+numKeys:]
+	if len(rest) == 0 {
+		for range *srcs {
+			*weights = append(*weights, 1)
+		}
+		return nil
+	}
+	if strings.ToUpper(rest[0].BulkStr) != "WEIGHTS" || len(rest)-1 != int(numKeys) {
+		return ErrSyntax
+	}
+	for _, arg := range rest[1:] {
+		weight, err := parseInt(arg.BulkStr)
+		if err != nil || weight < 0 {
+			return ErrCmsWeight
+		}
+		*weights = append(*weights, uint64(weight))
+	}
+	return nil
+}
+
+func (e cmsCmdExecutor) executeCmsMergeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		dest    string
+		srcs    []string
+		weights []uint64
+	)
+	err := e.parseCmsMergeCmdArgs(cmdArgs, &dest, &srcs, &weights)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	destSketch, found := db.CmsStore[dest]
+	if !found {
+		AddErrorReplyEvent(c, ErrCmsKeyNotFound)
+		return
+	}
+	sketches := make([]*algo.CountMinSketch, 0, len(srcs))
+	for _, src := range srcs {
+		sketch, found := db.CmsStore[src]
+		if !found {
+			AddErrorReplyEvent(c, ErrCmsKeyNotFound)
+			return
+		}
+		sketches = append(sketches, sketch)
+	}
+
+	err = destSketch.Merge(sketches, weights)
+	if err == algo.ErrCountMinSketchDims {
+		AddErrorReplyEvent(c, ErrCmsDimsDifferent)
+		return
+	}
+	if err != nil {
+		AddErrorReplyEvent(c, ErrCmsMergeOverflow)
+		return
+	}
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+func (e cmsCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "CMS.INITBYDIM":
+		e.executeCmsInitByDimCmd(c, cmdArgs)
+	case "CMS.INITBYPROB":
+		e.executeCmsInitByProbCmd(c, cmdArgs)
+	case "CMS.INCRBY":
+		e.executeCmsIncrByCmd(c, cmdArgs)
+	case "CMS.QUERY":
+		e.executeCmsQueryCmd(c, cmdArgs)
+	case "CMS.MERGE":
+		e.executeCmsMergeCmd(c, cmdArgs)
+	}
+}
@@ -0,0 +1,51 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmsCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := cmsCmdExecutor{}
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.INITBYDIM", "a", "2000", "5"))
+	assert.Equal(t, "-CMS: key already exists\r\n", runCmd(e, "CMS.INITBYDIM", "a", "2000", "5"))
+	assert.Equal(t, "*2\r\n:3\r\n:1\r\n", runCmd(e, "CMS.INCRBY", "a", "x", "3", "y", "1"))
+	assert.Equal(t, "*1\r\n:5\r\n", runCmd(e, "CMS.INCRBY", "a", "x", "2"))
+	assert.Equal(t, "*3\r\n:5\r\n:1\r\n:0\r\n", runCmd(e, "CMS.QUERY", "a", "x", "y", "z"))
+	assert.Equal(t, "-CMS: Cannot parse number\r\n", runCmd(e, "CMS.INCRBY", "a", "x", "1", "y", "one"))
+	assert.Equal(t, "*1\r\n:5\r\n", runCmd(e, "CMS.QUERY", "a", "x"))
+	assert.Equal(t, "-CMS: key does not exist\r\n", runCmd(e, "CMS.QUERY", "missing", "x"))
+
+	// An error rate of 0.001 and a probability of 0.01 make a sketch of 2000 x 7
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.INITBYPROB", "b", "0.001", "0.01"))
+	assert.Equal(t, "-CMS: width/depth is not equal\r\n", runCmd(e, "CMS.MERGE", "b", "1", "a"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.INITBYDIM", "c", "2000", "5"))
+	runCmd(e, "CMS.INCRBY", "c", "x", "1", "z", "4")
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.MERGE", "c", "2", "a", "c", "WEIGHTS", "2", "1"))
+	assert.Equal(t, "*3\r\n:11\r\n:2\r\n:4\r\n", runCmd(e, "CMS.QUERY", "c", "x", "y", "z"))
+
+	assert.Equal(t, "-CMS: key does not exist\r\n", runCmd(e, "CMS.MERGE", "c", "2", "a", "missing"))
+	assert.Equal(t, "-CMS: invalid numkeys\r\n", runCmd(e, "CMS.MERGE", "c", "3", "a", "c"))
+	assert.Equal(t, "-CMS: invalid weight value\r\n", runCmd(e, "CMS.MERGE", "c", "1", "a", "WEIGHTS", "-1"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "CMS.MERGE", "c", "1", "a", "WEIGHTS", "1", "2"))
+	assert.Equal(t, "-CMS: invalid width\r\n", runCmd(e, "CMS.INITBYDIM", "d", "0", "5"))
+	assert.Equal(t, "-CMS: invalid depth\r\n", runCmd(e, "CMS.INITBYDIM", "d", "10", "x"))
+	assert.Equal(t, "-CMS: invalid overestimation value\r\n", runCmd(e, "CMS.INITBYPROB", "d", "1", "0.01"))
+	assert.Equal(t, "-CMS: invalid prob value\r\n", runCmd(e, "CMS.INITBYPROB", "d", "0.01", "0"))
+
+	// Sketches are bounded, and counters do not wrap around
+	assert.Equal(t, "-CMS: width*depth is too large\r\n", runCmd(e, "CMS.INITBYDIM", "d", "4611686018427387904", "4"))
+	assert.Equal(t, "-CMS: width*depth is too large\r\n", runCmd(e, "CMS.INITBYPROB", "d", "1e-300", "0.01"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.INITBYDIM", "d", "10", "2"))
+	assert.Equal(t, "*1\r\n:9223372036854775807\r\n", runCmd(e, "CMS.INCRBY", "d", "x", "9223372036854775807"))
+	assert.Equal(t, "-CMS: INCRBY overflow\r\n", runCmd(e, "CMS.INCRBY", "d", "x", "1"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CMS.INITBYDIM", "e", "10", "2"))
+	assert.Equal(t, "-CMS: INCRBY overflow\r\n", runCmd(e, "CMS.INCRBY", "e", "y", "1", "x", "9223372036854775807"))
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "CMS.QUERY", "e", "y"))
+	assert.Equal(t, "-CMS: MERGE overflow\r\n", runCmd(e, "CMS.MERGE", "e", "1", "d", "WEIGHTS", "2"))
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "CMS.QUERY", "e", "x"))
+}
@@ -0,0 +1,49 @@
+package cmdexec
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/config"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+type configCmdExecutor struct{}
+
+/*
+Syntax: CONFIG GET parameter [parameter ...]
+Reply:
+  - Array reply: the names and the values of the effective config parameters matching any of the glob patterns
+*/
+func (e configCmdExecutor) executeConfigGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) == 0 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, name := range config.Names() {
+		for _, arg := range cmdArgs {
+			if matched, _ := path.Match(strings.ToLower(arg.BulkStr), name); matched {
+				value, _ := config.Current.Get(name)
+				res = append(res, resp.MakeBulkString(name), resp.MakeBulkString(value))
+				break
+			}
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func (e configCmdExecutor) Execute(c *ClientInfo, _ string, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) == 0 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	switch strings.ToUpper(cmdArgs[0].BulkStr) {
+	case "GET":
+		e.executeConfigGetCmd(c, cmdArgs[1:])
+	default:
+		AddErrorReplyEvent(c, fmt.Errorf("ERR unknown subcommand '%s'", cmdArgs[0].BulkStr))
+	}
+}
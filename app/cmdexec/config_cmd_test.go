@@ -0,0 +1,24 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigCmds(t *testing.T) {
+	MakeEventBus()
+	e := configCmdExecutor{}
+
+	defer func(cfg *config.Config) { config.Current = cfg }(config.Current)
+	config.Current = config.Default()
+	config.Current.Port = 7000
+
+	assert.Equal(t, "*2\r\n$4\r\nport\r\n$4\r\n7000\r\n", runCmd(e, "CONFIG", "GET", "port"))
+	assert.Equal(t, "*4\r\n$10\r\nappendonly\r\n$2\r\nno\r\n$4\r\nport\r\n$4\r\n7000\r\n", runCmd(e, "CONFIG", "get", "PORT", "appendo*", "port"))
+	assert.Equal(t, "*4\r\n$3\r\ndir\r\n$1\r\n.\r\n$2\r\nhz\r\n$2\r\n20\r\n", runCmd(e, "CONFIG", "GET", "??", "???"))
+	assert.Equal(t, "*0\r\n", runCmd(e, "CONFIG", "GET", "save"))
+	assert.Equal(t, "-ERR unknown subcommand 'SET'\r\n", runCmd(e, "CONFIG", "SET", "port", "1"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "CONFIG", "GET"))
+}
@@ -0,0 +1,204 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// Same as RedisBloom, filters created by CF.ADD use the default parameters
+const (
+	CuckooDefaultCapacity      = 1024
+	CuckooDefaultBucketSize    = 2
+	CuckooDefaultMaxIterations = 20
+	CuckooDefaultExpansion     = 1
+	CuckooMaxBucketSize        = 255
+	CuckooMaxIterations        = 65535
+	CuckooMaxExpansion         = 32768
+)
+
+var (
+	ErrCuckooCapacity      = errors.New("ERR Capacity must be larger than 0")
+	ErrCuckooBucketSize    = errors.New("ERR Bucket size must be between 1 and 255")
+	ErrCuckooMaxIterations = errors.New("ERR MAXITERATIONS parameter needs to be a positive integer")
+	ErrCuckooExpansion     = errors.New("ERR EXPANSION parameter needs to be a non-negative integer")
+	ErrCuckooFull          = errors.New("ERR Filter is full")
+	ErrCuckooTooLarge      = errors.New("ERR filter is too large")
+)
+
+type cuckooCmdExecutor struct{}
+
+/*
+Syntax: CF.RESERVE key capacity [BUCKETSIZE bucketsize] [MAXITERATIONS maxiterations] [EXPANSION expansion]
+Reply:
+  - Simple string reply: OK
+
+The false positive rate is about 2 * bucketsize / 255, so smaller buckets are more accurate but fill up earlier.
+An expansion of 0 makes the filter non-scaling.
+*/
+func (e cuckooCmdExecutor) parseCfReserveCmdArgs(cmdArgs []*resp.RespValue, key *string, capacity *int64, bucketSize *int64, maxIterations *int64, expansion *int64) error {
+	if len(cmdArgs) < 2 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	*capacity, err = parseInt(cmdArgs[1].BulkStr)
+	if err != nil || *capacity <= 0 {
+		return ErrCuckooCapacity
+	}
+
+	*bucketSize = CuckooDefaultBucketSize
+	*maxIterations = CuckooDefaultMaxIterations
+	*expansion = CuckooDefaultExpansion
+	for i := 2; i < len(cmdArgs); i += 2 {
+		if i+1 >= len(cmdArgs) {
+			return ErrSyntax
+		}
+		v, err := parseInt(cmdArgs[i+1].BulkStr)
+
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "BUCKETSIZE":
+			if err != nil || v < 1 || v > CuckooMaxBucketSize {
+				return ErrCuckooBucketSize
+			}
+			*bucketSize = v
+		case "MAXITERATIONS":
+			if err != nil || v < 1 || v > CuckooMaxIterations {
+				return ErrCuckooMaxIterations
+			}
+			*maxIterations = v
+		case "EXPANSION":
+			if err != nil || v < 0 || v > CuckooMaxExpansion {
+				return ErrCuckooExpansion
+			}
+			*expansion = v
+		default:
+			return ErrSyntax
+		}
+	}
+	if !algo.CuckooFilterFits(uint64(*capacity), int(*bucketSize)) {
+		return ErrCuckooTooLarge
+	}
+	return nil
+}
+
+func (e cuckooCmdExecutor) executeCfReserveCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key           string
+		capacity      int64
+		bucketSize    int64
+		maxIterations int64
+		expansion     int64
+	)
+	err := e.parseCfReserveCmdArgs(cmdArgs, &key, &capacity, &bucketSize, &maxIterations, &expansion)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if _, found := db.CuckooStore[key]; found {
+		AddErrorReplyEvent(c, ErrFilterExists)
+		return
+	}
+	db.CuckooStore[key] = algo.MakeCuckooFilter(uint64(capacity), int(bucketSize), int(maxIterations), int(expansion))
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax: CF.ADD key item
+Reply:
+  - Integer reply: 1 once the item is added. An item can be added multiple times.
+
+The filter is created with the default parameters if it does not exist.
+*/
+func (e cuckooCmdExecutor) executeCfAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	filter, found := db.CuckooStore[key]
+	if !found {
+		filter = algo.MakeCuckooFilter(CuckooDefaultCapacity, CuckooDefaultBucketSize, CuckooDefaultMaxIterations, CuckooDefaultExpansion)
+		db.CuckooStore[key] = filter
+	}
+	err := filter.Add([]byte(cmdArgs[1].BulkStr))
+	if err == algo.ErrCuckooTooLarge {
+		AddErrorReplyEvent(c, ErrCuckooTooLarge)
+		return
+	}
+	if err != nil {
+		AddErrorReplyEvent(c, ErrCuckooFull)
+		return
+	}
+	AddIntegerReplyEvent(c, 1)
+}
+
+/*
+Syntax: CF.DEL key item
+Reply:
+  - Integer reply: 1 if an occurrence of the item is deleted, or 0 if it is not found
+
+Only items that have been added should be deleted, otherwise other items sharing fingerprints might be deleted.
+*/
+func (e cuckooCmdExecutor) executeCfDelCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	filter, found := db.CuckooStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrFilterNotFound)
+		return
+	}
+	if filter.Delete([]byte(cmdArgs[1].BulkStr)) {
+		AddIntegerReplyEvent(c, 1)
+	} else {
+		AddIntegerReplyEvent(c, 0)
+	}
+}
+
+/*
+Syntax:
+  - CF.EXISTS key item
+  - CF.COUNT key item
+
+Reply:
+  - Integer reply: 1 if the item might have been added, or 0 if it definitely has not, for CF.EXISTS
+  - Integer reply: the number of times the item might have been added for CF.COUNT
+*/
+func (e cuckooCmdExecutor) executeCfCountCmd(c *ClientInfo, cmdArgs []*resp.RespValue, existsOnly bool) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	filter, found := db.CuckooStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+
+	count := filter.Count([]byte(cmdArgs[1].BulkStr))
+	if existsOnly {
+		count = min(count, 1)
+	}
+	AddIntegerReplyEvent(c, count)
+}
+
+func (e cuckooCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "CF.RESERVE":
+		e.executeCfReserveCmd(c, cmdArgs)
+	case "CF.ADD":
+		e.executeCfAddCmd(c, cmdArgs)
+	case "CF.DEL":
+		e.executeCfDelCmd(c, cmdArgs)
+	case "CF.EXISTS":
+		e.executeCfCountCmd(c, cmdArgs, true)
+	case "CF.COUNT":
+		e.executeCfCountCmd(c, cmdArgs, false)
+	}
+}
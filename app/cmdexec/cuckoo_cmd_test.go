@@ -0,0 +1,44 @@
+package cmdexec
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckooCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := cuckooCmdExecutor{}
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "CF.ADD", "cf", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "CF.ADD", "cf", "a"))
+	assert.Equal(t, ":2\r\n", runCmd(e, "CF.COUNT", "cf", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "CF.EXISTS", "cf", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "CF.EXISTS", "cf", "b"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "CF.COUNT", "missing", "a"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "CF.DEL", "cf", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "CF.DEL", "cf", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "CF.DEL", "cf", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "CF.EXISTS", "cf", "a"))
+	assert.Equal(t, "-ERR not found\r\n", runCmd(e, "CF.DEL", "missing", "a"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "CF.RESERVE", "small", "4", "BUCKETSIZE", "1", "MAXITERATIONS", "5", "EXPANSION", "0"))
+	assert.Equal(t, "-ERR item exists\r\n", runCmd(e, "CF.RESERVE", "small", "4"))
+	reply := ""
+	for i := 0; i < 100 && reply != "-ERR Filter is full\r\n"; i++ {
+		reply = runCmd(e, "CF.ADD", "small", strconv.Itoa(i))
+	}
+	assert.Equal(t, "-ERR Filter is full\r\n", reply)
+
+	assert.Equal(t, "-ERR Capacity must be larger than 0\r\n", runCmd(e, "CF.RESERVE", "new", "0"))
+	assert.Equal(t, "-ERR Bucket size must be between 1 and 255\r\n", runCmd(e, "CF.RESERVE", "new", "10", "BUCKETSIZE", "256"))
+	assert.Equal(t, "-ERR MAXITERATIONS parameter needs to be a positive integer\r\n", runCmd(e, "CF.RESERVE", "new", "10", "MAXITERATIONS", "0"))
+	assert.Equal(t, "-ERR EXPANSION parameter needs to be a non-negative integer\r\n", runCmd(e, "CF.RESERVE", "new", "10", "EXPANSION", "-1"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "CF.RESERVE", "new", "10", "BUCKETSIZE"))
+	assert.Equal(t, "-ERR filter is too large\r\n", runCmd(e, "CF.RESERVE", "new", "9223372036854775807"))
+	assert.Equal(t, "-ERR MAXITERATIONS parameter needs to be a positive integer\r\n", runCmd(e, "CF.RESERVE", "new", "2", "MAXITERATIONS", "9223372036854775807"))
+	assert.Equal(t, "-ERR EXPANSION parameter needs to be a non-negative integer\r\n", runCmd(e, "CF.RESERVE", "new", "2", "EXPANSION", "32769"))
+}
@@ -59,6 +59,10 @@ func AddEmptyArrayReplyEvent(c *ClientInfo) {
 	AddReplyEvent(c, &resp.RespValue{DataType: resp.TypeArrays, Array: []*resp.RespValue{}})
 }
 
+func AddNullArrayReplyEvent(c *ClientInfo) {
+	AddReplyEvent(c, resp.MakeNilArray())
+}
+
 func AddArrayReplyEvent(c *ClientInfo, arr []*resp.RespValue) {
 	AddReplyEvent(c, &resp.RespValue{DataType: resp.TypeArrays, Array: arr})
 }
@@ -10,28 +10,123 @@ import (
 var (
 	ErrInvalidArgs = errors.New("invalid args")
 	ErrOverflow    = errors.New("overflow")
+	ErrNoSuchKey   = errors.New("ERR no such key")
+	ErrSyntax      = errors.New("ERR syntax error")
 )
 
 var CmdLookupTable = map[string]cmdExecutor{
-	"COMMAND":       &pingCmdExecutor{},
-	"PING":          &pingCmdExecutor{},
-	"ECHO":          &echoCmdExecutor{},
-	"SET":           &setCmdExecutor{},
-	"GET":           &setCmdExecutor{},
-	"ZADD":          &zsetCmdExecutor{},
-	"ZREM":          &zsetCmdExecutor{},
-	"ZSCORE":        &zsetCmdExecutor{},
-	"ZCOUNT":        &zsetCmdExecutor{},
-	"ZRANGEBYSCORE": &zsetCmdExecutor{},
-	"ZRANK":         &zsetCmdExecutor{},
-	"ZRANGE":        &zsetCmdExecutor{},
-	"XADD":          &streamCmdExecutor{},
-	"XRANGE":        &streamCmdExecutor{},
-	"XREAD":         &streamCmdExecutor{},
-	"GEOADD":        &geoCmdExecutor{},
-	"GEODIST":       &geoCmdExecutor{},
-	"GEOHASH":       &geoCmdExecutor{},
-	"GEORADIUS":     &geoCmdExecutor{},
+	"COMMAND":              &pingCmdExecutor{},
+	"PING":                 &pingCmdExecutor{},
+	"ECHO":                 &echoCmdExecutor{},
+	"CONFIG":               &configCmdExecutor{},
+	"SET":                  &setCmdExecutor{},
+	"GET":                  &setCmdExecutor{},
+	"INCR":                 &setCmdExecutor{},
+	"DECR":                 &setCmdExecutor{},
+	"INCRBY":               &setCmdExecutor{},
+	"DECRBY":               &setCmdExecutor{},
+	"INCRBYFLOAT":          &setCmdExecutor{},
+	"APPEND":               &setCmdExecutor{},
+	"STRLEN":               &setCmdExecutor{},
+	"GETRANGE":             &setCmdExecutor{},
+	"SETRANGE":             &setCmdExecutor{},
+	"MGET":                 &setCmdExecutor{},
+	"MSET":                 &setCmdExecutor{},
+	"MSETNX":               &setCmdExecutor{},
+	"GETDEL":               &setCmdExecutor{},
+	"GETEX":                &setCmdExecutor{},
+	"GETSET":               &setCmdExecutor{},
+	"SETNX":                &setCmdExecutor{},
+	"SETEX":                &setCmdExecutor{},
+	"PSETEX":               &setCmdExecutor{},
+	"LCS":                  &setCmdExecutor{},
+	"SETBIT":               &bitmapCmdExecutor{},
+	"GETBIT":               &bitmapCmdExecutor{},
+	"BITCOUNT":             &bitmapCmdExecutor{},
+	"BITPOS":               &bitmapCmdExecutor{},
+	"BITOP":                &bitmapCmdExecutor{},
+	"BITFIELD":             &bitmapCmdExecutor{},
+	"BITFIELD_RO":          &bitmapCmdExecutor{},
+	"PFADD":                &hllCmdExecutor{},
+	"PFCOUNT":              &hllCmdExecutor{},
+	"PFMERGE":              &hllCmdExecutor{},
+	"BF.RESERVE":           &bloomCmdExecutor{},
+	"BF.ADD":               &bloomCmdExecutor{},
+	"BF.MADD":              &bloomCmdExecutor{},
+	"BF.EXISTS":            &bloomCmdExecutor{},
+	"BF.MEXISTS":           &bloomCmdExecutor{},
+	"BF.INFO":              &bloomCmdExecutor{},
+	"CF.RESERVE":           &cuckooCmdExecutor{},
+	"CF.ADD":               &cuckooCmdExecutor{},
+	"CF.DEL":               &cuckooCmdExecutor{},
+	"CF.EXISTS":            &cuckooCmdExecutor{},
+	"CF.COUNT":             &cuckooCmdExecutor{},
+	"CMS.INITBYDIM":        &cmsCmdExecutor{},
+	"CMS.INITBYPROB":       &cmsCmdExecutor{},
+	"CMS.INCRBY":           &cmsCmdExecutor{},
+	"CMS.QUERY":            &cmsCmdExecutor{},
+	"CMS.MERGE":            &cmsCmdExecutor{},
+	"TOPK.RESERVE":         &topkCmdExecutor{},
+	"TOPK.ADD":             &topkCmdExecutor{},
+	"TOPK.INCRBY":          &topkCmdExecutor{},
+	"TOPK.QUERY":           &topkCmdExecutor{},
+	"TOPK.LIST":            &topkCmdExecutor{},
+	"TS.CREATE":            &tsCmdExecutor{},
+	"TS.ADD":               &tsCmdExecutor{},
+	"TS.MADD":              &tsCmdExecutor{},
+	"TS.GET":               &tsCmdExecutor{},
+	"TS.RANGE":             &tsCmdExecutor{},
+	"TS.REVRANGE":          &tsCmdExecutor{},
+	"TS.MRANGE":            &tsCmdExecutor{},
+	"TS.CREATERULE":        &tsCmdExecutor{},
+	"TS.DELETERULE":        &tsCmdExecutor{},
+	"JSON.SET":             &jsonCmdExecutor{},
+	"JSON.GET":             &jsonCmdExecutor{},
+	"JSON.MGET":            &jsonCmdExecutor{},
+	"JSON.DEL":             &jsonCmdExecutor{},
+	"JSON.TYPE":            &jsonCmdExecutor{},
+	"JSON.NUMINCRBY":       &jsonCmdExecutor{},
+	"JSON.STRAPPEND":       &jsonCmdExecutor{},
+	"JSON.ARRAPPEND":       &jsonCmdExecutor{},
+	"JSON.ARRINSERT":       &jsonCmdExecutor{},
+	"JSON.ARRLEN":          &jsonCmdExecutor{},
+	"JSON.OBJKEYS":         &jsonCmdExecutor{},
+	"HSET":                 &hashCmdExecutor{},
+	"HGET":                 &hashCmdExecutor{},
+	"HDEL":                 &hashCmdExecutor{},
+	"FT.CREATE":            &searchCmdExecutor{},
+	"FT.SEARCH":            &searchCmdExecutor{},
+	"VADD":                 &vectorSetCmdExecutor{},
+	"VREM":                 &vectorSetCmdExecutor{},
+	"VSIM":                 &vectorSetCmdExecutor{},
+	"VCARD":                &vectorSetCmdExecutor{},
+	"VDIM":                 &vectorSetCmdExecutor{},
+	"VEMB":                 &vectorSetCmdExecutor{},
+	"ZADD":                 &zsetCmdExecutor{},
+	"ZREM":                 &zsetCmdExecutor{},
+	"ZSCORE":               &zsetCmdExecutor{},
+	"ZCOUNT":               &zsetCmdExecutor{},
+	"ZRANGEBYSCORE":        &zsetCmdExecutor{},
+	"ZRANK":                &zsetCmdExecutor{},
+	"ZRANGE":               &zsetCmdExecutor{},
+	"XADD":                 &streamCmdExecutor{},
+	"XRANGE":               &streamCmdExecutor{},
+	"XREAD":                &streamCmdExecutor{},
+	"XINFO":                &streamCmdExecutor{},
+	"GEOADD":               &geoCmdExecutor{},
+	"GEODIST":              &geoCmdExecutor{},
+	"GEOHASH":              &geoCmdExecutor{},
+	"GEOPOS":               &geoCmdExecutor{},
+	"GEORADIUS":            &geoCmdExecutor{},
+	"GEORADIUS_RO":         &geoCmdExecutor{},
+	"GEORADIUSBYMEMBER":    &geoCmdExecutor{},
+	"GEORADIUSBYMEMBER_RO": &geoCmdExecutor{},
+	"GEOSEARCH":            &geoCmdExecutor{},
+	"GEOSEARCHSTORE":       &geoCmdExecutor{},
+	"GEOPOLYSEARCH":        &geoCmdExecutor{},
+	"GEOPOLYCONTAINS":      &geoCmdExecutor{},
+	"GEOKNN":               &geoCmdExecutor{},
+	"GEOFENCE":             &geoCmdExecutor{},
 }
 
 func Execute(c *ClientInfo, val *resp.RespValue) {
@@ -1,8 +1,9 @@
 package cmdexec
 
 import (
-	"strconv"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/stanleygy/toy-redis/app/algo"
 	"github.com/stanleygy/toy-redis/app/resp"
@@ -10,80 +11,121 @@ import (
 
 type geoCmdExecutor struct{}
 
+// checkGeoCoord checks if `coord` can be encoded in a geo set score
+func checkGeoCoord(coord algo.GeoCoord) error {
+	if coord.Lon < algo.GeoLonMin || coord.Lon > algo.GeoLonMax || coord.Lat < algo.GeoLatMin || coord.Lat > algo.GeoLatMax {
+		return fmt.Errorf("ERR invalid longitude,latitude pair %f,%f", coord.Lon, coord.Lat)
+	}
+	return nil
+}
+
 /*
-Syntax: GEOADD key longitude latitude member [longitude latitude member ...]
+Syntax: GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...]
+Reply:
+  - Integer reply: the number of members added, or added and updated with CH
 */
-func (e geoCmdExecutor) parseGeoAddCmdArgs(cmdArgs []*resp.RespValue, key *string, longitudes *[]float64, latitudes *[]float64, members *[]string) error {
+func (e geoCmdExecutor) parseGeoAddCmdArgs(cmdArgs []*resp.RespValue, key *string, coords *[]algo.GeoCoord, members *[]string, nxFlag *bool, xxFlag *bool, chFlag *bool) error {
 	if len(cmdArgs) < 4 {
 		return ErrInvalidArgs
 	}
-	var i int
-
 	*key = cmdArgs[0].BulkStr
-	for i = 1; i < len(cmdArgs); i += 3 {
-		if i+2 >= len(cmdArgs) {
-			return ErrInvalidArgs
+
+	// Options come before coordinates
+	i := 1
+	for ; i < len(cmdArgs); i++ {
+		option := strings.ToUpper(cmdArgs[i].BulkStr)
+		if option == "NX" {
+			*nxFlag = true
+		} else if option == "XX" {
+			*xxFlag = true
+		} else if option == "CH" {
+			*chFlag = true
+		} else {
+			break
 		}
-		lon, err := strconv.ParseFloat(cmdArgs[i].BulkStr, 64)
+	}
+	if *nxFlag && *xxFlag {
+		return ErrGeoNXAndXX
+	}
+	if i == len(cmdArgs) || (len(cmdArgs)-i)%3 != 0 {
+		return ErrSyntax
+	}
+
+	for ; i < len(cmdArgs); i += 3 {
+		var coord algo.GeoCoord
+		err := parseGeoFloats(cmdArgs[i:], &coord.Lon, &coord.Lat)
 		if err != nil {
 			return err
 		}
-		lat, err := strconv.ParseFloat(cmdArgs[i+1].BulkStr, 64)
+		err = checkGeoCoord(coord)
 		if err != nil {
 			return err
 		}
-		*longitudes = append(*longitudes, lon)
-		*latitudes = append(*latitudes, lat)
+		*coords = append(*coords, coord)
 		*members = append(*members, cmdArgs[i+2].BulkStr)
 	}
-
-	if i < len(cmdArgs) {
-		return ErrInvalidArgs
-	}
 	return nil
 }
 
 func (e geoCmdExecutor) executeGeoAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
 	var (
-		key        string
-		longitudes []float64
-		latitudes  []float64
-		members    []string
+		key     string
+		coords  []algo.GeoCoord
+		members []string
+		nxFlag  bool
+		xxFlag  bool
+		chFlag  bool
 	)
-	err := e.parseGeoAddCmdArgs(cmdArgs, &key, &longitudes, &latitudes, &members)
+	err := e.parseGeoAddCmdArgs(cmdArgs, &key, &coords, &members, &nxFlag, &xxFlag, &chFlag)
 	if err != nil {
 		AddErrorReplyEvent(c, err)
 		return
 	}
 
-	// Look up store at key
-	store, found := db.GeoStore[key]
+	// Geo sets are sorted sets scored by geohashes
+	sortedSet, found := db.SortedSetStore[key]
 	if !found {
-		db.GeoStore[key] = make(map[string]*GeoStoreValue)
-		store = db.GeoStore[key]
+		if xxFlag {
+			AddIntegerReplyEvent(c, 0)
+			return
+		}
+		db.SortedSetStore[key] = algo.MakeSkipList(time.Now().Unix())
+		sortedSet = db.SortedSetStore[key]
 	}
 
 	// Execute cmd
+	numAdded, numUpdated := 0, 0
 	for i := 0; i < len(members); i++ {
-		c := algo.GeoCoord{
-			Lat: latitudes[i],
-			Lon: longitudes[i],
+		_, exists := sortedSet.MemberMap[members[i]]
+		if (exists && nxFlag) || (!exists && xxFlag) {
+			continue
+		}
+		score := algo.GeoEncodeScore(coords[i])
+		if !sortedSet.Add(members[i], score, false) {
+			continue
 		}
-		store[members[i]] = &GeoStoreValue{
-			Coord: c,
-			Hash:  algo.GeoHash(c, algo.GeoMaxPrecision),
+		if exists {
+			numUpdated++
+		} else {
+			numAdded++
 		}
+		NotifyGeofences(key, members[i], algo.GeoDecodeScore(score))
+	}
+
+	if chFlag {
+		AddIntegerReplyEvent(c, numAdded+numUpdated)
+	} else {
+		AddIntegerReplyEvent(c, numAdded)
 	}
-	AddIntegerReplyEvent(c, len(members))
 }
 
 /*
-Syntax: GEODIST key member1 member2 [M | KM]
+Syntax: GEODIST key member1 member2 [M | KM | FT | MI]
 Reply:
   - Null reply: one or both of the elements are missing
   - Bulk string reply: distance as double
 */
-func (e geoCmdExecutor) parseGeoDistCmd(cmdArgs []*resp.RespValue, key *string, m1 *string, m2 *string, unit *string) error {
+func (e geoCmdExecutor) parseGeoDistCmd(cmdArgs []*resp.RespValue, key *string, m1 *string, m2 *string, unitScale *float64) error {
 	if len(cmdArgs) < 3 || len(cmdArgs) > 4 {
 		return ErrInvalidArgs
 	}
@@ -92,9 +134,10 @@ func (e geoCmdExecutor) parseGeoDistCmd(cmdArgs []*resp.RespValue, key *string,
 	*m2 = cmdArgs[2].BulkStr
 
 	if len(cmdArgs) == 4 {
-		*unit = strings.ToUpper(cmdArgs[3].BulkStr)
-		if *unit != "M" && *unit != "KM" {
-			return ErrInvalidArgs
+		var err error
+		*unitScale, err = parseGeoUnit(cmdArgs[3].BulkStr)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -102,33 +145,33 @@ func (e geoCmdExecutor) parseGeoDistCmd(cmdArgs []*resp.RespValue, key *string,
 
 func (e geoCmdExecutor) executeGeoDistCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
 	var (
-		key  string
-		m1   string
-		m2   string
-		unit string = "KM"
+		key       string
+		m1        string
+		m2        string
+		unitScale float64 = 1
 	)
-	err := e.parseGeoDistCmd(cmdArgs, &key, &m1, &m2, &unit)
+	err := e.parseGeoDistCmd(cmdArgs, &key, &m1, &m2, &unitScale)
 	if err != nil {
 		AddErrorReplyEvent(c, err)
 		return
 	}
 
-	// Look up store at key
-	store, found := db.GeoStore[key]
+	// Look up sorted set at key
+	sortedSet, found := db.SortedSetStore[key]
 	if !found {
 		AddNullBulkStringReplyEvent(c)
 		return
 	}
 
 	// Look up members
-	v1 := store[m1]
-	v2 := store[m2]
-	dist := algo.GeoHaversineDist(v1.Coord, v2.Coord)
-
-	if unit == "KM" {
-		dist /= 1000.0
+	v1, found1 := sortedSet.MemberMap[m1]
+	v2, found2 := sortedSet.MemberMap[m2]
+	if !found1 || !found2 {
+		AddNullBulkStringReplyEvent(c)
+		return
 	}
-	AddBulkStringReplyEvent(c, strconv.FormatFloat(dist, 'f', -1, 64))
+	dist := algo.GeoHaversineDist(algo.GeoDecodeScore(v1.Score), algo.GeoDecodeScore(v2.Score))
+	AddBulkStringReplyEvent(c, formatGeoDist(dist/unitScale))
 }
 
 /*
@@ -159,8 +202,8 @@ func (e geoCmdExecutor) executeGeoHashCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 		return
 	}
 
-	// Look up store at key
-	store, found := db.GeoStore[key]
+	// Look up sorted set at key
+	sortedSet, found := db.SortedSetStore[key]
 	if !found {
 		AddEmptyArrayReplyEvent(c)
 		return
@@ -169,9 +212,9 @@ func (e geoCmdExecutor) executeGeoHashCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 	// Collect all hashes
 	var res []*resp.RespValue
 	for _, m := range members {
-		v, found := store[m]
+		node, found := sortedSet.MemberMap[m]
 		if found {
-			res = append(res, resp.MakeBulkString(v.Hash))
+			res = append(res, resp.MakeBulkString(algo.GeoHashFromScore(node.Score)))
 		} else {
 			res = append(res, resp.MakeNilBulkString())
 		}
@@ -180,59 +223,47 @@ func (e geoCmdExecutor) executeGeoHashCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 }
 
 /*
-Syntax: GEORADIUS key longitude latitude radius
+Syntax: GEOPOS key member [member ...]
+Reply:
+  - Array reply: a list of [longitude, latitude] pairs, or nil arrays for missing members
 */
-func (e geoCmdExecutor) parseGeoRadiusCmdArgs(cmdArgs []*resp.RespValue, key *string, longitude *float64, latitude *float64, radius *float64) error {
-	if len(cmdArgs) < 4 {
+func (e geoCmdExecutor) parseGeoPosCmd(cmdArgs []*resp.RespValue, key *string, members *[]string) error {
+	if len(cmdArgs) < 2 {
 		return ErrInvalidArgs
 	}
-	var err error
 	*key = cmdArgs[0].BulkStr
-	*longitude, err = strconv.ParseFloat(cmdArgs[1].BulkStr, 64)
-	if err != nil {
-		return err
-	}
-	*latitude, err = strconv.ParseFloat(cmdArgs[2].BulkStr, 64)
-	if err != nil {
-		return err
-	}
-	*radius, err = strconv.ParseFloat(cmdArgs[3].BulkStr, 64)
-	if err != nil {
-		return err
+	for i := 1; i < len(cmdArgs); i++ {
+		*members = append(*members, cmdArgs[i].BulkStr)
 	}
 	return nil
 }
 
-func (e geoCmdExecutor) executeGeoRadiusCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+func (e geoCmdExecutor) executeGeoPosCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
 	var (
-		key       string
-		longitude float64
-		latitude  float64
-		radius    float64
+		key     string
+		members []string
 	)
-	err := e.parseGeoRadiusCmdArgs(cmdArgs, &key, &longitude, &latitude, &radius)
+
+	err := e.parseGeoPosCmd(cmdArgs, &key, &members)
 	if err != nil {
 		AddErrorReplyEvent(c, err)
 		return
 	}
 
-	// Look up store at key
-	store, found := db.GeoStore[key]
-	if !found {
-		AddEmptyArrayReplyEvent(c)
-		return
-	}
+	// A missing key is treated as an empty set
+	sortedSet, found := db.SortedSetStore[key]
 
-	// This searching algorithm takes O(N^2) and unoptimized. Redis uses skip list to speed up
-	// the geospatial query.
-	originCoord := algo.GeoCoord{
-		Lat: latitude,
-		Lon: longitude,
-	}
-	var res []*resp.RespValue
-	for m, v := range store {
-		if algo.GeoHaversineDist(v.Coord, originCoord) < radius {
-			res = append(res, resp.MakeBulkString(m))
+	// Decode coordinates from scores
+	res := make([]*resp.RespValue, 0, len(members))
+	for _, m := range members {
+		var node *algo.Node
+		if found {
+			node = sortedSet.MemberMap[m]
+		}
+		if node != nil {
+			res = append(res, makeGeoCoordReply(algo.GeoDecodeScore(node.Score)))
+		} else {
+			res = append(res, resp.MakeNilArray())
 		}
 	}
 	AddArrayReplyEvent(c, res)
@@ -246,7 +277,27 @@ func (e geoCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.R
 		e.executeGeoDistCmd(c, cmdArgs)
 	case "GEOHASH":
 		e.executeGeoHashCmd(c, cmdArgs)
+	case "GEOPOS":
+		e.executeGeoPosCmd(c, cmdArgs)
 	case "GEORADIUS":
-		e.executeGeoRadiusCmd(c, cmdArgs)
+		e.executeGeoRadiusCmd(c, cmdArgs, false, false)
+	case "GEORADIUS_RO":
+		e.executeGeoRadiusCmd(c, cmdArgs, false, true)
+	case "GEORADIUSBYMEMBER":
+		e.executeGeoRadiusCmd(c, cmdArgs, true, false)
+	case "GEORADIUSBYMEMBER_RO":
+		e.executeGeoRadiusCmd(c, cmdArgs, true, true)
+	case "GEOSEARCH":
+		e.executeGeoSearchCmd(c, cmdArgs, false)
+	case "GEOSEARCHSTORE":
+		e.executeGeoSearchCmd(c, cmdArgs, true)
+	case "GEOPOLYSEARCH":
+		e.executeGeoPolySearchCmd(c, cmdArgs)
+	case "GEOPOLYCONTAINS":
+		e.executeGeoPolyContainsCmd(c, cmdArgs)
+	case "GEOKNN":
+		e.executeGeoKNNCmd(c, cmdArgs)
+	case "GEOFENCE":
+		e.executeGeofenceCmd(c, cmdArgs)
 	}
 }
@@ -0,0 +1,60 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGeoAddCmdArgs(t *testing.T) {
+	e := geoCmdExecutor{}
+
+	var (
+		key     string
+		coords  []algo.GeoCoord
+		members []string
+		nxFlag  bool
+		xxFlag  bool
+		chFlag  bool
+	)
+	err := e.parseGeoAddCmdArgs(makeCmdArgs("Sicily", "xx", "CH", "13.361389", "38.115556", "Palermo"), &key, &coords, &members, &nxFlag, &xxFlag, &chFlag)
+	assert.Nil(t, err)
+	assert.Equal(t, []algo.GeoCoord{{Lat: 38.115556, Lon: 13.361389}}, coords)
+	assert.Equal(t, []string{"Palermo"}, members)
+	assert.True(t, xxFlag && chFlag && !nxFlag)
+
+	nxFlag, xxFlag = false, false
+	err = e.parseGeoAddCmdArgs(makeCmdArgs("Sicily", "NX", "XX", "13", "38", "Palermo"), &key, &coords, &members, &nxFlag, &xxFlag, &chFlag)
+	assert.Equal(t, ErrGeoNXAndXX, err)
+
+	nxFlag, xxFlag = false, false
+
+	err = e.parseGeoAddCmdArgs(makeCmdArgs("Sicily", "200", "-95", "Palermo"), &key, &coords, &members, &nxFlag, &xxFlag, &chFlag)
+	assert.EqualError(t, err, "ERR invalid longitude,latitude pair 200.000000,-95.000000")
+
+	// Latitudes are limited to the range of web mercator
+	err = e.parseGeoAddCmdArgs(makeCmdArgs("Sicily", "13", "85.06", "Palermo"), &key, &coords, &members, &nxFlag, &xxFlag, &chFlag)
+	assert.EqualError(t, err, "ERR invalid longitude,latitude pair 13.000000,85.060000")
+}
+
+func TestGeoAddCounts(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	c := &ClientInfo{}
+	e := geoCmdExecutor{}
+
+	reply := func(args ...string) int {
+		Reset()
+		e.executeGeoAddCmd(c, makeCmdArgs(args...))
+		return EventBus[0].Resp.Int
+	}
+	assert.Equal(t, 0, reply("Sicily", "XX", "13", "38", "Palermo"))
+	assert.Equal(t, 1, reply("Sicily", "13", "38", "Palermo"))
+	assert.Equal(t, 0, reply("Sicily", "13.5", "38", "Palermo"))
+	assert.Equal(t, 2, reply("Sicily", "CH", "13", "38", "Palermo", "13.5", "38", "Catania"))
+	assert.Equal(t, 2, reply("Sicily", "CH", "13", "38", "Palermo", "15", "37", "Catania", "14", "37", "x"))
+	assert.Equal(t, 1, reply("Sicily", "NX", "CH", "14", "38", "Palermo", "14", "37", "y"))
+	assert.Equal(t, 2, reply("Sicily", "XX", "CH", "14", "38", "Palermo", "14", "37", "z", "13", "37", "y"))
+	Reset()
+}
@@ -0,0 +1,110 @@
+package cmdexec
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// Rings of cells to expand at a step before moving to a coarser step
+const geoNearestMaxRings = 3
+
+var ErrGeoInvalidK = errors.New("ERR k must be > 0")
+
+// searchNearest looks up the `opts.Count` members closest to the search center. Rings of cells
+// are expanded outward from the cell containing the center, until the k-th closest candidate is
+// closer than anything outside the visited cells.
+func (e geoCmdExecutor) searchNearest(sortedSet *algo.SkipList, opts *geoSearchOptions) []*geoSearchResult {
+	k := opts.Count
+	if sortedSet.Size() == 0 {
+		return []*geoSearchResult{}
+	}
+
+	// Members might be clustered, so start from the finest step and move to coarser steps
+	// until k members are found
+	for step := algo.GeoStepMax; ; step = max(step-2, 1) {
+		center := algo.MakeGeoCell(opts.Center, step)
+		candidates := make([]*geoSearchResult, 0)
+		visited := map[int]bool{center.Bits: true}
+		ring := []algo.GeoCell{center}
+
+		for r := 0; step == 1 || r <= geoNearestMaxRings; r++ {
+			for _, cell := range ring {
+				minScore, maxScore := cell.ScoreRange()
+				for _, node := range sortedSet.FindByRange(minScore, maxScore) {
+					coord := algo.GeoDecodeScore(node.Score)
+					dist := algo.GeoHaversineDist(opts.Center, coord)
+					candidates = append(candidates, &geoSearchResult{Member: node.Member, Score: node.Score, Coord: coord, Dist: dist})
+				}
+			}
+			sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Dist < candidates[j].Dist })
+
+			// The next ring is made of unvisited neighbors of the current ring
+			next := make([]algo.GeoCell, 0)
+			for _, cell := range ring {
+				for _, n := range algo.GeoGetCellNeighbors(cell) {
+					if !visited[n.Bits] {
+						visited[n.Bits] = true
+						next = append(next, n)
+					}
+				}
+			}
+
+			// All cells are visited
+			if len(next) == 0 {
+				return candidates[:min(k, len(candidates))]
+			}
+			if len(candidates) >= k && candidates[k-1].Dist <= algo.GeoCellBlockMinDist(opts.Center, center, r) {
+				return candidates[:k]
+			}
+			if len(candidates) == 0 && r > 0 && step > 1 {
+				break
+			}
+			ring = next
+		}
+	}
+}
+
+/*
+Syntax: GEOKNN key k <M | KM | FT | MI> <FROMMEMBER member | FROMLONLAT longitude latitude> [WITHCOORD] [WITHDIST] [WITHHASH]
+Reply:
+  - Array reply: a list of the k closest members ordered by distance, or a list of [member, [dist], [hash], [coord]] with any WITH* options
+*/
+func (e geoCmdExecutor) parseGeoKNNCmdArgs(cmdArgs []*resp.RespValue, key *string, opts *geoSearchOptions) error {
+	if len(cmdArgs) < 3 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	opts.Count, err = strconv.Atoi(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	if opts.Count <= 0 {
+		return ErrGeoInvalidK
+	}
+	opts.UnitScale, err = parseGeoUnit(cmdArgs[2].BulkStr)
+	if err != nil {
+		return err
+	}
+	opts.ByNearest = true
+	opts.Sort = geoSortAsc
+	return e.parseGeoSearchOptions(cmdArgs[3:], opts, geoCmdNearest)
+}
+
+func (e geoCmdExecutor) executeGeoKNNCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key  string
+		opts geoSearchOptions
+	)
+	err := e.parseGeoKNNCmdArgs(cmdArgs, &key, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	e.searchAndReply(c, key, &opts)
+}
@@ -0,0 +1,72 @@
+package cmdexec
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoSearchNearest(t *testing.T) {
+	e := geoCmdExecutor{}
+	r := rand.New(rand.NewSource(1))
+
+	// Include a dense area across the antimeridian, a sparse area near the pole and a tiny set
+	sets := []*algo.SkipList{
+		makeGeoSet(r, algo.GeoCoord{Lat: 38.1, Lon: 13.3}, 4, 20000),
+		makeGeoSet(r, algo.GeoCoord{Lat: -70, Lon: 179.9}, 4, 20000),
+		makeGeoSet(r, algo.GeoCoord{Lat: 80, Lon: 0}, 10, 500),
+		makeGeoSet(r, algo.GeoCoord{Lat: 0, Lon: 0}, 100, 5),
+	}
+	for _, sortedSet := range sets {
+		// Expected results are found by sorting all members by distance
+		nodes := make([]*algo.Node, 0)
+		for node := sortedSet.Front(); node != nil && node != sortedSet.Tail; node = node.NextNodes[0] {
+			nodes = append(nodes, node)
+		}
+
+		for i := 0; i < 50; i++ {
+			query := algo.GeoDecodeScore(nodes[r.Intn(len(nodes))].Score)
+			query.Lat += r.Float64() - 0.5
+			query.Lon += r.Float64() - 0.5
+			k := 1 + r.Intn(20)
+
+			dists := make([]float64, 0)
+			for _, node := range nodes {
+				dists = append(dists, algo.GeoHaversineDist(query, algo.GeoDecodeScore(node.Score)))
+			}
+			sort.Float64s(dists)
+
+			opts := geoSearchOptions{Center: query, ByNearest: true, Count: k, UnitScale: 1}
+			results := e.search(sortedSet, &opts)
+			assert.Equal(t, min(k, len(nodes)), len(results))
+			for j, res := range results {
+				assert.Equal(t, dists[j], res.Dist)
+			}
+		}
+	}
+}
+
+func BenchmarkGeoKNN(b *testing.B) {
+	e := geoCmdExecutor{}
+	r := rand.New(rand.NewSource(1))
+
+	// A million members in a city-sized area, with queries of the 10 closest members
+	center := algo.GeoCoord{Lat: 40.7, Lon: -74}
+	sortedSet := makeGeoSet(r, center, 1, 1000000)
+	queries := make([]algo.GeoCoord, 1000)
+	for i := range queries {
+		queries[i] = algo.GeoCoord{
+			Lat: center.Lat + (r.Float64()-0.5)*0.8,
+			Lon: center.Lon + (r.Float64()-0.5)*0.8,
+		}
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		opts := geoSearchOptions{Center: queries[i%len(queries)], ByNearest: true, Count: 10, UnitScale: 1}
+		e.search(sortedSet, &opts)
+	}
+}
@@ -0,0 +1,134 @@
+package cmdexec
+
+import (
+	"strconv"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+/*
+parseGeoPolygon parses `numvertices longitude latitude [longitude latitude ...]`, and sets
+`numArgs` to the number of arguments consumed
+*/
+func (e geoCmdExecutor) parseGeoPolygon(cmdArgs []*resp.RespValue, polygon **algo.GeoPolygon, numArgs *int) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	numVertices, err := strconv.Atoi(cmdArgs[0].BulkStr)
+	if err != nil {
+		return err
+	}
+	if numVertices < 3 {
+		return ErrGeoPolygonTooSmall
+	}
+	if len(cmdArgs) < 1+numVertices*2 {
+		return ErrSyntax
+	}
+
+	vertices := make([]algo.GeoCoord, numVertices)
+	for i := range vertices {
+		err = parseGeoFloats(cmdArgs[1+i*2:], &vertices[i].Lon, &vertices[i].Lat)
+		if err != nil {
+			return err
+		}
+		err = checkGeoCoord(vertices[i])
+		if err != nil {
+			return err
+		}
+	}
+	*polygon = algo.MakeGeoPolygon(vertices)
+	*numArgs = 1 + numVertices*2
+	return nil
+}
+
+/*
+Syntax: GEOPOLYSEARCH key numvertices longitude latitude [longitude latitude ...] [WITHCOORD] [WITHHASH] [COUNT count [ANY]]
+Reply:
+  - Array reply: a list of members inside the polygon, or a list of [member, [hash], [coord]] with any WITH* options
+*/
+func (e geoCmdExecutor) parseGeoPolySearchCmdArgs(cmdArgs []*resp.RespValue, key *string, opts *geoSearchOptions) error {
+	if len(cmdArgs) < 2 {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+
+	var numArgs int
+	err := e.parseGeoPolygon(cmdArgs[1:], &opts.Polygon, &numArgs)
+	if err != nil {
+		return err
+	}
+	opts.ByPolygon = true
+	opts.FromLonLat = true
+	opts.Center = opts.Polygon.Center()
+	opts.UnitScale = 1
+	return e.parseGeoSearchOptions(cmdArgs[1+numArgs:], opts, geoCmdPolySearch)
+}
+
+func (e geoCmdExecutor) executeGeoPolySearchCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key  string
+		opts geoSearchOptions
+	)
+	err := e.parseGeoPolySearchCmdArgs(cmdArgs, &key, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	e.searchAndReply(c, key, &opts)
+}
+
+/*
+Syntax: GEOPOLYCONTAINS key member numvertices longitude latitude [longitude latitude ...]
+Reply:
+  - Null reply: the member is missing
+  - Integer reply: 1 if the member is inside the polygon, or 0 otherwise
+*/
+func (e geoCmdExecutor) parseGeoPolyContainsCmdArgs(cmdArgs []*resp.RespValue, key *string, member *string, polygon **algo.GeoPolygon) error {
+	if len(cmdArgs) < 3 {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+	*member = cmdArgs[1].BulkStr
+
+	var numArgs int
+	err := e.parseGeoPolygon(cmdArgs[2:], polygon, &numArgs)
+	if err != nil {
+		return err
+	}
+	if 2+numArgs != len(cmdArgs) {
+		return ErrSyntax
+	}
+	return nil
+}
+
+func (e geoCmdExecutor) executeGeoPolyContainsCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key     string
+		member  string
+		polygon *algo.GeoPolygon
+	)
+	err := e.parseGeoPolyContainsCmdArgs(cmdArgs, &key, &member, &polygon)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// Look up sorted set at key
+	sortedSet, found := db.SortedSetStore[key]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	node, found := sortedSet.MemberMap[member]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+
+	if polygon.Contains(algo.GeoDecodeScore(node.Score)) {
+		AddIntegerReplyEvent(c, 1)
+	} else {
+		AddIntegerReplyEvent(c, 0)
+	}
+}
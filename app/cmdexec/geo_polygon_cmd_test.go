@@ -0,0 +1,54 @@
+package cmdexec
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoPolySearch(t *testing.T) {
+	e := geoCmdExecutor{}
+	r := rand.New(rand.NewSource(1))
+
+	// Include an area across the antimeridian
+	for _, center := range []algo.GeoCoord{{Lat: 38.1, Lon: 13.3}, {Lat: -70, Lon: 179.9}} {
+		sortedSet := makeGeoSet(r, center, 4, 20000)
+
+		for i := 0; i < 100; i++ {
+			// Star-shaped polygons, which might be concave
+			vertices := make([]algo.GeoCoord, 3+r.Intn(6))
+			for j := range vertices {
+				angle := 2 * math.Pi * float64(j) / float64(len(vertices))
+				dist := 0.1 + r.Float64()
+				vertices[j] = algo.GeoCoord{
+					Lat: center.Lat + dist*math.Sin(angle),
+					Lon: center.Lon + dist*math.Cos(angle),
+				}
+				if vertices[j].Lon > 180 {
+					vertices[j].Lon -= 360
+				}
+			}
+			polygon := algo.MakeGeoPolygon(vertices)
+
+			opts := geoSearchOptions{ByPolygon: true, Polygon: polygon, Center: polygon.Center(), UnitScale: 1}
+			members := make([]string, 0)
+			for _, res := range e.search(sortedSet, &opts) {
+				members = append(members, res.Member)
+			}
+
+			expected := make([]string, 0)
+			for node := sortedSet.Front(); node != nil && node != sortedSet.Tail; node = node.NextNodes[0] {
+				if polygon.Contains(algo.GeoDecodeScore(node.Score)) {
+					expected = append(expected, node.Member)
+				}
+			}
+			sort.Strings(expected)
+			sort.Strings(members)
+			assert.Equal(t, expected, members)
+		}
+	}
+}
@@ -0,0 +1,526 @@
+package cmdexec
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+const (
+	geoSortNone = 0
+	geoSortAsc  = 1
+	geoSortDesc = 2
+)
+
+// Geo search commands accept different subsets of the options
+const (
+	geoCmdRadius      = 0 // GEORADIUS and GEORADIUSBYMEMBER
+	geoCmdRadiusRO    = 1 // GEORADIUS_RO and GEORADIUSBYMEMBER_RO
+	geoCmdSearch      = 2 // GEOSEARCH
+	geoCmdSearchStore = 3 // GEOSEARCHSTORE
+	geoCmdPolySearch  = 4 // GEOPOLYSEARCH
+	geoCmdNearest     = 5 // GEOKNN
+)
+
+// GeoStoreDistScale is the score scale of sorted sets stored by STOREDIST, so that stored distances keep
+// 6 decimal places, which is more precise than the replies of GEODIST
+const GeoStoreDistScale = 1000000
+
+var (
+	ErrGeoUnsupportedUnit = errors.New("ERR unsupported unit provided. please use M, KM, FT, MI")
+	ErrGeoMemberNotFound  = errors.New("ERR could not decode requested zset member")
+	ErrGeoFromRequired    = errors.New("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	ErrGeoByRequired      = errors.New("ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+	ErrGeoKNNFromRequired = errors.New("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOKNN")
+	ErrGeoAnyWithoutCount = errors.New("ERR the ANY argument requires COUNT argument")
+	ErrGeoInvalidCount    = errors.New("ERR COUNT must be > 0")
+	ErrGeoPolygonTooSmall = errors.New("ERR a polygon needs at least 3 vertices")
+	ErrGeoNXAndXX         = errors.New("ERR XX and NX options at the same time are not compatible")
+	ErrGeoStoreWithReply  = errors.New("ERR STORE option in GEORADIUS is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+)
+
+type geoSearchResult struct {
+	Member string
+	Score  int
+	Coord  algo.GeoCoord
+	Dist   float64
+}
+
+// geoSearchOptions holds the search area and the reply options shared by geo search commands.
+// Radius, width and height are in meters.
+type geoSearchOptions struct {
+	FromMember string
+	FromLonLat bool
+	Center     algo.GeoCoord
+
+	ByRadius  bool
+	ByBox     bool
+	ByPolygon bool
+	Polygon   *algo.GeoPolygon
+	ByNearest bool
+	Radius    float64
+	Width     float64
+	Height    float64
+	UnitScale float64
+
+	Sort      int
+	Count     int
+	Any       bool
+	WithCoord bool
+	WithDist  bool
+	WithHash  bool
+	Store     bool
+	StoreKey  string
+	StoreDist bool
+}
+
+func parseGeoUnit(unit string) (float64, error) {
+	// Returns the number of meters per unit
+	switch strings.ToUpper(unit) {
+	case "M":
+		return 1, nil
+	case "KM":
+		return 1000, nil
+	case "FT":
+		return 0.3048, nil
+	case "MI":
+		return 1609.34, nil
+	}
+	return 0, ErrGeoUnsupportedUnit
+}
+
+func parseGeoFloats(cmdArgs []*resp.RespValue, vals ...*float64) error {
+	if len(cmdArgs) < len(vals) {
+		return ErrSyntax
+	}
+	for i, v := range vals {
+		f, err := strconv.ParseFloat(cmdArgs[i].BulkStr, 64)
+		if err != nil {
+			return err
+		}
+		*v = f
+	}
+	return nil
+}
+
+/*
+parseGeoSearchOptions parses the options of geo search commands in any order:
+  - FROMMEMBER member | FROMLONLAT longitude latitude (GEOSEARCH, GEOSEARCHSTORE and GEOKNN)
+  - BYRADIUS radius unit | BYBOX width height unit (GEOSEARCH and GEOSEARCHSTORE)
+  - ASC | DESC (all but GEOPOLYSEARCH and GEOKNN)
+  - COUNT count [ANY] (all but GEOKNN)
+  - WITHCOORD | WITHHASH (all but GEOSEARCHSTORE)
+  - WITHDIST (all but GEOSEARCHSTORE and GEOPOLYSEARCH)
+  - STORE key | STOREDIST key (GEORADIUS and GEORADIUSBYMEMBER)
+  - STOREDIST (GEOSEARCHSTORE)
+*/
+func (e geoCmdExecutor) parseGeoSearchOptions(cmdArgs []*resp.RespValue, opts *geoSearchOptions, cmdType int) error {
+	var err error
+	isSearch := cmdType == geoCmdSearch || cmdType == geoCmdSearchStore
+	hasFrom := isSearch || cmdType == geoCmdNearest
+	hasSort := cmdType != geoCmdPolySearch && cmdType != geoCmdNearest
+	numFrom, numBy := 0, 0
+
+	for i := 0; i < len(cmdArgs); i++ {
+		option := strings.ToUpper(cmdArgs[i].BulkStr)
+		switch {
+		case option == "FROMMEMBER" && hasFrom:
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.FromMember = cmdArgs[i+1].BulkStr
+			numFrom++
+			i++
+		case option == "FROMLONLAT" && hasFrom:
+			err = parseGeoFloats(cmdArgs[i+1:], &opts.Center.Lon, &opts.Center.Lat)
+			if err != nil {
+				return err
+			}
+			opts.FromLonLat = true
+			numFrom++
+			i += 2
+		case option == "BYRADIUS" && isSearch:
+			err = parseGeoFloats(cmdArgs[i+1:], &opts.Radius)
+			if err != nil {
+				return err
+			}
+			if i+2 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.UnitScale, err = parseGeoUnit(cmdArgs[i+2].BulkStr)
+			if err != nil {
+				return err
+			}
+			opts.Radius *= opts.UnitScale
+			opts.ByRadius = true
+			numBy++
+			i += 2
+		case option == "BYBOX" && isSearch:
+			err = parseGeoFloats(cmdArgs[i+1:], &opts.Width, &opts.Height)
+			if err != nil {
+				return err
+			}
+			if i+3 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.UnitScale, err = parseGeoUnit(cmdArgs[i+3].BulkStr)
+			if err != nil {
+				return err
+			}
+			opts.Width *= opts.UnitScale
+			opts.Height *= opts.UnitScale
+			opts.ByBox = true
+			numBy++
+			i += 3
+		case option == "ASC" && hasSort:
+			opts.Sort = geoSortAsc
+		case option == "DESC" && hasSort:
+			opts.Sort = geoSortDesc
+		case option == "COUNT" && cmdType != geoCmdNearest:
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.Count, err = strconv.Atoi(cmdArgs[i+1].BulkStr)
+			if err != nil {
+				return err
+			}
+			if opts.Count <= 0 {
+				return ErrGeoInvalidCount
+			}
+			i++
+			if i+1 < len(cmdArgs) && strings.ToUpper(cmdArgs[i+1].BulkStr) == "ANY" {
+				opts.Any = true
+				i++
+			}
+		case option == "WITHCOORD" && cmdType != geoCmdSearchStore:
+			opts.WithCoord = true
+		case option == "WITHDIST" && cmdType != geoCmdSearchStore && cmdType != geoCmdPolySearch:
+			opts.WithDist = true
+		case option == "WITHHASH" && cmdType != geoCmdSearchStore:
+			opts.WithHash = true
+		case (option == "STORE" || option == "STOREDIST") && cmdType == geoCmdRadius:
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.Store = true
+			opts.StoreKey = cmdArgs[i+1].BulkStr
+			opts.StoreDist = option == "STOREDIST"
+			i++
+		case option == "STOREDIST" && cmdType == geoCmdSearchStore:
+			opts.StoreDist = true
+		default:
+			return ErrSyntax
+		}
+	}
+
+	if isSearch {
+		if numFrom != 1 {
+			return ErrGeoFromRequired
+		}
+		if numBy != 1 {
+			return ErrGeoByRequired
+		}
+	}
+	if cmdType == geoCmdNearest && numFrom != 1 {
+		return ErrGeoKNNFromRequired
+	}
+	if opts.Any && opts.Count == 0 {
+		return ErrGeoAnyWithoutCount
+	}
+	if opts.Store && (opts.WithCoord || opts.WithDist || opts.WithHash) {
+		return ErrGeoStoreWithReply
+	}
+
+	// Same as Redis, the closest members are returned when COUNT is used without ANY.
+	// Polygons have no center to measure distances from.
+	if opts.Count > 0 && !opts.Any && opts.Sort == geoSortNone && cmdType != geoCmdPolySearch {
+		opts.Sort = geoSortAsc
+	}
+	return nil
+}
+
+// getDistIfInShape returns the distance from the search center to `coord` in meters,
+// and whether `coord` is inside the search area
+func (e geoCmdExecutor) getDistIfInShape(opts *geoSearchOptions, coord algo.GeoCoord) (float64, bool) {
+	if opts.ByRadius {
+		dist := algo.GeoHaversineDist(opts.Center, coord)
+		return dist, dist <= opts.Radius
+	}
+	if opts.ByPolygon {
+		return 0, opts.Polygon.Contains(coord)
+	}
+
+	// Same as Redis, check the latitude distance first which is cheaper to compute,
+	// and measure the longitude distance along the member's latitude
+	latDist := algo.GeoHaversineDist(opts.Center, algo.GeoCoord{Lat: coord.Lat, Lon: opts.Center.Lon})
+	if latDist > opts.Height/2 {
+		return 0, false
+	}
+	lonDist := algo.GeoHaversineDist(algo.GeoCoord{Lat: coord.Lat, Lon: opts.Center.Lon}, coord)
+	if lonDist > opts.Width/2 {
+		return 0, false
+	}
+	return algo.GeoHaversineDist(opts.Center, coord), true
+}
+
+// search looks up members inside the search area. Only members in the geohash cell containing
+// the search center and its neighbors are considered as candidates.
+func (e geoCmdExecutor) search(sortedSet *algo.SkipList, opts *geoSearchOptions) []*geoSearchResult {
+	if opts.ByNearest {
+		return e.searchNearest(sortedSet, opts)
+	}
+
+	var (
+		box    *algo.GeoBoundingBox
+		radius float64
+	)
+	switch {
+	case opts.ByRadius:
+		box = algo.MakeGeoBoundingBoxAround(opts.Center, opts.Radius, opts.Radius)
+		radius = opts.Radius
+	case opts.ByBox:
+		box = algo.MakeGeoBoundingBoxAround(opts.Center, opts.Width/2, opts.Height/2)
+		radius = math.Hypot(opts.Width/2, opts.Height/2)
+	case opts.ByPolygon:
+		box = opts.Polygon.BoundingBox()
+		for _, v := range opts.Polygon.Vertices {
+			radius = math.Max(radius, algo.GeoHaversineDist(opts.Center, v))
+		}
+	}
+
+	res := make([]*geoSearchResult, 0)
+	for _, cell := range algo.GeoGetSearchCells(opts.Center, radius, box) {
+		minScore, maxScore := cell.ScoreRange()
+		for _, node := range sortedSet.FindByRange(minScore, maxScore) {
+			coord := algo.GeoDecodeScore(node.Score)
+			dist, inShape := e.getDistIfInShape(opts, coord)
+			if !inShape {
+				continue
+			}
+			res = append(res, &geoSearchResult{Member: node.Member, Score: node.Score, Coord: coord, Dist: dist})
+
+			// ANY returns as soon as enough members are found
+			if opts.Any && len(res) == opts.Count {
+				break
+			}
+		}
+		if opts.Any && len(res) == opts.Count {
+			break
+		}
+	}
+
+	switch opts.Sort {
+	case geoSortAsc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].Dist < res[j].Dist })
+	case geoSortDesc:
+		sort.SliceStable(res, func(i, j int) bool { return res[i].Dist > res[j].Dist })
+	}
+	if opts.Count > 0 && len(res) > opts.Count {
+		res = res[:opts.Count]
+	}
+	return res
+}
+
+func formatGeoDist(dist float64) string {
+	return strconv.FormatFloat(dist, 'f', 4, 64)
+}
+
+func formatGeoCoord(v float64) string {
+	// Same as Redis, print 17 decimal places without trailing zeros
+	s := strconv.FormatFloat(v, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func makeGeoCoordReply(coord algo.GeoCoord) *resp.RespValue {
+	return resp.MakeArray([]*resp.RespValue{
+		resp.MakeBulkString(formatGeoCoord(coord.Lon)),
+		resp.MakeBulkString(formatGeoCoord(coord.Lat)),
+	})
+}
+
+func (e geoCmdExecutor) makeSearchResultsReply(results []*geoSearchResult, opts *geoSearchOptions) []*resp.RespValue {
+	res := make([]*resp.RespValue, 0, len(results))
+	for _, r := range results {
+		if !opts.WithDist && !opts.WithHash && !opts.WithCoord {
+			res = append(res, resp.MakeBulkString(r.Member))
+			continue
+		}
+
+		item := []*resp.RespValue{resp.MakeBulkString(r.Member)}
+		if opts.WithDist {
+			item = append(item, resp.MakeBulkString(formatGeoDist(r.Dist/opts.UnitScale)))
+		}
+		if opts.WithHash {
+			item = append(item, resp.MakeInt(r.Score))
+		}
+		if opts.WithCoord {
+			item = append(item, makeGeoCoordReply(r.Coord))
+		}
+		res = append(res, resp.MakeArray(item))
+	}
+	return res
+}
+
+// storeSearchResults replaces the sorted set at `key` with search results, scored by either geohashes or
+// distances. Sorted set scores are integers, so distances are stored in millionths of the unit, and the
+// sorted set is scaled so that its scores are read and written as distances.
+func (e geoCmdExecutor) storeSearchResults(key string, results []*geoSearchResult, opts *geoSearchOptions) {
+	if len(results) == 0 {
+		delete(db.SortedSetStore, key)
+		NotifyGeofencesOnReplace(key, nil, true)
+		return
+	}
+
+	sortedSet := algo.MakeSkipList(time.Now().Unix())
+	if opts.StoreDist {
+		sortedSet.ScoreScale = GeoStoreDistScale
+	}
+	for _, r := range results {
+		score := r.Score
+		if opts.StoreDist {
+			score = int(math.Round(r.Dist / opts.UnitScale * GeoStoreDistScale))
+		}
+		sortedSet.Add(r.Member, score, true)
+	}
+	db.SortedSetStore[key] = sortedSet
+	NotifyGeofencesOnReplace(key, sortedSet, !opts.StoreDist)
+}
+
+// searchAndReply runs a parsed geo search command against the sorted set at `key`, and either
+// replies with the results or stores them
+func (e geoCmdExecutor) searchAndReply(c *ClientInfo, key string, opts *geoSearchOptions) {
+	var results []*geoSearchResult
+
+	// Look up sorted set at key. A missing key is treated as an empty set.
+	sortedSet, found := db.SortedSetStore[key]
+	if found {
+		if !opts.FromLonLat {
+			node, found := sortedSet.MemberMap[opts.FromMember]
+			if !found {
+				AddErrorReplyEvent(c, ErrGeoMemberNotFound)
+				return
+			}
+			opts.Center = algo.GeoDecodeScore(node.Score)
+		}
+		results = e.search(sortedSet, opts)
+	}
+
+	if opts.Store {
+		e.storeSearchResults(opts.StoreKey, results, opts)
+		AddIntegerReplyEvent(c, len(results))
+		return
+	}
+	AddArrayReplyEvent(c, e.makeSearchResultsReply(results, opts))
+}
+
+/*
+Syntax:
+  - GEORADIUS key longitude latitude radius <M | KM | FT | MI> [WITHCOORD] [WITHDIST] [WITHHASH]
+    [COUNT count [ANY]] [ASC | DESC] [STORE key | STOREDIST key]
+  - GEORADIUSBYMEMBER key member radius <M | KM | FT | MI> [WITHCOORD] [WITHDIST] [WITHHASH]
+    [COUNT count [ANY]] [ASC | DESC] [STORE key | STOREDIST key]
+  - GEORADIUS_RO and GEORADIUSBYMEMBER_RO take the same arguments except STORE and STOREDIST
+
+Reply:
+  - Array reply: a list of members, or a list of [member, [dist], [hash], [coord]] with any WITH* options
+  - Integer reply: the number of members stored at key with STORE or STOREDIST
+*/
+func (e geoCmdExecutor) parseGeoRadiusCmdArgs(cmdArgs []*resp.RespValue, byMember bool, readOnly bool, key *string, opts *geoSearchOptions) error {
+	var err error
+
+	if (byMember && len(cmdArgs) < 4) || (!byMember && len(cmdArgs) < 5) {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+	cmdArgs = cmdArgs[1:]
+
+	if byMember {
+		opts.FromMember = cmdArgs[0].BulkStr
+		cmdArgs = cmdArgs[1:]
+	} else {
+		err = parseGeoFloats(cmdArgs, &opts.Center.Lon, &opts.Center.Lat)
+		if err != nil {
+			return err
+		}
+		opts.FromLonLat = true
+		cmdArgs = cmdArgs[2:]
+	}
+
+	err = parseGeoFloats(cmdArgs, &opts.Radius)
+	if err != nil {
+		return err
+	}
+	opts.UnitScale, err = parseGeoUnit(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	opts.Radius *= opts.UnitScale
+	opts.ByRadius = true
+
+	cmdType := geoCmdRadius
+	if readOnly {
+		cmdType = geoCmdRadiusRO
+	}
+	return e.parseGeoSearchOptions(cmdArgs[2:], opts, cmdType)
+}
+
+func (e geoCmdExecutor) executeGeoRadiusCmd(c *ClientInfo, cmdArgs []*resp.RespValue, byMember bool, readOnly bool) {
+	var (
+		key  string
+		opts geoSearchOptions
+	)
+	err := e.parseGeoRadiusCmdArgs(cmdArgs, byMember, readOnly, &key, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	e.searchAndReply(c, key, &opts)
+}
+
+/*
+Syntax:
+  - GEOSEARCH key <FROMMEMBER member | FROMLONLAT longitude latitude> <BYRADIUS radius unit | BYBOX width height unit>
+    [ASC | DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+  - GEOSEARCHSTORE destination source <FROMMEMBER member | FROMLONLAT longitude latitude>
+    <BYRADIUS radius unit | BYBOX width height unit> [ASC | DESC] [COUNT count [ANY]] [STOREDIST]
+
+Reply:
+  - Array reply: a list of members, or a list of [member, [dist], [hash], [coord]] with any WITH* options
+  - Integer reply: the number of members stored at destination for GEOSEARCHSTORE
+*/
+func (e geoCmdExecutor) parseGeoSearchCmdArgs(cmdArgs []*resp.RespValue, isStore bool, key *string, opts *geoSearchOptions) error {
+	if len(cmdArgs) < 1 || (isStore && len(cmdArgs) < 2) {
+		return ErrInvalidArgs
+	}
+
+	cmdType := geoCmdSearch
+	if isStore {
+		opts.Store = true
+		opts.StoreKey = cmdArgs[0].BulkStr
+		cmdArgs = cmdArgs[1:]
+		cmdType = geoCmdSearchStore
+	}
+	*key = cmdArgs[0].BulkStr
+	opts.UnitScale = 1
+	return e.parseGeoSearchOptions(cmdArgs[1:], opts, cmdType)
+}
+
+func (e geoCmdExecutor) executeGeoSearchCmd(c *ClientInfo, cmdArgs []*resp.RespValue, isStore bool) {
+	var (
+		key  string
+		opts geoSearchOptions
+	)
+	err := e.parseGeoSearchCmdArgs(cmdArgs, isStore, &key, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	e.searchAndReply(c, key, &opts)
+}
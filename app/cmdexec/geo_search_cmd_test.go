@@ -0,0 +1,183 @@
+package cmdexec
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeGeoSet scatters members uniformly in a box of `size` degrees around `center`
+func makeGeoSet(r *rand.Rand, center algo.GeoCoord, size float64, numMembers int) *algo.SkipList {
+	sortedSet := algo.MakeSkipList(1)
+	for i := 0; i < numMembers; i++ {
+		coord := algo.GeoCoord{
+			Lat: center.Lat + (r.Float64()-0.5)*size,
+			Lon: center.Lon + (r.Float64()-0.5)*size,
+		}
+		sortedSet.Add(strconv.Itoa(i), algo.GeoEncodeScore(coord), true)
+	}
+	return sortedSet
+}
+
+func scanByRadius(sortedSet *algo.SkipList, center algo.GeoCoord, radius float64) []string {
+	res := make([]string, 0)
+	for node := sortedSet.Front(); node != nil && node != sortedSet.Tail; node = node.NextNodes[0] {
+		if algo.GeoHaversineDist(center, algo.GeoDecodeScore(node.Score)) <= radius {
+			res = append(res, node.Member)
+		}
+	}
+	return res
+}
+
+func TestGeoSearchByRadius(t *testing.T) {
+	e := geoCmdExecutor{}
+	r := rand.New(rand.NewSource(1))
+
+	// Include an area across the antimeridian
+	for _, center := range []algo.GeoCoord{{Lat: 38.1, Lon: 13.3}, {Lat: -70, Lon: 179.9}} {
+		sortedSet := makeGeoSet(r, center, 4, 20000)
+
+		for i := 0; i < 100; i++ {
+			query := algo.GeoCoord{
+				Lat: center.Lat + (r.Float64()-0.5)*4,
+				Lon: center.Lon + (r.Float64()-0.5)*4,
+			}
+			if query.Lon > 180 {
+				query.Lon -= 360
+			}
+			radius := r.Float64() * 100000
+
+			members := make([]string, 0)
+			opts := geoSearchOptions{Center: query, ByRadius: true, Radius: radius, UnitScale: 1}
+			for _, res := range e.search(sortedSet, &opts) {
+				members = append(members, res.Member)
+			}
+			expected := scanByRadius(sortedSet, query, radius)
+			sort.Strings(expected)
+			sort.Strings(members)
+			assert.Equal(t, expected, members)
+		}
+	}
+}
+
+func BenchmarkGeoRadius(b *testing.B) {
+	e := geoCmdExecutor{}
+	r := rand.New(rand.NewSource(1))
+
+	// A million members in a city-sized area, with queries of 1km radius
+	center := algo.GeoCoord{Lat: 40.7, Lon: -74}
+	sortedSet := makeGeoSet(r, center, 1, 1000000)
+	queries := make([]algo.GeoCoord, 1000)
+	for i := range queries {
+		queries[i] = algo.GeoCoord{
+			Lat: center.Lat + (r.Float64()-0.5)*0.8,
+			Lon: center.Lon + (r.Float64()-0.5)*0.8,
+		}
+	}
+	b.ResetTimer()
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			scanByRadius(sortedSet, queries[i%len(queries)], 1000)
+		}
+	})
+	b.Run("neighbors", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			opts := geoSearchOptions{Center: queries[i%len(queries)], ByRadius: true, Radius: 1000, UnitScale: 1}
+			e.search(sortedSet, &opts)
+		}
+	})
+}
+
+func TestGeoSearchByBox(t *testing.T) {
+	e := geoCmdExecutor{}
+	sortedSet := algo.MakeSkipList(1)
+	sortedSet.Add("Palermo", algo.GeoEncodeScore(algo.GeoCoord{Lat: 38.115556, Lon: 13.361389}), true)
+	sortedSet.Add("Catania", algo.GeoEncodeScore(algo.GeoCoord{Lat: 37.502669, Lon: 15.087269}), true)
+	sortedSet.Add("edge1", algo.GeoEncodeScore(algo.GeoCoord{Lat: 38.788135, Lon: 12.758489}), true)
+	sortedSet.Add("edge2", algo.GeoEncodeScore(algo.GeoCoord{Lat: 38.788135, Lon: 17.241510}), true)
+
+	members := func(results []*geoSearchResult) []string {
+		res := make([]string, 0)
+		for _, r := range results {
+			res = append(res, r.Member)
+		}
+		return res
+	}
+
+	opts := geoSearchOptions{Center: algo.GeoCoord{Lat: 37, Lon: 15}, ByBox: true, Width: 400000, Height: 400000, UnitScale: 1000, Sort: geoSortAsc}
+	assert.Equal(t, []string{"Catania", "Palermo", "edge2", "edge1"}, members(e.search(sortedSet, &opts)))
+
+	// A radius of the same size excludes the corners
+	opts = geoSearchOptions{Center: algo.GeoCoord{Lat: 37, Lon: 15}, ByRadius: true, Radius: 200000, UnitScale: 1000, Sort: geoSortDesc}
+	assert.Equal(t, []string{"Palermo", "Catania"}, members(e.search(sortedSet, &opts)))
+
+	opts.Count = 1
+	assert.Equal(t, []string{"Palermo"}, members(e.search(sortedSet, &opts)))
+}
+
+func TestParseGeoRadiusCmdArgs(t *testing.T) {
+	e := geoCmdExecutor{}
+	var key string
+	opts := geoSearchOptions{}
+	err := e.parseGeoRadiusCmdArgs(makeCmdArgs("Sicily", "15", "37", "2", "mi", "COUNT", "1", "STOREDIST", "dst"), false, false, &key, &opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "Sicily", key)
+	assert.InDelta(t, 3218.68, opts.Radius, 1e-6)
+	assert.Equal(t, geoSortAsc, opts.Sort)
+	assert.True(t, opts.Store && opts.StoreDist)
+	assert.Equal(t, "dst", opts.StoreKey)
+
+	opts = geoSearchOptions{}
+	err = e.parseGeoRadiusCmdArgs(makeCmdArgs("Sicily", "Palermo", "200", "km", "WITHDIST"), true, true, &key, &opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "Palermo", opts.FromMember)
+	assert.False(t, opts.FromLonLat)
+
+	opts = geoSearchOptions{}
+	err = e.parseGeoRadiusCmdArgs(makeCmdArgs("Sicily", "Palermo", "200", "km", "STORE", "dst"), true, true, &key, &opts)
+	assert.Equal(t, ErrSyntax, err)
+
+	opts = geoSearchOptions{}
+	err = e.parseGeoRadiusCmdArgs(makeCmdArgs("Sicily", "15", "37", "200", "km", "STORE", "dst", "WITHHASH"), false, false, &key, &opts)
+	assert.Equal(t, ErrGeoStoreWithReply, err)
+
+	opts = geoSearchOptions{}
+	err = e.parseGeoRadiusCmdArgs(makeCmdArgs("Sicily", "15", "37", "200", "yd"), false, false, &key, &opts)
+	assert.Equal(t, ErrGeoUnsupportedUnit, err)
+}
+
+func TestGeoSearchStoreDist(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	MakeGeofenceList()
+	e := geoCmdExecutor{}
+
+	runCmd(e, "GEOADD", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")
+	assert.Equal(t, "$8\r\n166.2273\r\n", runCmd(e, "GEODIST", "Sicily", "Palermo", "Catania", "km"))
+
+	// Scores are the distances in the unit of the search, instead of being rounded to integers
+	z := zsetCmdExecutor{}
+	assert.Equal(t, ":2\r\n", runCmd(e, "GEOSEARCHSTORE", "dst", "Sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "200", "km", "STOREDIST"))
+	assert.Equal(t, "$1\r\n0\r\n", runCmd(z, "ZSCORE", "dst", "Palermo"))
+	assert.Equal(t, "$10\r\n166.227251\r\n", runCmd(z, "ZSCORE", "dst", "Catania"))
+	assert.Equal(t, "*4\r\n$7\r\nPalermo\r\n$1\r\n0\r\n$7\r\nCatania\r\n$10\r\n166.227251\r\n", runCmd(z, "ZRANGE", "dst", "0", "1", "WITHSCORE"))
+
+	// Distances can be written and queried like the stored ones
+	assert.Equal(t, ":1\r\n", runCmd(z, "ZADD", "dst", "0.5", "Messina"))
+	assert.Equal(t, ":2\r\n", runCmd(z, "ZCOUNT", "dst", "0", "166.2"))
+	assert.Equal(t, "*2\r\n$7\r\nMessina\r\n$3\r\n0.5\r\n", runCmd(z, "ZRANGEBYSCORE", "dst", "0.1", "166.2", "WITHSCORES"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "GEORADIUS", "Sicily", "15", "37", "100", "mi", "STOREDIST", "dst"))
+	assert.Equal(t, "*1\r\n*2\r\n$7\r\nCatania\r\n$7\r\n35.0612\r\n", runCmd(e, "GEORADIUS", "Sicily", "15", "37", "100", "mi", "WITHDIST"))
+	assert.Equal(t, "$9\r\n35.061166\r\n", runCmd(z, "ZSCORE", "dst", "Catania"))
+
+	// STORE keeps integer geohash scores
+	assert.Equal(t, ":1\r\n", runCmd(e, "GEORADIUS", "Sicily", "15", "37", "100", "mi", "STORE", "dst"))
+	assert.Equal(t, "$16\r\n3479447370796909\r\n", runCmd(z, "ZSCORE", "dst", "Catania"))
+	Reset()
+}
@@ -0,0 +1,202 @@
+package cmdexec
+
+import (
+	"sort"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+const (
+	GeofenceEnter = "enter"
+	GeofenceExit  = "exit"
+)
+
+// A geofence remembers which members of its geo set are inside, so that only members crossing
+// its boundary trigger notifications
+type geofence struct {
+	Name   string
+	Shape  *geoSearchOptions
+	Inside map[string]bool
+}
+
+// Geofences and their subscribers are indexed by the keys of geo sets
+var geofences map[string]map[string]*geofence
+var geofenceSubscribers map[string][]*ClientInfo
+var geofenceClientKeys map[int][]string
+
+func MakeGeofenceList() {
+	geofences = make(map[string]map[string]*geofence)
+	geofenceSubscribers = make(map[string][]*ClientInfo)
+	geofenceClientKeys = make(map[int][]string)
+}
+
+func makeGeofence(name string, shape *geoSearchOptions, sortedSet *algo.SkipList) *geofence {
+	f := &geofence{
+		Name:   name,
+		Shape:  shape,
+		Inside: make(map[string]bool),
+	}
+	if sortedSet != nil {
+		for _, r := range (geoCmdExecutor{}).search(sortedSet, shape) {
+			f.Inside[r.Member] = true
+		}
+	}
+	return f
+}
+
+func (f *geofence) contains(coord algo.GeoCoord) bool {
+	_, inShape := geoCmdExecutor{}.getDistIfInShape(f.Shape, coord)
+	return inShape
+}
+
+// subscribeGeofences subscribes `c` to geofence notifications on `key`, and returns the number of keys
+// the client subscribes to
+func subscribeGeofences(c *ClientInfo, key string) int {
+	for _, k := range geofenceClientKeys[c.ConnFd] {
+		if k == key {
+			return len(geofenceClientKeys[c.ConnFd])
+		}
+	}
+	geofenceSubscribers[key] = append(geofenceSubscribers[key], c)
+	geofenceClientKeys[c.ConnFd] = append(geofenceClientKeys[c.ConnFd], key)
+	return len(geofenceClientKeys[c.ConnFd])
+}
+
+// unsubscribeGeofences unsubscribes the client from geofence notifications on `key`, and returns
+// the number of keys the client still subscribes to
+func unsubscribeGeofences(connFd int, key string) int {
+	subscribers := geofenceSubscribers[key]
+	for i, s := range subscribers {
+		if s.ConnFd == connFd {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+	if len(subscribers) == 0 {
+		delete(geofenceSubscribers, key)
+	} else {
+		geofenceSubscribers[key] = subscribers
+	}
+
+	keys := geofenceClientKeys[connFd]
+	for i, k := range keys {
+		if k == key {
+			keys = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		delete(geofenceClientKeys, connFd)
+	} else {
+		geofenceClientKeys[connFd] = keys
+	}
+	return len(keys)
+}
+
+// RemoveGeofenceSubscriber unsubscribes a disconnected client from all geofence notifications
+func RemoveGeofenceSubscriber(connFd int) {
+	keys := append([]string{}, geofenceClientKeys[connFd]...)
+	for _, key := range keys {
+		unsubscribeGeofences(connFd, key)
+	}
+}
+
+func IsGeofenceSubscriber(connFd int) bool {
+	_, found := geofenceClientKeys[connFd]
+	return found
+}
+
+// NotifyGeofences is called when `member` of the geo set at `key` moves to `coord`. Subscribers are
+// notified of every fence the member enters or exits.
+func NotifyGeofences(key string, member string, coord algo.GeoCoord) {
+	updateGeofences(key, member, func(f *geofence) bool {
+		return f.contains(coord)
+	})
+}
+
+// NotifyGeofencesOnRemove is called when `member` is removed from the geo set at `key`. Subscribers are
+// notified of every fence the member exits.
+func NotifyGeofencesOnRemove(key string, member string) {
+	updateGeofences(key, member, func(*geofence) bool {
+		return false
+	})
+}
+
+/*
+NotifyGeofencesOnReplace is called when the geo set at `key` is replaced by `sortedSet`, or deleted if it is nil.
+Members that are gone exit their fences, and the others are checked like GEOADD. Sorted sets that are not scored by
+geohashes, like the ones stored by STOREDIST, have no coordinates, so all their members exit.
+*/
+func NotifyGeofencesOnReplace(key string, sortedSet *algo.SkipList, isGeoSet bool) {
+	fences, found := geofences[key]
+	if !found {
+		return
+	}
+
+	removed := make(map[string]bool)
+	for _, f := range fences {
+		for member := range f.Inside {
+			if !isGeoSet || sortedSet == nil || sortedSet.MemberMap[member] == nil {
+				removed[member] = true
+			}
+		}
+	}
+	members := make([]string, 0, len(removed))
+	for member := range removed {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		NotifyGeofencesOnRemove(key, member)
+	}
+
+	if !isGeoSet || sortedSet == nil {
+		return
+	}
+	for node := sortedSet.Front(); node != nil && node != sortedSet.Tail; node = node.NextNodes[0] {
+		NotifyGeofences(key, node.Member, algo.GeoDecodeScore(node.Score))
+	}
+}
+
+// updateGeofences moves `member` of the geo set at `key` into or out of every fence, and notifies subscribers
+// of the changes
+func updateGeofences(key string, member string, isInside func(f *geofence) bool) {
+	fences, found := geofences[key]
+	if !found {
+		return
+	}
+
+	// Notify in the order of fence names
+	names := make([]string, 0, len(fences))
+	for name := range fences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fences[name]
+		inside := isInside(f)
+		if inside == f.Inside[member] {
+			continue
+		}
+
+		event := GeofenceExit
+		if inside {
+			event = GeofenceEnter
+			f.Inside[member] = true
+		} else {
+			delete(f.Inside, member)
+		}
+
+		for _, c := range geofenceSubscribers[key] {
+			AddArrayReplyEvent(c, []*resp.RespValue{
+				resp.MakeBulkString("geofence"),
+				resp.MakeBulkString(key),
+				resp.MakeBulkString(f.Name),
+				resp.MakeBulkString(member),
+				resp.MakeBulkString(event),
+			})
+		}
+	}
+}
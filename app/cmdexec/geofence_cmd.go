@@ -0,0 +1,181 @@
+package cmdexec
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+/*
+Syntax:
+  - GEOFENCE ADD key fence <BYRADIUS longitude latitude radius unit | BYPOLYGON numvertices longitude latitude [longitude latitude ...]>
+  - GEOFENCE DEL key fence
+  - GEOFENCE LIST key
+  - GEOFENCE SUBSCRIBE key [key ...]
+  - GEOFENCE UNSUBSCRIBE [key ...]
+
+Reply:
+  - Integer reply: 1 if the fence is added or deleted, or 0 if an existing fence is replaced or the fence is missing
+  - Array reply: a list of fence names for LIST
+  - Array reply: a [subscribe | unsubscribe, key, number of subscribed keys] message per key for SUBSCRIBE and UNSUBSCRIBE
+
+Subscribers receive a [geofence, key, fence, member, enter | exit] message when GEOADD moves a member
+into or out of a fence. Members removed by ZREM, or dropped when STORE or STOREDIST overwrites the geo set,
+exit their fences.
+*/
+func (e geoCmdExecutor) parseGeofenceCmdArgs(cmdArgs []*resp.RespValue, subCmd *string, keys *[]string, name *string, shape **geoSearchOptions) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*subCmd = strings.ToUpper(cmdArgs[0].BulkStr)
+	for _, arg := range cmdArgs[1:] {
+		*keys = append(*keys, arg.BulkStr)
+	}
+
+	switch *subCmd {
+	case "ADD":
+		if len(cmdArgs) < 5 {
+			return ErrInvalidArgs
+		}
+		*keys = (*keys)[:1]
+		*name = cmdArgs[2].BulkStr
+		*shape = &geoSearchOptions{UnitScale: 1, FromLonLat: true}
+
+		var numArgs int
+		switch strings.ToUpper(cmdArgs[3].BulkStr) {
+		case "BYRADIUS":
+			if len(cmdArgs) != 8 {
+				return ErrSyntax
+			}
+			err = parseGeoFloats(cmdArgs[4:], &(*shape).Center.Lon, &(*shape).Center.Lat, &(*shape).Radius)
+			if err != nil {
+				return err
+			}
+			err = checkGeoCoord((*shape).Center)
+			if err != nil {
+				return err
+			}
+			(*shape).UnitScale, err = parseGeoUnit(cmdArgs[7].BulkStr)
+			if err != nil {
+				return err
+			}
+			(*shape).Radius *= (*shape).UnitScale
+			(*shape).ByRadius = true
+		case "BYPOLYGON":
+			err = e.parseGeoPolygon(cmdArgs[4:], &(*shape).Polygon, &numArgs)
+			if err != nil {
+				return err
+			}
+			if 4+numArgs != len(cmdArgs) {
+				return ErrSyntax
+			}
+			(*shape).Center = (*shape).Polygon.Center()
+			(*shape).ByPolygon = true
+		default:
+			return ErrSyntax
+		}
+	case "DEL":
+		if len(cmdArgs) != 3 {
+			return ErrInvalidArgs
+		}
+		*keys = (*keys)[:1]
+		*name = cmdArgs[2].BulkStr
+	case "LIST":
+		if len(cmdArgs) != 2 {
+			return ErrInvalidArgs
+		}
+	case "SUBSCRIBE":
+		if len(cmdArgs) < 2 {
+			return ErrInvalidArgs
+		}
+	case "UNSUBSCRIBE":
+	default:
+		return ErrInvalidArgs
+	}
+	return nil
+}
+
+func (e geoCmdExecutor) makeSubscriptionReply(kind string, key *resp.RespValue, numKeys int) *resp.RespValue {
+	return resp.MakeArray([]*resp.RespValue{
+		resp.MakeBulkString(kind),
+		key,
+		resp.MakeInt(numKeys),
+	})
+}
+
+func (e geoCmdExecutor) executeGeofenceCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		subCmd string
+		keys   []string
+		name   string
+		shape  *geoSearchOptions
+	)
+	err := e.parseGeofenceCmdArgs(cmdArgs, &subCmd, &keys, &name, &shape)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	switch subCmd {
+	case "ADD":
+		// Members already inside the fence do not trigger notifications
+		fences, found := geofences[keys[0]]
+		if !found {
+			fences = make(map[string]*geofence)
+			geofences[keys[0]] = fences
+		}
+		_, replaced := fences[name]
+		fences[name] = makeGeofence(name, shape, db.SortedSetStore[keys[0]])
+
+		if replaced {
+			AddIntegerReplyEvent(c, 0)
+		} else {
+			AddIntegerReplyEvent(c, 1)
+		}
+	case "DEL":
+		fences := geofences[keys[0]]
+		_, found := fences[name]
+		if !found {
+			AddIntegerReplyEvent(c, 0)
+			return
+		}
+		delete(fences, name)
+		if len(fences) == 0 {
+			delete(geofences, keys[0])
+		}
+		AddIntegerReplyEvent(c, 1)
+	case "LIST":
+		names := make([]string, 0)
+		for name := range geofences[keys[0]] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		res := make([]*resp.RespValue, 0, len(names))
+		for _, name := range names {
+			res = append(res, resp.MakeBulkString(name))
+		}
+		AddArrayReplyEvent(c, res)
+	case "SUBSCRIBE":
+		for _, key := range keys {
+			numKeys := subscribeGeofences(c, key)
+			AddReplyEvent(c, e.makeSubscriptionReply("subscribe", resp.MakeBulkString(key), numKeys))
+		}
+	case "UNSUBSCRIBE":
+		// Unsubscribe from all keys if none is provided
+		if len(keys) == 0 {
+			keys = append(keys, geofenceClientKeys[c.ConnFd]...)
+		}
+		if len(keys) == 0 {
+			AddReplyEvent(c, e.makeSubscriptionReply("unsubscribe", resp.MakeNilBulkString(), 0))
+			return
+		}
+		for _, key := range keys {
+			numKeys := unsubscribeGeofences(c.ConnFd, key)
+			AddReplyEvent(c, e.makeSubscriptionReply("unsubscribe", resp.MakeBulkString(key), numKeys))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeofenceNotifications(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	MakeGeofenceList()
+	sub := &ClientInfo{ConnFd: 2}
+	e := geoCmdExecutor{}
+
+	// Collects [fence, member, event] of notifications sent to the subscriber
+	run := func(cmdName string, args ...string) [][]string {
+		runCmd(e, cmdName, args...)
+		res := make([][]string, 0)
+		for _, ev := range EventBus {
+			if ev.Client == sub {
+				res = append(res, []string{ev.Resp.Array[2].BulkStr, ev.Resp.Array[3].BulkStr, ev.Resp.Array[4].BulkStr})
+			}
+		}
+		return res
+	}
+
+	run("GEOADD", "Sicily", "13.361389", "38.115556", "Palermo")
+	run("GEOFENCE", "ADD", "Sicily", "east", "BYRADIUS", "15", "37.5", "50", "km")
+	run("GEOFENCE", "ADD", "Sicily", "west", "BYPOLYGON", "4", "12", "37", "14", "37", "14", "39", "12", "39")
+	e.Execute(sub, "GEOFENCE", makeCmdArgs("SUBSCRIBE", "Sicily"))
+
+	// Members already inside a fence when it is added do not trigger notifications
+	assert.Equal(t, [][]string{}, run("GEOADD", "Sicily", "13.5", "38", "Palermo"))
+	assert.Equal(t, [][]string{{"west", "Catania", "enter"}}, run("GEOADD", "Sicily", "13", "37.5", "Catania"))
+	assert.Equal(t, [][]string{{"east", "Palermo", "enter"}, {"west", "Palermo", "exit"}}, run("GEOADD", "Sicily", "15", "37.6", "Palermo"))
+	assert.Equal(t, [][]string{}, run("GEOADD", "Sicily", "15.1", "37.6", "Palermo"))
+
+	run("GEOFENCE", "DEL", "Sicily", "east")
+	assert.Equal(t, [][]string{{"west", "Palermo", "enter"}}, run("GEOADD", "Sicily", "13", "38", "Palermo"))
+
+	// Disconnected clients are no longer notified
+	assert.True(t, IsGeofenceSubscriber(sub.ConnFd))
+	RemoveGeofenceSubscriber(sub.ConnFd)
+	assert.False(t, IsGeofenceSubscriber(sub.ConnFd))
+	assert.Equal(t, [][]string{}, run("GEOADD", "Sicily", "15", "37.6", "Palermo"))
+	Reset()
+}
+
+func TestGeofenceNotificationsOnRemove(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	MakeGeofenceList()
+	sub := &ClientInfo{ConnFd: 2}
+
+	// Collects [fence, member, event] of notifications sent to the subscriber
+	run := func(cmdName string, args ...string) [][]string {
+		runCmd(CmdLookupTable[cmdName], cmdName, args...)
+		res := make([][]string, 0)
+		for _, ev := range EventBus {
+			if ev.Client == sub {
+				res = append(res, []string{ev.Resp.Array[2].BulkStr, ev.Resp.Array[3].BulkStr, ev.Resp.Array[4].BulkStr})
+			}
+		}
+		return res
+	}
+
+	run("GEOADD", "Sicily", "13", "38", "Palermo", "15", "37.6", "Catania")
+	run("GEOFENCE", "ADD", "Sicily", "east", "BYRADIUS", "15", "37.5", "50", "km")
+	run("GEOFENCE", "ADD", "Sicily", "west", "BYPOLYGON", "4", "12", "37", "14", "37", "14", "39", "12", "39")
+	geoCmdExecutor{}.Execute(sub, "GEOFENCE", makeCmdArgs("SUBSCRIBE", "Sicily"))
+
+	// Removed members exit their fences
+	assert.Equal(t, [][]string{{"west", "Palermo", "exit"}}, run("ZREM", "Sicily", "Palermo", "Missing"))
+	assert.Equal(t, [][]string{{"west", "Palermo", "enter"}}, run("GEOADD", "Sicily", "13", "38", "Palermo"))
+
+	// Members that are not stored again exit when the geo set is overwritten
+	assert.Equal(t, [][]string{{"east", "Catania", "exit"}},
+		run("GEOSEARCHSTORE", "Sicily", "Sicily", "FROMLONLAT", "13", "38", "BYRADIUS", "10", "km"))
+	assert.Equal(t, [][]string{{"east", "Catania", "enter"}}, run("GEOADD", "Sicily", "15", "37.6", "Catania"))
+
+	// Distances are not coordinates, so all members exit with STOREDIST
+	assert.Equal(t, [][]string{{"east", "Catania", "exit"}, {"west", "Palermo", "exit"}},
+		run("GEORADIUS", "Sicily", "15", "37.6", "500", "km", "STOREDIST", "Sicily"))
+	assert.Equal(t, [][]string{{"west", "Palermo", "enter"}}, run("GEOADD", "Sicily", "13", "38", "Palermo"))
+
+	// Empty results delete the geo set
+	assert.Equal(t, [][]string{{"west", "Palermo", "exit"}},
+		run("GEOSEARCHSTORE", "Sicily", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "km"))
+	Reset()
+}
@@ -0,0 +1,101 @@
+package cmdexec
+
+import (
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+type hashCmdExecutor struct{}
+
+/*
+Syntax: HSET key field value [field value ...]
+Reply:
+  - Integer reply: the number of fields that are added
+*/
+func (e hashCmdExecutor) executeHSetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 3 || len(cmdArgs)%2 != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	hash, found := db.HashStore[key]
+	if !found {
+		hash = make(map[string]string)
+		db.HashStore[key] = hash
+	}
+	numAdded := 0
+	for i := 1; i < len(cmdArgs); i += 2 {
+		if _, found := hash[cmdArgs[i].BulkStr]; !found {
+			numAdded++
+		}
+		hash[cmdArgs[i].BulkStr] = cmdArgs[i+1].BulkStr
+	}
+	AddIntegerReplyEvent(c, numAdded)
+}
+
+/*
+Syntax: HGET key field
+Reply:
+  - Bulk string reply: the value of the field
+  - Null reply: the field or the key does not exist
+*/
+func (e hashCmdExecutor) executeHGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	val, found := db.HashStore[cmdArgs[0].BulkStr][cmdArgs[1].BulkStr]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	AddBulkStringReplyEvent(c, val)
+}
+
+/*
+Syntax: HDEL key field [field ...]
+Reply:
+  - Integer reply: the number of fields that are removed
+
+The key is removed with its last field.
+*/
+func (e hashCmdExecutor) executeHDelCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	hash, found := db.HashStore[key]
+	if !found {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	numRemoved := 0
+	for _, arg := range cmdArgs[1:] {
+		if _, found := hash[arg.BulkStr]; found {
+			delete(hash, arg.BulkStr)
+			numRemoved++
+		}
+	}
+	if len(hash) == 0 {
+		delete(db.HashStore, key)
+	}
+	AddIntegerReplyEvent(c, numRemoved)
+}
+
+func (e hashCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "HSET":
+		e.executeHSetCmd(c, cmdArgs)
+	case "HGET":
+		e.executeHGetCmd(c, cmdArgs)
+	case "HDEL":
+		e.executeHDelCmd(c, cmdArgs)
+	}
+
+	// Search indexes watch hashes, so they are updated after every write
+	if (cmdName == "HSET" || cmdName == "HDEL") && len(cmdArgs) > 0 {
+		updateSearchIndexes(cmdArgs[0].BulkStr)
+	}
+}
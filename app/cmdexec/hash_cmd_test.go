@@ -0,0 +1,29 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := hashCmdExecutor{}
+
+	assert.Equal(t, ":2\r\n", runCmd(e, "HSET", "h", "a", "1", "b", "2"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "HSET", "h", "a", "3", "c", "4"))
+	assert.Equal(t, "$1\r\n3\r\n", runCmd(e, "HGET", "h", "a"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "HGET", "h", "missing"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "HGET", "missing", "a"))
+
+	assert.Equal(t, ":2\r\n", runCmd(e, "HDEL", "h", "a", "b", "missing"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "HDEL", "missing", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "HDEL", "h", "c"))
+	_, found := db.HashStore["h"]
+	assert.False(t, found)
+
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "HSET", "h", "a"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "HGET", "h"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "HDEL", "h"))
+}
@@ -0,0 +1,20 @@
+package cmdexec
+
+import (
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+func makeCmdArgs(args ...string) []*resp.RespValue {
+	res := make([]*resp.RespValue, 0)
+	for _, a := range args {
+		res = append(res, resp.MakeBulkString(a))
+	}
+	return res
+}
+
+// runCmd executes a command on a fresh event bus and returns the serialized reply
+func runCmd(e cmdExecutor, cmdName string, args ...string) string {
+	Reset()
+	e.Execute(&ClientInfo{}, cmdName, makeCmdArgs(args...))
+	return string(EventBus[0].Resp.ToByteArray())
+}
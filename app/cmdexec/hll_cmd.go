@@ -0,0 +1,205 @@
+package cmdexec
+
+import (
+	"errors"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrHllWrongType = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHllCorrupted = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// HLLs are strings in the dict store, serialized the same as Redis. They are stored as bytes, so that
+// they are updated in place.
+type hllCmdExecutor struct{}
+
+// lookUp returns the HLL at `key` and its value, or nil if it does not exist. The HLL shares its
+// bytes with the value.
+func (e hllCmdExecutor) lookUp(key string) (*algo.HyperLogLog, *DictStoreValue, error) {
+	val, found := setCmdExecutor{}.lookUp(key)
+	if !found {
+		return nil, nil, nil
+	}
+	buf := val.Bytes
+	if val.Encoding != EncodingBytes {
+		buf = []byte(val.String())
+	}
+	h, err := algo.LoadHyperLogLog(buf)
+	if err != nil {
+		return nil, nil, ErrHllWrongType
+	}
+	val.SetBytes(buf)
+	return h, val, nil
+}
+
+// store stores the HLL at `key`, where `val` is its existing value or nil. The TTL of an existing
+// key is kept.
+func (e hllCmdExecutor) store(key string, val *DictStoreValue, h *algo.HyperLogLog) {
+	if val == nil {
+		val = &DictStoreValue{}
+		db.DictStore[key] = val
+	}
+	val.SetBytes(h.Buf)
+}
+
+/*
+Syntax: PFADD key [element [element ...]]
+Reply:
+  - Integer reply: 1 if the HLL is created or any register is updated, or 0 otherwise
+*/
+func (e hllCmdExecutor) executePfAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	h, val, err := e.lookUp(key)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	created := h == nil
+	if created {
+		h = algo.MakeHyperLogLog()
+	}
+
+	elems := make([][]byte, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[1:] {
+		elems = append(elems, []byte(arg.BulkStr))
+	}
+	updated, err := h.Add(elems...)
+	if err != nil {
+		AddErrorReplyEvent(c, ErrHllCorrupted)
+		return
+	}
+
+	if !created && !updated {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	e.store(key, val, h)
+	AddIntegerReplyEvent(c, 1)
+}
+
+/*
+Syntax: PFCOUNT key [key ...]
+Reply:
+  - Integer reply: the estimated cardinality of the union of HLLs, where missing keys are empty
+*/
+func (e hllCmdExecutor) executePfCountCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	// The cardinality of a single HLL is cached in its header, which is only written when it is stale
+	if len(cmdArgs) == 1 {
+		h, _, err := e.lookUp(cmdArgs[0].BulkStr)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+		if h == nil {
+			AddIntegerReplyEvent(c, 0)
+			return
+		}
+		count, err := h.Count()
+		if err != nil {
+			AddErrorReplyEvent(c, ErrHllCorrupted)
+			return
+		}
+		AddIntegerReplyEvent(c, int(count))
+		return
+	}
+
+	regs := make([]uint8, algo.HllRegisters)
+	for _, arg := range cmdArgs {
+		h, _, err := e.lookUp(arg.BulkStr)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+		if h == nil {
+			continue
+		}
+		err = h.Merge(regs)
+		if err != nil {
+			AddErrorReplyEvent(c, ErrHllCorrupted)
+			return
+		}
+	}
+	AddIntegerReplyEvent(c, int(algo.HllCount(regs)))
+}
+
+/*
+Syntax: PFMERGE destkey [sourcekey [sourcekey ...]]
+Reply:
+  - Simple string reply: OK
+
+The union of the destination and source HLLs is stored at destkey. Same as Redis, the result is dense
+if any of the HLLs is dense.
+*/
+func (e hllCmdExecutor) executePfMergeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	destKey := cmdArgs[0].BulkStr
+
+	var (
+		dest    *algo.HyperLogLog
+		destVal *DictStoreValue
+	)
+	regs := make([]uint8, algo.HllRegisters)
+	useDense := false
+	for i, arg := range cmdArgs {
+		h, val, err := e.lookUp(arg.BulkStr)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+		if h == nil {
+			continue
+		}
+		if i == 0 {
+			dest, destVal = h, val
+		}
+		if h.Encoding() == algo.HllDense {
+			useDense = true
+		}
+		err = h.Merge(regs)
+		if err != nil {
+			AddErrorReplyEvent(c, ErrHllCorrupted)
+			return
+		}
+	}
+
+	if dest == nil {
+		dest = algo.MakeHyperLogLog()
+	}
+	if useDense {
+		err := dest.ToDense()
+		if err != nil {
+			AddErrorReplyEvent(c, ErrHllCorrupted)
+			return
+		}
+	}
+	dest.SetRegisters(regs)
+	e.store(destKey, destVal, dest)
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+func (e hllCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "PFADD":
+		e.executePfAddCmd(c, cmdArgs)
+	case "PFCOUNT":
+		e.executePfCountCmd(c, cmdArgs)
+	case "PFMERGE":
+		e.executePfMergeCmd(c, cmdArgs)
+	}
+}
@@ -0,0 +1,69 @@
+package cmdexec
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHllCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := hllCmdExecutor{}
+
+	// Same bytes as PFADD hll a in Redis
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFADD", "hll", "a"))
+	assert.Equal(t, "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x71\xa6\x84\x4e\x57", db.DictStore["hll"].String())
+	assert.Equal(t, ":0\r\n", runCmd(e, "PFADD", "hll", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFCOUNT", "hll"))
+	assert.Equal(t, "HYLL\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x71\xa6\x84\x4e\x57", db.DictStore["hll"].String())
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFADD", "empty"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "PFADD", "empty"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "PFCOUNT", "empty", "missing"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFADD", "hll", "foo", "bar", "zap"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "PFADD", "hll", "zap", "zap", "zap"))
+	assert.Equal(t, ":4\r\n", runCmd(e, "PFCOUNT", "hll"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFADD", "other", "1", "2", "3", "a"))
+	assert.Equal(t, ":7\r\n", runCmd(e, "PFCOUNT", "hll", "other"))
+
+	// Values copied from Redis are loaded with SET
+	db.DictStore["copy"] = MakeDictStoreValue("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x71\xa6\x84\x4e\x57")
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFCOUNT", "copy"))
+
+	// Dense sources make the result dense
+	args := []string{"dense"}
+	for i := 0; i < 5000; i++ {
+		args = append(args, "elem:"+strconv.Itoa(i))
+	}
+	runCmd(e, "PFADD", args...)
+	h, _ := algo.LoadHyperLogLog([]byte(db.DictStore["dense"].String()))
+	assert.Equal(t, algo.HllDense, h.Encoding())
+	assert.Equal(t, EncodingBytes, db.DictStore["dense"].Encoding)
+
+	// Dense HLLs are updated in place
+	regs := &db.DictStore["dense"].Bytes[0]
+	assert.Equal(t, ":1\r\n", runCmd(e, "PFADD", "dense", "elem:5000", "elem:5001", "elem:5002"))
+	runCmd(e, "PFCOUNT", "dense")
+	assert.Same(t, regs, &db.DictStore["dense"].Bytes[0])
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "PFMERGE", "hll", "other"))
+	assert.Equal(t, ":7\r\n", runCmd(e, "PFCOUNT", "hll"))
+	h, _ = algo.LoadHyperLogLog([]byte(db.DictStore["hll"].String()))
+	assert.Equal(t, algo.HllSparse, h.Encoding())
+	assert.Equal(t, "+OK\r\n", runCmd(e, "PFMERGE", "merged", "hll", "dense"))
+	h, _ = algo.LoadHyperLogLog([]byte(db.DictStore["merged"].String()))
+	assert.Equal(t, algo.HllDense, h.Encoding())
+	count, _ := h.Count()
+	assert.InEpsilon(t, 5007, count, 0.03)
+
+	db.DictStore["str"] = MakeDictStoreValue("hello")
+	assert.Equal(t, "-WRONGTYPE Key is not a valid HyperLogLog string value.\r\n", runCmd(e, "PFADD", "str", "a"))
+	assert.Equal(t, "-WRONGTYPE Key is not a valid HyperLogLog string value.\r\n", runCmd(e, "PFCOUNT", "hll", "str"))
+	db.DictStore["bad"] = MakeDictStoreValue("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x7f\xfe")
+	assert.Equal(t, "-INVALIDOBJ Corrupted HLL object detected\r\n", runCmd(e, "PFCOUNT", "bad"))
+	assert.Equal(t, "-INVALIDOBJ Corrupted HLL object detected\r\n", runCmd(e, "PFADD", "bad", "a"))
+}
@@ -0,0 +1,219 @@
+package cmdexec
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrNotInteger      = errors.New("ERR value is not an integer or out of range")
+	ErrNotFloat        = errors.New("ERR value is not a valid float")
+	ErrIncrOverflow    = errors.New("ERR increment or decrement would overflow")
+	ErrDecrOverflow    = errors.New("ERR decrement would overflow")
+	ErrIncrNaNInfinity = errors.New("ERR increment would produce NaN or Infinity")
+)
+
+// parseInt parses `val` as a 64-bit integer the same way as Redis, rejecting leading zeros,
+// signs and spaces
+func parseInt(val string) (int64, error) {
+	v := MakeDictStoreValue(val)
+	if v.Encoding != EncodingInt {
+		return 0, ErrNotInteger
+	}
+	return v.IntValue, nil
+}
+
+func parseFloat(val string) (float64, error) {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrNotFloat
+	}
+	return f, nil
+}
+
+// Same as Redis, INCRBYFLOAT adds in long double precision, which has a 64-bit mantissa, so that
+// 0.1 + 0.2 is 0.3 once printed with 17 significant digits
+const longDoublePrec = 64
+
+func parseLongDouble(val string) (*big.Float, error) {
+	if _, err := parseFloat(val); err != nil {
+		return nil, err
+	}
+	f, _, err := big.ParseFloat(val, 0, longDoublePrec, big.ToNearestEven)
+	if err != nil {
+		return nil, ErrNotFloat
+	}
+	return f, nil
+}
+
+// formatLongDouble prints 17 significant digits without exponents or trailing zeros
+func formatLongDouble(f *big.Float) string {
+	mant, expStr, _ := strings.Cut(f.Text('e', 16), "e")
+	exp, _ := strconv.Atoi(expStr)
+	sign := ""
+	if strings.HasPrefix(mant, "-") {
+		sign, mant = "-", mant[1:]
+	}
+	digits := strings.Replace(mant, ".", "", 1)
+
+	var s string
+	switch {
+	case exp < 0:
+		s = "0." + strings.Repeat("0", -exp-1) + digits
+	case exp+1 >= len(digits):
+		s = digits + strings.Repeat("0", exp+1-len(digits))
+	default:
+		s = digits[:exp+1] + "." + digits[exp+1:]
+	}
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "0" {
+		return s
+	}
+	return sign + s
+}
+
+/*
+Syntax:
+  - INCR key
+  - DECR key
+  - INCRBY key increment
+  - DECRBY key decrement
+
+Reply:
+  - Integer reply: the value of the key after the increment
+*/
+func (e setCmdExecutor) parseIncrCmdArgs(cmdArgs []*resp.RespValue, cmdName string, key *string, incr *int64) error {
+	var err error
+
+	switch cmdName {
+	case "INCR", "DECR":
+		if len(cmdArgs) != 1 {
+			return ErrInvalidArgs
+		}
+		*incr = 1
+	case "INCRBY", "DECRBY":
+		if len(cmdArgs) != 2 {
+			return ErrInvalidArgs
+		}
+		*incr, err = parseInt(cmdArgs[1].BulkStr)
+		if err != nil {
+			return err
+		}
+	}
+	*key = cmdArgs[0].BulkStr
+
+	if cmdName == "DECR" || cmdName == "DECRBY" {
+		// The decrement cannot be negated
+		if *incr == math.MinInt64 {
+			return ErrDecrOverflow
+		}
+		*incr = -*incr
+	}
+	return nil
+}
+
+func (e setCmdExecutor) executeIncrCmd(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	var (
+		key  string
+		incr int64
+	)
+	err := e.parseIncrCmdArgs(cmdArgs, cmdName, &key, &incr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// A missing key starts from 0
+	var curr int64
+	found := e.doesKeyExistOrUnexpire(key)
+	val := db.DictStore[key]
+	if found {
+		// Bitmaps are stored as bytes, but they can still spell integers
+		if val.Encoding == EncodingBytes {
+			val.SetString(val.String())
+		}
+		if val.Encoding != EncodingInt {
+			AddErrorReplyEvent(c, ErrNotInteger)
+			return
+		}
+		curr = val.IntValue
+	} else {
+		val = &DictStoreValue{}
+	}
+
+	if (incr > 0 && curr > math.MaxInt64-incr) || (incr < 0 && curr < math.MinInt64-incr) {
+		AddErrorReplyEvent(c, ErrIncrOverflow)
+		return
+	}
+
+	// The TTL of an existing key is kept
+	val.SetInt(curr + incr)
+	if !found {
+		db.DictStore[key] = val
+	}
+	AddIntegerReplyEvent(c, int(val.IntValue))
+}
+
+/*
+Syntax: INCRBYFLOAT key increment
+Reply:
+  - Bulk string reply: the value of the key after the increment
+*/
+func (e setCmdExecutor) parseIncrByFloatCmdArgs(cmdArgs []*resp.RespValue, key *string, incr **big.Float) error {
+	if len(cmdArgs) != 2 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*key = cmdArgs[0].BulkStr
+	*incr, err = parseLongDouble(cmdArgs[1].BulkStr)
+	return err
+}
+
+func (e setCmdExecutor) executeIncrByFloatCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key  string
+		incr *big.Float
+	)
+	err := e.parseIncrByFloatCmdArgs(cmdArgs, &key, &incr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// A missing key starts from 0
+	curr := new(big.Float).SetPrec(longDoublePrec)
+	found := e.doesKeyExistOrUnexpire(key)
+	val := db.DictStore[key]
+	if found {
+		curr, err = parseLongDouble(val.String())
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+	} else {
+		val = &DictStoreValue{}
+	}
+
+	// Results are stored as strings that must parse as floats again
+	res := curr.Add(curr, incr)
+	if f, _ := res.Float64(); math.IsInf(f, 0) {
+		AddErrorReplyEvent(c, ErrIncrNaNInfinity)
+		return
+	}
+
+	// Same as Redis, the result is stored as a string without exponents or trailing zeros.
+	// The TTL of an existing key is kept.
+	val.SetString(formatLongDouble(res))
+	if !found {
+		db.DictStore[key] = val
+	}
+	AddBulkStringReplyEvent(c, val.String())
+}
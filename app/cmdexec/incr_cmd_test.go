@@ -0,0 +1,69 @@
+package cmdexec
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictStoreValueEncoding(t *testing.T) {
+	for _, s := range []string{"0", "-1", "123", "9223372036854775807", "-9223372036854775808"} {
+		v := MakeDictStoreValue(s)
+		assert.Equal(t, EncodingInt, v.Encoding, s)
+		assert.Equal(t, s, v.String())
+	}
+	for _, s := range []string{"", "007", "+1", "-0", " 1", "1.0", "9223372036854775808", "abc"} {
+		v := MakeDictStoreValue(s)
+		assert.Equal(t, EncodingRaw, v.Encoding, s)
+		assert.Equal(t, s, v.String())
+	}
+}
+
+func TestIncr(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := setCmdExecutor{}
+
+	assert.Equal(t, ":5\r\n", runCmd(e, "INCRBY", "counter", "5"))
+	assert.Equal(t, ":-5\r\n", runCmd(e, "DECRBY", "counter", "10"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", runCmd(e, "INCRBY", "counter", "007"))
+
+	// Overflows leave the value untouched
+	runCmd(e, "SET", "counter", "9223372036854775806")
+	assert.Equal(t, ":9223372036854775807\r\n", runCmd(e, "INCR", "counter"))
+	assert.Equal(t, "-ERR increment or decrement would overflow\r\n", runCmd(e, "INCR", "counter"))
+	assert.Equal(t, int64(math.MaxInt64), db.DictStore["counter"].IntValue)
+	assert.Equal(t, "-ERR decrement would overflow\r\n", runCmd(e, "DECRBY", "counter", "-9223372036854775808"))
+
+	// TTLs are kept
+	runCmd(e, "SET", "counter", "1", "EX", "100")
+	expireTime := db.DictStore["counter"].ExpireTime
+	assert.Equal(t, ":0\r\n", runCmd(e, "DECR", "counter"))
+	assert.True(t, db.DictStore["counter"].WillExpire)
+	assert.Equal(t, expireTime, db.DictStore["counter"].ExpireTime)
+
+	// Expired keys start from 0
+	db.DictStore["counter"].ExpireTime = time.Now().Add(-time.Second)
+	assert.Equal(t, ":1\r\n", runCmd(e, "INCR", "counter"))
+	assert.False(t, db.DictStore["counter"].WillExpire)
+
+	runCmd(e, "SET", "float", "10.50")
+	assert.Equal(t, "$4\r\n10.6\r\n", runCmd(e, "INCRBYFLOAT", "float", "0.1"))
+	assert.Equal(t, "$1\r\n5\r\n", runCmd(e, "INCRBYFLOAT", "float", "-5.6"))
+	assert.Equal(t, EncodingInt, db.DictStore["float"].Encoding)
+	assert.Equal(t, "$4\r\n5000\r\n", runCmd(e, "INCRBYFLOAT", "float", "4.995e3"))
+	assert.Equal(t, "-ERR value is not a valid float\r\n", runCmd(e, "INCRBYFLOAT", "float", "nan"))
+
+	// Same as Redis, sums are printed with 17 significant digits
+	assert.Equal(t, "$3\r\n0.1\r\n", runCmd(e, "INCRBYFLOAT", "sum", "0.1"))
+	assert.Equal(t, "$3\r\n0.3\r\n", runCmd(e, "INCRBYFLOAT", "sum", "0.2"))
+	assert.Equal(t, "$1\r\n0\r\n", runCmd(e, "INCRBYFLOAT", "sum", "-0.3"))
+	assert.Equal(t, "$10\r\n0.00000015\r\n", runCmd(e, "INCRBYFLOAT", "sum", "1.5e-7"))
+	assert.Equal(t, "$21\r\n100000000000000000000\r\n", runCmd(e, "INCRBYFLOAT", "big", "1e20"))
+	assert.Equal(t, "$19\r\n-3.3333333333333333\r\n", runCmd(e, "INCRBYFLOAT", "neg", "-3.33333333333333333333"))
+
+	runCmd(e, "SET", "float", "1.7e308")
+	assert.Equal(t, "-ERR increment would produce NaN or Infinity\r\n", runCmd(e, "INCRBYFLOAT", "float", "1.7e308"))
+}
@@ -0,0 +1,455 @@
+package cmdexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	JsonTypeNull = iota
+	JsonTypeBoolean
+	JsonTypeInteger
+	JsonTypeNumber
+	JsonTypeString
+	JsonTypeArray
+	JsonTypeObject
+)
+
+var jsonTypeNames = []string{"null", "boolean", "integer", "number", "string", "array", "object"}
+
+var (
+	ErrJsonSyntax = errors.New("ERR invalid JSON value")
+	ErrJsonPath   = errors.New("ERR invalid JSON path")
+)
+
+/*
+JsonValue is a node of a JSON document. Integers and floating-point numbers are different types,
+so that integers stay integers after increments. Object fields keep their insertion order.
+
+Nodes are modified in place, so that the results of a path query stay valid while they are updated.
+*/
+type JsonValue struct {
+	Type   int
+	Bool   bool
+	Int    int64
+	Float  float64
+	Str    string
+	Array  []*JsonValue
+	Keys   []string
+	Fields map[string]*JsonValue
+}
+
+func MakeJsonArray(values []*JsonValue) *JsonValue {
+	return &JsonValue{Type: JsonTypeArray, Array: values}
+}
+
+func MakeJsonObject() *JsonValue {
+	return &JsonValue{Type: JsonTypeObject, Keys: make([]string, 0), Fields: make(map[string]*JsonValue)}
+}
+
+func (v *JsonValue) TypeName() string {
+	return jsonTypeNames[v.Type]
+}
+
+func (v *JsonValue) IsNumber() bool {
+	return v.Type == JsonTypeInteger || v.Type == JsonTypeNumber
+}
+
+func (v *JsonValue) Number() float64 {
+	if v.Type == JsonTypeInteger {
+		return float64(v.Int)
+	}
+	return v.Float
+}
+
+// Set adds or replaces a field of an object
+func (v *JsonValue) Set(key string, field *JsonValue) {
+	if _, found := v.Fields[key]; !found {
+		v.Keys = append(v.Keys, key)
+	}
+	v.Fields[key] = field
+}
+
+// Remove removes a child of an array or an object by its identity, and returns false if it is not a child
+func (v *JsonValue) Remove(child *JsonValue) bool {
+	switch v.Type {
+	case JsonTypeArray:
+		for i, e := range v.Array {
+			if e == child {
+				v.Array = append(v.Array[:i], v.Array[i+1:]...)
+				return true
+			}
+		}
+	case JsonTypeObject:
+		for i, key := range v.Keys {
+			if v.Fields[key] == child {
+				v.Keys = append(v.Keys[:i], v.Keys[i+1:]...)
+				delete(v.Fields, key)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *JsonValue) Clone() *JsonValue {
+	c := *v
+	switch v.Type {
+	case JsonTypeArray:
+		c.Array = make([]*JsonValue, 0, len(v.Array))
+		for _, e := range v.Array {
+			c.Array = append(c.Array, e.Clone())
+		}
+	case JsonTypeObject:
+		c.Keys = append([]string{}, v.Keys...)
+		c.Fields = make(map[string]*JsonValue, len(v.Fields))
+		for key, field := range v.Fields {
+			c.Fields[key] = field.Clone()
+		}
+	}
+	return &c
+}
+
+func ParseJson(s string) (*JsonValue, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	v, err := parseJsonValue(dec)
+	if err != nil {
+		return nil, ErrJsonSyntax
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrJsonSyntax
+	}
+	return v, nil
+}
+
+func parseJsonValue(dec *json.Decoder) (*JsonValue, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case nil:
+		return &JsonValue{Type: JsonTypeNull}, nil
+	case bool:
+		return &JsonValue{Type: JsonTypeBoolean, Bool: t}, nil
+	case string:
+		return &JsonValue{Type: JsonTypeString, Str: t}, nil
+	case json.Number:
+		if !strings.ContainsAny(t.String(), ".eE") {
+			if i, err := t.Int64(); err == nil {
+				return &JsonValue{Type: JsonTypeInteger, Int: i}, nil
+			}
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &JsonValue{Type: JsonTypeNumber, Float: f}, nil
+	}
+
+	switch tok.(json.Delim) {
+	case '[':
+		v := MakeJsonArray(make([]*JsonValue, 0))
+		for dec.More() {
+			e, err := parseJsonValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.Array = append(v.Array, e)
+		}
+		_, err = dec.Token()
+		return v, err
+	case '{':
+		v := MakeJsonObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			field, err := parseJsonValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.Set(key.(string), field)
+		}
+		_, err = dec.Token()
+		return v, err
+	}
+	return nil, ErrJsonSyntax
+}
+
+// JsonFormat is the formatting options of JSON.GET. `Indent` is repeated for each level of nesting.
+type JsonFormat struct {
+	Indent  string
+	Newline string
+	Space   string
+}
+
+func (v *JsonValue) String() string {
+	return v.Format(&JsonFormat{})
+}
+
+func (v *JsonValue) Format(f *JsonFormat) string {
+	var sb strings.Builder
+	v.encode(&sb, f, 0)
+	return sb.String()
+}
+
+func encodeJsonString(sb *strings.Builder, s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	sb.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+func formatJsonFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "null"
+	}
+	// Same as RedisJSON, floating-point numbers always look like floating-point numbers
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (v *JsonValue) encode(sb *strings.Builder, f *JsonFormat, level int) {
+	newline := func(level int) {
+		sb.WriteString(f.Newline)
+		sb.WriteString(strings.Repeat(f.Indent, level))
+	}
+
+	switch v.Type {
+	case JsonTypeNull:
+		sb.WriteString("null")
+	case JsonTypeBoolean:
+		sb.WriteString(strconv.FormatBool(v.Bool))
+	case JsonTypeInteger:
+		sb.WriteString(strconv.FormatInt(v.Int, 10))
+	case JsonTypeNumber:
+		sb.WriteString(formatJsonFloat(v.Float))
+	case JsonTypeString:
+		encodeJsonString(sb, v.Str)
+	case JsonTypeArray:
+		if len(v.Array) == 0 {
+			sb.WriteString("[]")
+			return
+		}
+		sb.WriteString("[")
+		for i, e := range v.Array {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			newline(level + 1)
+			e.encode(sb, f, level+1)
+		}
+		newline(level)
+		sb.WriteString("]")
+	case JsonTypeObject:
+		if len(v.Keys) == 0 {
+			sb.WriteString("{}")
+			return
+		}
+		sb.WriteString("{")
+		for i, key := range v.Keys {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			newline(level + 1)
+			encodeJsonString(sb, key)
+			sb.WriteString(":")
+			sb.WriteString(f.Space)
+			v.Fields[key].encode(sb, f, level+1)
+		}
+		newline(level)
+		sb.WriteString("}")
+	}
+}
+
+const (
+	jsonSegmentKey = iota
+	jsonSegmentIndex
+	jsonSegmentWildcard
+)
+
+// jsonPathSegment selects children by a key, an index or all children. A recursive segment selects
+// them from the node and all its descendants.
+type jsonPathSegment struct {
+	Kind      int
+	Key       string
+	Index     int
+	Recursive bool
+}
+
+/*
+JsonPath is a subset of JSONPath:
+
+	$              the root
+	.field         a field of an object, also ['field'] or ["field"]
+	[n]            an element of an array, counting from the end if negative
+	.* or [*]      all fields of an object or elements of an array
+	..field        the fields of the node and all its descendants, also ..* and ..[n]
+
+Same as RedisJSON, paths not starting with "$" are legacy paths, such as "." for the root or "a.b",
+which reply a single value instead of an array of all matches.
+*/
+type JsonPath struct {
+	Raw      string
+	Legacy   bool
+	Segments []*jsonPathSegment
+}
+
+func ParseJsonPath(raw string) (*JsonPath, error) {
+	p := &JsonPath{Raw: raw, Segments: make([]*jsonPathSegment, 0)}
+
+	s := raw
+	if strings.HasPrefix(s, "$") {
+		s = s[1:]
+	} else {
+		p.Legacy = true
+		if s == "." {
+			s = ""
+		} else if !strings.HasPrefix(s, ".") && !strings.HasPrefix(s, "[") {
+			s = "." + s
+		}
+	}
+
+	for len(s) > 0 {
+		seg := &jsonPathSegment{}
+		switch {
+		case strings.HasPrefix(s, ".."):
+			seg.Recursive = true
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(s, "."):
+			s = strings.TrimPrefix(s, ".")
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, ErrJsonPath
+			}
+			if s[:end] == "*" {
+				seg.Kind = jsonSegmentWildcard
+			} else {
+				seg.Kind = jsonSegmentKey
+				seg.Key = s[:end]
+			}
+			s = s[end:]
+			p.Segments = append(p.Segments, seg)
+			continue
+		case !strings.HasPrefix(s, "["):
+			return nil, ErrJsonPath
+		}
+
+		// Brackets hold an index, a wildcard or a quoted key
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return nil, ErrJsonPath
+		}
+		inner := s[1:end]
+		if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+			seg.Kind = jsonSegmentKey
+			seg.Key = inner[1 : len(inner)-1]
+		} else if inner == "*" {
+			seg.Kind = jsonSegmentWildcard
+		} else {
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, ErrJsonPath
+			}
+			seg.Kind = jsonSegmentIndex
+			seg.Index = i
+		}
+		s = s[end+1:]
+		p.Segments = append(p.Segments, seg)
+	}
+	return p, nil
+}
+
+// IsRoot returns true if the path only selects the root
+func (p *JsonPath) IsRoot() bool {
+	return len(p.Segments) == 0
+}
+
+// Parent returns the path without the last segment
+func (p *JsonPath) Parent() *JsonPath {
+	return &JsonPath{Raw: p.Raw, Legacy: p.Legacy, Segments: p.Segments[:len(p.Segments)-1]}
+}
+
+// JsonMatch is a node selected by a path, and its parent that is nil for the root
+type JsonMatch struct {
+	Parent *JsonValue
+	Value  *JsonValue
+}
+
+func (seg *jsonPathSegment) selectChildren(m *JsonMatch, matches []*JsonMatch) []*JsonMatch {
+	v := m.Value
+	switch {
+	case seg.Kind == jsonSegmentKey && v.Type == JsonTypeObject:
+		if field, found := v.Fields[seg.Key]; found {
+			matches = append(matches, &JsonMatch{Parent: v, Value: field})
+		}
+	case seg.Kind == jsonSegmentIndex && v.Type == JsonTypeArray:
+		i := seg.Index
+		if i < 0 {
+			i += len(v.Array)
+		}
+		if i >= 0 && i < len(v.Array) {
+			matches = append(matches, &JsonMatch{Parent: v, Value: v.Array[i]})
+		}
+	case seg.Kind == jsonSegmentWildcard && v.Type == JsonTypeArray:
+		for _, e := range v.Array {
+			matches = append(matches, &JsonMatch{Parent: v, Value: e})
+		}
+	case seg.Kind == jsonSegmentWildcard && v.Type == JsonTypeObject:
+		for _, key := range v.Keys {
+			matches = append(matches, &JsonMatch{Parent: v, Value: v.Fields[key]})
+		}
+	}
+	return matches
+}
+
+// descendants returns the node and all its descendants in preorder
+func descendants(m *JsonMatch, res []*JsonMatch) []*JsonMatch {
+	res = append(res, m)
+	for _, child := range (&jsonPathSegment{Kind: jsonSegmentWildcard}).selectChildren(m, nil) {
+		res = descendants(child, res)
+	}
+	return res
+}
+
+// Eval returns the nodes selected by the path in document order
+func (p *JsonPath) Eval(root *JsonValue) []*JsonMatch {
+	matches := []*JsonMatch{{Value: root}}
+	for _, seg := range p.Segments {
+		if seg.Recursive {
+			all := make([]*JsonMatch, 0)
+			for _, m := range matches {
+				all = descendants(m, all)
+			}
+			matches = all
+		}
+
+		next := make([]*JsonMatch, 0)
+		for _, m := range matches {
+			next = seg.selectChildren(m, next)
+		}
+		matches = next
+	}
+	return matches
+}
@@ -0,0 +1,667 @@
+package cmdexec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrJsonNewAtRoot     = errors.New("ERR new objects must be created at the root")
+	ErrJsonKeyNotFound   = errors.New("ERR could not perform this operation on a key that doesn't exist")
+	ErrJsonIndexOutRange = errors.New("ERR index out of bounds")
+)
+
+func errJsonPathNotFound(path *JsonPath) error {
+	return fmt.Errorf("ERR Path '%s' does not exist", path.Raw)
+}
+
+func errJsonWrongType(expected string, found *JsonValue) error {
+	return fmt.Errorf("ERR wrong type of path value - expected %s but found %s", expected, found.TypeName())
+}
+
+type jsonCmdExecutor struct{}
+
+// parsePathArg parses the optional path at `i`, which defaults to the legacy root
+func (e jsonCmdExecutor) parsePathArg(cmdArgs []*resp.RespValue, i int) (*JsonPath, error) {
+	if i >= len(cmdArgs) {
+		return ParseJsonPath(".")
+	}
+	return ParseJsonPath(cmdArgs[i].BulkStr)
+}
+
+/*
+replyPerMatch replies the results of `fn` for each node selected by a path. `fn` returns an error if the
+operation does not apply to the node.
+
+JSONPath paths reply an array of the results, where nil stands for the nodes that the operation does not
+apply to. Legacy paths reply the first result, or an error if no node is selected or the operation applies
+to none of them.
+*/
+func (e jsonCmdExecutor) replyPerMatch(c *ClientInfo, path *JsonPath, matches []*JsonMatch, fn func(m *JsonMatch) (*resp.RespValue, error)) {
+	res := make([]*resp.RespValue, 0, len(matches))
+	var firstResult *resp.RespValue
+	var firstErr error
+
+	for _, m := range matches {
+		r, err := fn(m)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			res = append(res, resp.MakeNilBulkString())
+			continue
+		}
+		if firstResult == nil {
+			firstResult = r
+		}
+		res = append(res, r)
+	}
+
+	switch {
+	case !path.Legacy:
+		AddArrayReplyEvent(c, res)
+	case len(matches) == 0:
+		AddErrorReplyEvent(c, errJsonPathNotFound(path))
+	case firstResult == nil:
+		AddErrorReplyEvent(c, firstErr)
+	default:
+		AddReplyEvent(c, firstResult)
+	}
+}
+
+/*
+Syntax: JSON.SET key path value [NX | XX]
+Reply:
+  - Simple string reply: OK
+  - Nil reply: if NX or XX blocks the update, or the path selects nothing
+
+New keys must be created at the root. If the path selects nothing but its last segment is a key, the key is added to
+the objects selected by the rest of the path.
+*/
+func (e jsonCmdExecutor) parseJsonSetCmdArgs(cmdArgs []*resp.RespValue, key *string, path **JsonPath, value **JsonValue, nxFlag *bool, xxFlag *bool) error {
+	if len(cmdArgs) < 3 || len(cmdArgs) > 4 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	*path, err = ParseJsonPath(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	*value, err = ParseJson(cmdArgs[2].BulkStr)
+	if err != nil {
+		return err
+	}
+
+	if len(cmdArgs) == 4 {
+		switch strings.ToUpper(cmdArgs[3].BulkStr) {
+		case "NX":
+			*nxFlag = true
+		case "XX":
+			*xxFlag = true
+		default:
+			return ErrSyntax
+		}
+	}
+	return nil
+}
+
+func (e jsonCmdExecutor) executeJsonSetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key    string
+		path   *JsonPath
+		value  *JsonValue
+		nxFlag bool
+		xxFlag bool
+	)
+	err := e.parseJsonSetCmdArgs(cmdArgs, &key, &path, &value, &nxFlag, &xxFlag)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	doc, found := db.JsonStore[key]
+	if !found {
+		if !path.IsRoot() {
+			AddErrorReplyEvent(c, ErrJsonNewAtRoot)
+			return
+		}
+		if xxFlag {
+			AddNullBulkStringReplyEvent(c)
+			return
+		}
+		db.JsonStore[key] = value
+		AddSimpleStringReplyEvent(c, "OK")
+		return
+	}
+
+	matches := path.Eval(doc)
+	if len(matches) > 0 {
+		if nxFlag {
+			AddNullBulkStringReplyEvent(c)
+			return
+		}
+		// Each node gets its own copy of the value
+		for _, m := range matches {
+			*m.Value = *value.Clone()
+		}
+		AddSimpleStringReplyEvent(c, "OK")
+		return
+	}
+
+	last := path.Segments[len(path.Segments)-1]
+	if xxFlag || last.Kind != jsonSegmentKey || last.Recursive {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	added := false
+	for _, m := range path.Parent().Eval(doc) {
+		if m.Value.Type == JsonTypeObject {
+			m.Value.Set(last.Key, value.Clone())
+			added = true
+		}
+	}
+	if !added {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax: JSON.GET key [INDENT indent] [NEWLINE newline] [SPACE space] [path ...]
+Reply:
+  - Bulk string reply: the serialized value of the path, or an array of all matches for JSONPath. With multiple
+    paths, an object from each path to its value.
+  - Nil reply: if the key does not exist
+
+The path defaults to the legacy root, which is the whole document.
+*/
+func (e jsonCmdExecutor) parseJsonGetCmdArgs(cmdArgs []*resp.RespValue, key *string, format *JsonFormat, paths *[]*JsonPath) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+
+	// Formatting options come before paths
+	i := 1
+formatting:
+	for ; i+1 < len(cmdArgs); i += 2 {
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "INDENT":
+			format.Indent = cmdArgs[i+1].BulkStr
+		case "NEWLINE":
+			format.Newline = cmdArgs[i+1].BulkStr
+		case "SPACE":
+			format.Space = cmdArgs[i+1].BulkStr
+		default:
+			break formatting
+		}
+	}
+
+	for ; i < len(cmdArgs); i++ {
+		path, err := ParseJsonPath(cmdArgs[i].BulkStr)
+		if err != nil {
+			return err
+		}
+		*paths = append(*paths, path)
+	}
+	if len(*paths) == 0 {
+		root, _ := ParseJsonPath(".")
+		*paths = append(*paths, root)
+	}
+	return nil
+}
+
+func (e jsonCmdExecutor) executeJsonGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key    string
+		format JsonFormat
+		paths  []*JsonPath
+	)
+	err := e.parseJsonGetCmdArgs(cmdArgs, &key, &format, &paths)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	doc, found := db.JsonStore[key]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+
+	// All paths are treated as JSONPath if any of them is
+	legacy := true
+	for _, path := range paths {
+		legacy = legacy && path.Legacy
+	}
+
+	values := make([]*JsonValue, 0, len(paths))
+	for _, path := range paths {
+		matches := path.Eval(doc)
+		if legacy {
+			if len(matches) == 0 {
+				AddErrorReplyEvent(c, errJsonPathNotFound(path))
+				return
+			}
+			values = append(values, matches[0].Value)
+			continue
+		}
+		arr := MakeJsonArray(make([]*JsonValue, 0, len(matches)))
+		for _, m := range matches {
+			arr.Array = append(arr.Array, m.Value)
+		}
+		values = append(values, arr)
+	}
+
+	if len(paths) == 1 {
+		AddBulkStringReplyEvent(c, values[0].Format(&format))
+		return
+	}
+	obj := MakeJsonObject()
+	for i, path := range paths {
+		obj.Set(path.Raw, values[i])
+	}
+	AddBulkStringReplyEvent(c, obj.Format(&format))
+}
+
+/*
+Syntax: JSON.MGET key [key ...] path
+Reply:
+  - Array reply: the serialized value of the path for each key, or an array of all matches for JSONPath. Nil if the
+    key does not exist, or the legacy path selects nothing.
+*/
+func (e jsonCmdExecutor) executeJsonMGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := ParseJsonPath(cmdArgs[len(cmdArgs)-1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[:len(cmdArgs)-1] {
+		doc, found := db.JsonStore[arg.BulkStr]
+		if !found {
+			res = append(res, resp.MakeNilBulkString())
+			continue
+		}
+
+		matches := path.Eval(doc)
+		if path.Legacy {
+			if len(matches) == 0 {
+				res = append(res, resp.MakeNilBulkString())
+			} else {
+				res = append(res, resp.MakeBulkString(matches[0].Value.String()))
+			}
+			continue
+		}
+		arr := MakeJsonArray(make([]*JsonValue, 0, len(matches)))
+		for _, m := range matches {
+			arr.Array = append(arr.Array, m.Value)
+		}
+		res = append(res, resp.MakeBulkString(arr.String()))
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: JSON.DEL key [path]
+Reply:
+  - Integer reply: the number of deleted values
+
+The path defaults to the root, and deleting the root deletes the key.
+*/
+func (e jsonCmdExecutor) executeJsonDelCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+	path, err := e.parsePathArg(cmdArgs, 1)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	doc, found := db.JsonStore[key]
+	if !found {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	if path.IsRoot() {
+		delete(db.JsonStore, key)
+		AddIntegerReplyEvent(c, 1)
+		return
+	}
+
+	// Matches are in document order, so descendants are deleted before their ancestors
+	matches := path.Eval(doc)
+	deleted := 0
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i].Parent.Remove(matches[i].Value) {
+			deleted++
+		}
+	}
+	AddIntegerReplyEvent(c, deleted)
+}
+
+/*
+Syntax: JSON.TYPE key [path]
+Reply:
+  - Array reply: the type names of all matches for JSONPath
+  - Simple string reply: the type name for legacy paths, one of null, boolean, integer, number, string, array or object
+  - Nil reply: if the key does not exist
+*/
+func (e jsonCmdExecutor) executeJsonTypeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := e.parsePathArg(cmdArgs, 1)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+
+	e.replyPerMatch(c, path, path.Eval(doc), func(m *JsonMatch) (*resp.RespValue, error) {
+		return resp.MakeSimpleString(m.Value.TypeName()), nil
+	})
+}
+
+/*
+Syntax: JSON.NUMINCRBY key path value
+Reply:
+  - Bulk string reply: the serialized array of the new values of all matches for JSONPath, where null stands for
+    non-number values, or the new value for legacy paths
+
+Integers stay integers when incremented by integers without overflows.
+*/
+func (e jsonCmdExecutor) executeJsonNumIncrByCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := ParseJsonPath(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	incr, err := ParseJson(cmdArgs[2].BulkStr)
+	if err != nil || !incr.IsNumber() {
+		AddErrorReplyEvent(c, errors.New("ERR value must be a number"))
+		return
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrJsonKeyNotFound)
+		return
+	}
+
+	matches := path.Eval(doc)
+	res := MakeJsonArray(make([]*JsonValue, 0, len(matches)))
+	for _, m := range matches {
+		v := m.Value
+		if !v.IsNumber() {
+			if path.Legacy {
+				AddErrorReplyEvent(c, errJsonWrongType("a number", v))
+				return
+			}
+			res.Array = append(res.Array, &JsonValue{Type: JsonTypeNull})
+			continue
+		}
+
+		sum := v.Int + incr.Int
+		overflow := (incr.Int > 0 && sum < v.Int) || (incr.Int < 0 && sum > v.Int)
+		if v.Type == JsonTypeInteger && incr.Type == JsonTypeInteger && !overflow {
+			v.Int = sum
+		} else {
+			f := v.Number() + incr.Number()
+			if math.IsInf(f, 0) {
+				AddErrorReplyEvent(c, ErrOverflow)
+				return
+			}
+			*v = JsonValue{Type: JsonTypeNumber, Float: f}
+		}
+		res.Array = append(res.Array, v)
+	}
+
+	if !path.Legacy {
+		AddBulkStringReplyEvent(c, res.String())
+	} else if len(matches) == 0 {
+		AddErrorReplyEvent(c, errJsonPathNotFound(path))
+	} else {
+		AddBulkStringReplyEvent(c, res.Array[0].String())
+	}
+}
+
+/*
+Syntax: JSON.STRAPPEND key [path] value
+Reply:
+  - Array reply: the new lengths of all matches for JSONPath, or nil for non-string values
+  - Integer reply: the new length for legacy paths
+
+The value must be a JSON string, such as '"abc"'.
+*/
+func (e jsonCmdExecutor) executeJsonStrAppendCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 || len(cmdArgs) > 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := e.parsePathArg(cmdArgs[:len(cmdArgs)-1], 1)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	value, err := ParseJson(cmdArgs[len(cmdArgs)-1].BulkStr)
+	if err != nil || value.Type != JsonTypeString {
+		AddErrorReplyEvent(c, ErrJsonSyntax)
+		return
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrJsonKeyNotFound)
+		return
+	}
+
+	e.replyPerMatch(c, path, path.Eval(doc), func(m *JsonMatch) (*resp.RespValue, error) {
+		if m.Value.Type != JsonTypeString {
+			return nil, errJsonWrongType("a string", m.Value)
+		}
+		m.Value.Str += value.Str
+		return resp.MakeInt(len(m.Value.Str)), nil
+	})
+}
+
+/*
+Syntax:
+  - JSON.ARRAPPEND key path value [value ...]
+  - JSON.ARRINSERT key path index value [value ...]
+
+Reply:
+  - Array reply: the new lengths of all matches for JSONPath, or nil for non-array values
+  - Integer reply: the new length for legacy paths
+
+The values are inserted before `index`, which counts from the end if negative.
+*/
+func (e jsonCmdExecutor) executeJsonArrInsertCmd(c *ClientInfo, cmdArgs []*resp.RespValue, withIndex bool) {
+	minArgs := 3
+	if withIndex {
+		minArgs = 4
+	}
+	if len(cmdArgs) < minArgs {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := ParseJsonPath(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	var index int64
+	if withIndex {
+		index, err = parseInt(cmdArgs[2].BulkStr)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+	}
+	values := make([]*JsonValue, 0)
+	for _, arg := range cmdArgs[minArgs-1:] {
+		v, err := ParseJson(arg.BulkStr)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+		values = append(values, v)
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrJsonKeyNotFound)
+		return
+	}
+
+	matches := path.Eval(doc)
+	// Check the indexes of all arrays first, so that either all or none of them are modified
+	for _, m := range matches {
+		n := int64(len(m.Value.Array))
+		if withIndex && m.Value.Type == JsonTypeArray && (index > n || index < -n) {
+			AddErrorReplyEvent(c, ErrJsonIndexOutRange)
+			return
+		}
+	}
+
+	e.replyPerMatch(c, path, matches, func(m *JsonMatch) (*resp.RespValue, error) {
+		arr := m.Value
+		if arr.Type != JsonTypeArray {
+			return nil, errJsonWrongType("an array", arr)
+		}
+		i := len(arr.Array)
+		if withIndex {
+			i = int(index)
+			if i < 0 {
+				i += len(arr.Array)
+			}
+		}
+
+		inserted := make([]*JsonValue, 0, len(values))
+		for _, v := range values {
+			inserted = append(inserted, v.Clone())
+		}
+		arr.Array = append(arr.Array[:i], append(inserted, arr.Array[i:]...)...)
+		return resp.MakeInt(len(arr.Array)), nil
+	})
+}
+
+/*
+Syntax: JSON.ARRLEN key [path]
+Reply:
+  - Array reply: the lengths of all matches for JSONPath, or nil for non-array values
+  - Integer reply: the length for legacy paths
+  - Nil reply: if the key does not exist
+*/
+func (e jsonCmdExecutor) executeJsonArrLenCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := e.parsePathArg(cmdArgs, 1)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+
+	e.replyPerMatch(c, path, path.Eval(doc), func(m *JsonMatch) (*resp.RespValue, error) {
+		if m.Value.Type != JsonTypeArray {
+			return nil, errJsonWrongType("an array", m.Value)
+		}
+		return resp.MakeInt(len(m.Value.Array)), nil
+	})
+}
+
+/*
+Syntax: JSON.OBJKEYS key [path]
+Reply:
+  - Array reply: the keys of each match for JSONPath, or nil for non-object values
+  - Array reply: the keys for legacy paths
+  - Nil reply: if the key does not exist
+*/
+func (e jsonCmdExecutor) executeJsonObjKeysCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	path, err := e.parsePathArg(cmdArgs, 1)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	doc, found := db.JsonStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+
+	e.replyPerMatch(c, path, path.Eval(doc), func(m *JsonMatch) (*resp.RespValue, error) {
+		if m.Value.Type != JsonTypeObject {
+			return nil, errJsonWrongType("an object", m.Value)
+		}
+		keys := make([]*resp.RespValue, 0, len(m.Value.Keys))
+		for _, key := range m.Value.Keys {
+			keys = append(keys, resp.MakeBulkString(key))
+		}
+		return resp.MakeArray(keys), nil
+	})
+}
+
+func (e jsonCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "JSON.SET":
+		e.executeJsonSetCmd(c, cmdArgs)
+	case "JSON.GET":
+		e.executeJsonGetCmd(c, cmdArgs)
+	case "JSON.MGET":
+		e.executeJsonMGetCmd(c, cmdArgs)
+	case "JSON.DEL":
+		e.executeJsonDelCmd(c, cmdArgs)
+	case "JSON.TYPE":
+		e.executeJsonTypeCmd(c, cmdArgs)
+	case "JSON.NUMINCRBY":
+		e.executeJsonNumIncrByCmd(c, cmdArgs)
+	case "JSON.STRAPPEND":
+		e.executeJsonStrAppendCmd(c, cmdArgs)
+	case "JSON.ARRAPPEND":
+		e.executeJsonArrInsertCmd(c, cmdArgs, false)
+	case "JSON.ARRINSERT":
+		e.executeJsonArrInsertCmd(c, cmdArgs, true)
+	case "JSON.ARRLEN":
+		e.executeJsonArrLenCmd(c, cmdArgs)
+	case "JSON.OBJKEYS":
+		e.executeJsonObjKeysCmd(c, cmdArgs)
+	}
+
+	// Search indexes watch JSON documents, so they are updated after every write
+	switch cmdName {
+	case "JSON.SET", "JSON.DEL", "JSON.NUMINCRBY", "JSON.STRAPPEND", "JSON.ARRAPPEND", "JSON.ARRINSERT":
+		if len(cmdArgs) > 0 {
+			updateSearchIndexes(cmdArgs[0].BulkStr)
+		}
+	}
+}
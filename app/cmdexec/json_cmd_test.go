@@ -0,0 +1,87 @@
+package cmdexec
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := jsonCmdExecutor{}
+
+	bulk := func(s string) string {
+		return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+	}
+
+	doc := `{"name":"Leonard","age":30,"score":1.5,"tags":["a","b"],"address":{"city":"Paris","zip":"75001"},"pets":[{"name":"Rex","age":3}]}`
+	assert.Equal(t, "-ERR new objects must be created at the root\r\n", runCmd(e, "JSON.SET", "user", "$.name", `"x"`))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "JSON.SET", "user", "$", doc))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.SET", "user", "$", "{}", "NX"))
+	assert.Equal(t, bulk(doc), runCmd(e, "JSON.GET", "user"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.GET", "missing"))
+
+	// JSONPath replies all matches, and legacy paths reply the first match
+	assert.Equal(t, bulk(`["Leonard","Rex"]`), runCmd(e, "JSON.GET", "user", "$..name"))
+	assert.Equal(t, bulk(`"Leonard"`), runCmd(e, "JSON.GET", "user", ".name"))
+	assert.Equal(t, bulk(`{"$.age":[30],"$.tags[0]":["a"]}`), runCmd(e, "JSON.GET", "user", "$.age", "$.tags[0]"))
+	assert.Equal(t, bulk(`{"age":30,"address.city":"Paris"}`), runCmd(e, "JSON.GET", "user", "age", "address.city"))
+	assert.Equal(t, bulk("{\n  \"city\": \"Paris\",\n  \"zip\": \"75001\"\n}"), runCmd(e, "JSON.GET", "user", "INDENT", "  ", "NEWLINE", "\n", "SPACE", " ", ".address"))
+	assert.Equal(t, "-ERR Path '.missing' does not exist\r\n", runCmd(e, "JSON.GET", "user", ".missing"))
+	assert.Equal(t, bulk("[]"), runCmd(e, "JSON.GET", "user", "$.missing"))
+
+	// New fields are added to objects, and existing values are replaced
+	assert.Equal(t, "+OK\r\n", runCmd(e, "JSON.SET", "user", "$.address.country", `"FR"`))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "JSON.SET", "user", "$.pets[*].age", "4"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.SET", "user", "$.nickname", `"Leo"`, "XX"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.SET", "user", "$.missing.field", "1"))
+	assert.Equal(t, bulk(`{"city":"Paris","zip":"75001","country":"FR"}`), runCmd(e, "JSON.GET", "user", ".address"))
+	assert.Equal(t, bulk("[4]"), runCmd(e, "JSON.GET", "user", "$.pets[0].age"))
+
+	assert.Equal(t, "*6\r\n+string\r\n+integer\r\n+number\r\n+array\r\n+object\r\n+array\r\n", runCmd(e, "JSON.TYPE", "user", "$.*"))
+	assert.Equal(t, "+object\r\n", runCmd(e, "JSON.TYPE", "user"))
+	assert.Equal(t, "*2\r\n+integer\r\n+integer\r\n", runCmd(e, "JSON.TYPE", "user", "$..age"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.TYPE", "missing"))
+
+	assert.Equal(t, bulk("[32,6]"), runCmd(e, "JSON.NUMINCRBY", "user", "$..age", "2"))
+	assert.Equal(t, bulk("3.0"), runCmd(e, "JSON.NUMINCRBY", "user", ".score", "1.5"))
+	assert.Equal(t, bulk("[null]"), runCmd(e, "JSON.NUMINCRBY", "user", "$.name", "1"))
+	assert.Equal(t, "-ERR wrong type of path value - expected a number but found string\r\n", runCmd(e, "JSON.NUMINCRBY", "user", ".name", "1"))
+	assert.Equal(t, "-ERR could not perform this operation on a key that doesn't exist\r\n", runCmd(e, "JSON.NUMINCRBY", "missing", "$", "1"))
+
+	assert.Equal(t, "*2\r\n:9\r\n:5\r\n", runCmd(e, "JSON.STRAPPEND", "user", "$..name", `" S"`))
+	assert.Equal(t, ":6\r\n", runCmd(e, "JSON.STRAPPEND", "user", ".address.city", `"!"`))
+	assert.Equal(t, "*3\r\n:7\r\n:6\r\n:3\r\n", runCmd(e, "JSON.STRAPPEND", "user", "$.address.*", `"!"`))
+	assert.Equal(t, "*1\r\n$-1\r\n", runCmd(e, "JSON.STRAPPEND", "user", "$.age", `"!"`))
+	assert.Equal(t, "-ERR invalid JSON value\r\n", runCmd(e, "JSON.STRAPPEND", "user", "$.name", "1"))
+
+	assert.Equal(t, "*1\r\n:4\r\n", runCmd(e, "JSON.ARRAPPEND", "user", "$.tags", `"c"`, `"d"`))
+	assert.Equal(t, ":6\r\n", runCmd(e, "JSON.ARRINSERT", "user", ".tags", "-1", `"x"`, `{"y":1}`))
+	assert.Equal(t, bulk(`["a","b","c","x",{"y":1},"d"]`), runCmd(e, "JSON.GET", "user", ".tags"))
+	assert.Equal(t, "-ERR index out of bounds\r\n", runCmd(e, "JSON.ARRINSERT", "user", "$.tags", "7", "1"))
+	assert.Equal(t, "*6\r\n$-1\r\n$-1\r\n$-1\r\n:6\r\n$-1\r\n:1\r\n", runCmd(e, "JSON.ARRLEN", "user", "$.*"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "JSON.ARRLEN", "user", ".pets"))
+	assert.Equal(t, "-ERR wrong type of path value - expected an array but found object\r\n", runCmd(e, "JSON.ARRLEN", "user"))
+
+	assert.Equal(t, "*3\r\n$4\r\ncity\r\n$3\r\nzip\r\n$7\r\ncountry\r\n", runCmd(e, "JSON.OBJKEYS", "user", ".address"))
+	assert.Equal(t, "*1\r\n*2\r\n$4\r\nname\r\n$3\r\nage\r\n", runCmd(e, "JSON.OBJKEYS", "user", "$.pets[*]"))
+	assert.Equal(t, "*1\r\n$-1\r\n", runCmd(e, "JSON.OBJKEYS", "user", "$.tags"))
+
+	runCmd(e, "JSON.SET", "other", ".", `{"name":"Ann","pets":[]}`)
+	assert.Equal(t, "*3\r\n"+bulk(`"Leonard S"`)+bulk(`"Ann"`)+"$-1\r\n", runCmd(e, "JSON.MGET", "user", "other", "missing", ".name"))
+	assert.Equal(t, "*2\r\n"+bulk(`["Rex S"]`)+bulk("[]"), runCmd(e, "JSON.MGET", "user", "other", "$.pets[*].name"))
+
+	assert.Equal(t, ":2\r\n", runCmd(e, "JSON.DEL", "user", "$..age"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "JSON.DEL", "user", "$.tags[0]"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "JSON.DEL", "user", "$.missing"))
+	assert.Equal(t, bulk(`{"name":"Leonard S","score":3.0,"tags":["b","c","x",{"y":1},"d"],"address":{"city":"Paris!!","zip":"75001!","country":"FR!"},"pets":[{"name":"Rex S"}]}`),
+		runCmd(e, "JSON.GET", "user"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "JSON.DEL", "user"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "JSON.GET", "user"))
+
+	assert.Equal(t, "-ERR invalid JSON value\r\n", runCmd(e, "JSON.SET", "user", "$", "{"))
+	assert.Equal(t, "-ERR invalid JSON path\r\n", runCmd(e, "JSON.GET", "other", "$["))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "JSON.SET", "other", "$", "1", "YY"))
+}
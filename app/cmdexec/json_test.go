@@ -0,0 +1,75 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonParseFormat(t *testing.T) {
+	v, err := ParseJson(` {"b": [1, 2.5, -3e2, true, null], "a": {"x": "<\"é\">"}, "c": {}, "d": [] } `)
+	assert.NoError(t, err)
+	// Fields keep their insertion order, and floating-point numbers stay floating-point numbers
+	assert.Equal(t, `{"b":[1,2.5,-300.0,true,null],"a":{"x":"<\"é\">"},"c":{},"d":[]}`, v.String())
+	assert.Equal(t, "{\n\t\"b\": [\n\t\t1,\n\t\t2.5,\n\t\t-300.0,\n\t\ttrue,\n\t\tnull\n\t],\n\t\"a\": {\n\t\t\"x\": \"<\\\"é\\\">\"\n\t},\n\t\"c\": {},\n\t\"d\": []\n}",
+		v.Format(&JsonFormat{Indent: "\t", Newline: "\n", Space: " "}))
+
+	assert.Equal(t, JsonTypeInteger, v.Fields["b"].Array[0].Type)
+	assert.Equal(t, JsonTypeNumber, v.Fields["b"].Array[1].Type)
+
+	// Integers that overflow are floating-point numbers
+	v, _ = ParseJson("92233720368547758070")
+	assert.Equal(t, "number", v.TypeName())
+
+	for _, s := range []string{"", "{", `{"a":1,}`, "[1] 2", "nan", "'a'"} {
+		_, err = ParseJson(s)
+		assert.Equal(t, ErrJsonSyntax, err, s)
+	}
+
+	c := v.Clone()
+	c.Float = 1
+	assert.NotEqual(t, v.Float, c.Float)
+}
+
+func TestJsonPath(t *testing.T) {
+	doc, _ := ParseJson(`{"store":{"book":[{"title":"A","price":8},{"title":"B","price":12,"tags":["x"]}],"bike":{"price":20}},"price":1}`)
+
+	eval := func(path string) string {
+		p, err := ParseJsonPath(path)
+		assert.NoError(t, err, path)
+		arr := MakeJsonArray(nil)
+		for _, m := range p.Eval(doc) {
+			arr.Array = append(arr.Array, m.Value)
+		}
+		return arr.String()
+	}
+
+	assert.Equal(t, "[1]", eval("$.price"))
+	assert.Equal(t, `["A","B"]`, eval("$.store.book[*].title"))
+	assert.Equal(t, `["B"]`, eval("$.store.book[-1].title"))
+	assert.Equal(t, `["A"]`, eval("$['store'][\"book\"][0].title"))
+	assert.Equal(t, "[1,8,12,20]", eval("$..price"))
+	assert.Equal(t, `[{"title":"A","price":8},{"title":"B","price":12,"tags":["x"]}]`, eval("$..book.*"))
+	assert.Equal(t, `[{"title":"A","price":8},"x"]`, eval("$..[0]"))
+	assert.Equal(t, "[]", eval("$.store.book[2]"))
+	assert.Equal(t, "[]", eval("$.price.missing"))
+	assert.Len(t, mustParseJsonPath(t, "$").Eval(doc), 1)
+
+	// Legacy paths
+	p := mustParseJsonPath(t, ".")
+	assert.True(t, p.Legacy)
+	assert.True(t, p.IsRoot())
+	assert.Equal(t, "[20]", eval("store.bike.price"))
+	assert.Equal(t, "[8]", eval(".store.book[0].price"))
+
+	for _, path := range []string{"$.", "$..", "$[", "$[a]", "$.a[0", "$a"} {
+		_, err := ParseJsonPath(path)
+		assert.Equal(t, ErrJsonPath, err, path)
+	}
+}
+
+func mustParseJsonPath(t *testing.T, path string) *JsonPath {
+	p, err := ParseJsonPath(path)
+	assert.NoError(t, err)
+	return p
+}
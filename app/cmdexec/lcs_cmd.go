@@ -0,0 +1,119 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// LcsIdxMaxTableBytes caps the table of IDX, which takes 4 bytes per cell
+const LcsIdxMaxTableBytes = 64 * 1024 * 1024
+
+var (
+	ErrLcsLenAndIdx = errors.New("ERR If you want both the length and indexes, please just use IDX.")
+	ErrLcsMemory    = errors.New("ERR Insufficient memory, transient memory for LCS IDX exceeds 64MB")
+)
+
+/*
+Syntax: LCS key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]
+Reply:
+  - Bulk string reply: the longest common subsequence
+  - Integer reply: the length of the longest common subsequence with LEN
+  - Array reply: "matches", the ranges of the matches from the last to the first, "len" and the length with IDX.
+    Each match is [[start1, end1], [start2, end2]], followed by the length of the match with WITHMATCHLEN.
+
+Missing keys are empty strings. Only IDX builds the whole table of the dynamic programming, which must fit in
+LcsIdxMaxTableBytes, while the other replies keep a few rows of it.
+*/
+func (e setCmdExecutor) parseLcsCmdArgs(cmdArgs []*resp.RespValue, key1 *string, key2 *string, lenFlag *bool, idxFlag *bool, minMatchLen *int64, withMatchLen *bool) error {
+	if len(cmdArgs) < 2 {
+		return ErrInvalidArgs
+	}
+	*key1, *key2 = cmdArgs[0].BulkStr, cmdArgs[1].BulkStr
+
+	for i := 2; i < len(cmdArgs); i++ {
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "LEN":
+			*lenFlag = true
+		case "IDX":
+			*idxFlag = true
+		case "WITHMATCHLEN":
+			*withMatchLen = true
+		case "MINMATCHLEN":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			var err error
+			*minMatchLen, err = parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil {
+				return err
+			}
+			*minMatchLen = max(*minMatchLen, 0)
+			i++
+		default:
+			return ErrSyntax
+		}
+	}
+	if *lenFlag && *idxFlag {
+		return ErrLcsLenAndIdx
+	}
+	return nil
+}
+
+func (e setCmdExecutor) executeLcsCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key1         string
+		key2         string
+		lenFlag      bool
+		idxFlag      bool
+		minMatchLen  int64
+		withMatchLen bool
+	)
+	err := e.parseLcsCmdArgs(cmdArgs, &key1, &key2, &lenFlag, &idxFlag, &minMatchLen, &withMatchLen)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	var a, b string
+	if val, found := e.lookUp(key1); found {
+		a = val.String()
+	}
+	if val, found := e.lookUp(key2); found {
+		b = val.String()
+	}
+
+	if lenFlag {
+		AddIntegerReplyEvent(c, algo.LcsLength(a, b))
+		return
+	}
+	if !idxFlag {
+		AddBulkStringReplyEvent(c, algo.Lcs(a, b))
+		return
+	}
+	if (uint64(len(a))+1)*(uint64(len(b))+1)*4 > LcsIdxMaxTableBytes {
+		AddErrorReplyEvent(c, ErrLcsMemory)
+		return
+	}
+	lcsLen, matches := algo.LcsIdx(a, b, int(minMatchLen))
+
+	res := make([]*resp.RespValue, 0, len(matches))
+	for _, m := range matches {
+		match := []*resp.RespValue{
+			resp.MakeArray([]*resp.RespValue{resp.MakeInt(m.AStart), resp.MakeInt(m.AEnd)}),
+			resp.MakeArray([]*resp.RespValue{resp.MakeInt(m.BStart), resp.MakeInt(m.BEnd)}),
+		}
+		if withMatchLen {
+			match = append(match, resp.MakeInt(m.Len()))
+		}
+		res = append(res, resp.MakeArray(match))
+	}
+	AddArrayReplyEvent(c, []*resp.RespValue{
+		resp.MakeBulkString("matches"),
+		resp.MakeArray(res),
+		resp.MakeBulkString("len"),
+		resp.MakeInt(lcsLen),
+	})
+}
@@ -0,0 +1,36 @@
+package cmdexec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLcsCmd(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := setCmdExecutor{}
+
+	runCmd(e, "MSET", "key1", "ohmytext", "key2", "mynewtext")
+	assert.Equal(t, "$6\r\nmytext\r\n", runCmd(e, "LCS", "key1", "key2"))
+	assert.Equal(t, ":6\r\n", runCmd(e, "LCS", "key1", "key2", "LEN"))
+	assert.Equal(t, "*4\r\n$7\r\nmatches\r\n*2\r\n*2\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n*2\r\n*2\r\n:2\r\n:3\r\n*2\r\n:0\r\n:1\r\n$3\r\nlen\r\n:6\r\n",
+		runCmd(e, "LCS", "key1", "key2", "IDX"))
+	assert.Equal(t, "*4\r\n$7\r\nmatches\r\n*1\r\n*3\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n:4\r\n$3\r\nlen\r\n:6\r\n",
+		runCmd(e, "LCS", "key1", "key2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN"))
+
+	// Missing keys are empty strings
+	assert.Equal(t, "$0\r\n\r\n", runCmd(e, "LCS", "key1", "missing"))
+	assert.Equal(t, "*4\r\n$7\r\nmatches\r\n*0\r\n$3\r\nlen\r\n:0\r\n", runCmd(e, "LCS", "missing", "key2", "IDX"))
+
+	assert.Equal(t, "-ERR If you want both the length and indexes, please just use IDX.\r\n", runCmd(e, "LCS", "key1", "key2", "LEN", "IDX"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", runCmd(e, "LCS", "key1", "key2", "MINMATCHLEN", "x"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "LCS", "key1", "key2", "FOO"))
+
+	// The table of large strings is too large for IDX, but their subsequence and length can still be computed
+	runCmd(e, "MSET", "big1", strings.Repeat("ab", 3000), "big2", strings.Repeat("ba", 3000))
+	assert.Equal(t, "-ERR Insufficient memory, transient memory for LCS IDX exceeds 64MB\r\n", runCmd(e, "LCS", "big1", "big2", "IDX"))
+	assert.Equal(t, ":5999\r\n", runCmd(e, "LCS", "big1", "big2", "LEN"))
+	assert.Equal(t, "$5999\r\n"+strings.Repeat("ba", 2999)+"b\r\n", runCmd(e, "LCS", "big1", "big2"))
+}
@@ -0,0 +1,550 @@
+package cmdexec
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+)
+
+const (
+	SearchFieldText = iota
+	SearchFieldTag
+	SearchFieldNumeric
+)
+
+const (
+	SearchOnHash = iota
+	SearchOnJson
+)
+
+var ErrSearchSyntax = errors.New("ERR Syntax error in query")
+
+type SearchField struct {
+	Name      string
+	Path      *JsonPath
+	Type      int
+	Separator string
+}
+
+type docSet map[string]struct{}
+
+// indexedDoc records the tags and terms of a document, so that it can be removed from the inverted indexes
+type indexedDoc struct {
+	Tags  map[string][]string
+	Terms map[string][]string
+}
+
+/*
+SearchIndex indexes the fields of hashes or JSON documents whose keys start with one of `Prefixes`:
+  - NUMERIC fields are indexed in skip lists by the first number, so that ranges are found in logarithmic time
+  - TAG fields are indexed in inverted sets from tags to keys. Tags are split by `Separator` and case-insensitive.
+  - TEXT fields are tokenized into lowercase terms, which are indexed in inverted sets from terms to keys
+
+Hashes are indexed as JSON objects of strings, and their NUMERIC fields are parsed into numbers.
+*/
+type SearchIndex struct {
+	Name     string
+	On       int
+	Prefixes []string
+	Fields   []*SearchField
+
+	Docs    map[string]*indexedDoc
+	Numeric map[string]*algo.SkipList
+	Tags    map[string]map[string]docSet
+	Terms   map[string]map[string]docSet
+}
+
+func MakeSearchIndex(name string, on int, prefixes []string, fields []*SearchField) *SearchIndex {
+	idx := &SearchIndex{
+		Name:     name,
+		On:       on,
+		Prefixes: prefixes,
+		Fields:   fields,
+		Docs:     make(map[string]*indexedDoc),
+		Numeric:  make(map[string]*algo.SkipList),
+		Tags:     make(map[string]map[string]docSet),
+		Terms:    make(map[string]map[string]docSet),
+	}
+	for _, f := range fields {
+		switch f.Type {
+		case SearchFieldNumeric:
+			idx.Numeric[f.Name] = algo.MakeSkipList(0)
+		case SearchFieldTag:
+			idx.Tags[f.Name] = make(map[string]docSet)
+		case SearchFieldText:
+			idx.Terms[f.Name] = make(map[string]docSet)
+		}
+	}
+	return idx
+}
+
+func (idx *SearchIndex) Field(name string) *SearchField {
+	for _, f := range idx.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Covers returns true if the index watches the key
+func (idx *SearchIndex) Covers(key string) bool {
+	for _, prefix := range idx.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashFieldPath selects a field of a hash converted to a JSON object. Field names are used as they are, since
+// they may contain any characters.
+func hashFieldPath(name string) *JsonPath {
+	return &JsonPath{Raw: name, Segments: []*jsonPathSegment{{Kind: jsonSegmentKey, Key: name}}}
+}
+
+// Document returns the document at `key` as JSON. Hashes are converted to objects of strings ordered by field names.
+func (idx *SearchIndex) Document(key string) (*JsonValue, bool) {
+	if idx.On == SearchOnJson {
+		doc, found := db.JsonStore[key]
+		return doc, found
+	}
+
+	hash, found := db.HashStore[key]
+	if !found {
+		return nil, false
+	}
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	doc := MakeJsonObject()
+	for _, field := range fields {
+		doc.Set(field, &JsonValue{Type: JsonTypeString, Str: hash[field]})
+	}
+	return doc, true
+}
+
+// numericValue returns the number of a NUMERIC field. Hashes only have strings, so their strings are parsed.
+func (idx *SearchIndex) numericValue(v *JsonValue) (float64, bool) {
+	if v.IsNumber() {
+		return v.Number(), true
+	}
+	if idx.On == SearchOnHash && v.Type == JsonTypeString {
+		f, err := parseFloat(v.Str)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+/*
+numericScore maps a float to an int of the same order, so that numbers can be indexed in skip lists.
+Positive floats are ordered by their bits, and negative floats in the reverse order of their bits.
+*/
+func numericScore(f float64) int {
+	if f == 0 {
+		// -0 and 0 are the same number
+		f = 0
+	}
+	bits := math.Float64bits(f)
+	if bits>>63 == 1 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	return int(bits ^ 1<<63)
+}
+
+// Tokenize splits text into lowercase terms of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func splitTags(s string, separator string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(s, separator) {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func addToSet(sets map[string]docSet, value string, key string) {
+	if sets[value] == nil {
+		sets[value] = make(docSet)
+	}
+	sets[value][key] = struct{}{}
+}
+
+func removeFromSet(sets map[string]docSet, value string, key string) {
+	delete(sets[value], key)
+	if len(sets[value]) == 0 {
+		delete(sets, value)
+	}
+}
+
+// Add indexes a document, which must not be indexed already
+func (idx *SearchIndex) Add(key string, doc *JsonValue) {
+	indexed := &indexedDoc{Tags: make(map[string][]string), Terms: make(map[string][]string)}
+
+	for _, f := range idx.Fields {
+		for _, m := range f.Path.Eval(doc) {
+			v := m.Value
+			switch f.Type {
+			case SearchFieldNumeric:
+				num, ok := idx.numericValue(v)
+				if _, found := idx.Numeric[f.Name].MemberMap[key]; !found && ok {
+					idx.Numeric[f.Name].Add(key, numericScore(num), true)
+				}
+			case SearchFieldTag:
+				// Arrays of strings are tags too
+				values := []*JsonValue{v}
+				if v.Type == JsonTypeArray {
+					values = v.Array
+				}
+				for _, e := range values {
+					if e.Type == JsonTypeString {
+						indexed.Tags[f.Name] = append(indexed.Tags[f.Name], splitTags(e.Str, f.Separator)...)
+					}
+				}
+			case SearchFieldText:
+				if v.Type == JsonTypeString {
+					indexed.Terms[f.Name] = append(indexed.Terms[f.Name], Tokenize(v.Str)...)
+				}
+			}
+		}
+	}
+
+	for field, tags := range indexed.Tags {
+		for _, tag := range tags {
+			addToSet(idx.Tags[field], tag, key)
+		}
+	}
+	for field, terms := range indexed.Terms {
+		for _, term := range terms {
+			addToSet(idx.Terms[field], term, key)
+		}
+	}
+	idx.Docs[key] = indexed
+}
+
+func (idx *SearchIndex) Remove(key string) {
+	indexed, found := idx.Docs[key]
+	if !found {
+		return
+	}
+	for _, l := range idx.Numeric {
+		l.Remove(key)
+	}
+	for field, tags := range indexed.Tags {
+		for _, tag := range tags {
+			removeFromSet(idx.Tags[field], tag, key)
+		}
+	}
+	for field, terms := range indexed.Terms {
+		for _, term := range terms {
+			removeFromSet(idx.Terms[field], term, key)
+		}
+	}
+	delete(idx.Docs, key)
+}
+
+// updateSearchIndexes indexes the latest version of a hash or a JSON document in all indexes watching it
+func updateSearchIndexes(key string) {
+	for _, idx := range db.SearchIndexes {
+		if !idx.Covers(key) {
+			continue
+		}
+		idx.Remove(key)
+		if doc, found := idx.Document(key); found {
+			idx.Add(key, doc)
+		}
+	}
+}
+
+func union(a docSet, b docSet) docSet {
+	res := make(docSet, len(a)+len(b))
+	for key := range a {
+		res[key] = struct{}{}
+	}
+	for key := range b {
+		res[key] = struct{}{}
+	}
+	return res
+}
+
+func intersect(a docSet, b docSet) docSet {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	res := make(docSet)
+	for key := range a {
+		if _, found := b[key]; found {
+			res[key] = struct{}{}
+		}
+	}
+	return res
+}
+
+/*
+searchQueryParser evaluates a query while parsing it. Intersections bind tighter than unions:
+
+	query     := intersect ('|' intersect)*
+	intersect := unary unary*
+	unary     := '-' unary | atom
+	atom      := '(' query ')' | '*' | term | '@' field ':' (numeric | tags | term | '(' query ')')
+	numeric   := '[' ['('] min ['('] max ']'     where min and max can be -inf and +inf
+	tags      := '{' tag ('|' tag)* '}'
+
+Terms match TEXT fields, which are all TEXT fields unless they are inside a field.
+*/
+type searchQueryParser struct {
+	idx *SearchIndex
+	s   string
+	pos int
+}
+
+func (p *searchQueryParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *searchQueryParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *searchQueryParser) all() docSet {
+	res := make(docSet, len(p.idx.Docs))
+	for key := range p.idx.Docs {
+		res[key] = struct{}{}
+	}
+	return res
+}
+
+func (p *searchQueryParser) parseQuery(field *SearchField) (docSet, error) {
+	res, err := p.parseIntersect(field)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '|' {
+		p.pos++
+		other, err := p.parseIntersect(field)
+		if err != nil {
+			return nil, err
+		}
+		res = union(res, other)
+	}
+	return res, nil
+}
+
+func (p *searchQueryParser) parseIntersect(field *SearchField) (docSet, error) {
+	var res docSet
+	for {
+		if c := p.peek(); c == 0 || c == ')' || c == '|' {
+			break
+		}
+		s, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = s
+		} else {
+			res = intersect(res, s)
+		}
+	}
+	if res == nil {
+		return nil, ErrSearchSyntax
+	}
+	return res, nil
+}
+
+func (p *searchQueryParser) parseUnary(field *SearchField) (docSet, error) {
+	if p.peek() != '-' {
+		return p.parseAtom(field)
+	}
+	p.pos++
+	s, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	res := make(docSet)
+	for key := range p.idx.Docs {
+		if _, found := s[key]; !found {
+			res[key] = struct{}{}
+		}
+	}
+	return res, nil
+}
+
+// readUntil returns the text until `end`, and moves past `end`
+func (p *searchQueryParser) readUntil(end byte) (string, error) {
+	i := strings.IndexByte(p.s[p.pos:], end)
+	if i < 0 {
+		return "", ErrSearchSyntax
+	}
+	text := p.s[p.pos : p.pos+i]
+	p.pos += i + 1
+	return text, nil
+}
+
+func (p *searchQueryParser) parseAtom(field *SearchField) (docSet, error) {
+	switch p.peek() {
+	case '(':
+		p.pos++
+		res, err := p.parseQuery(field)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, ErrSearchSyntax
+		}
+		p.pos++
+		return res, nil
+	case '@':
+		p.pos++
+		name, err := p.readUntil(':')
+		if err != nil {
+			return nil, err
+		}
+		f := p.idx.Field(name)
+		if f == nil {
+			return nil, fmt.Errorf("ERR Unknown field '%s'", name)
+		}
+		return p.parseFieldQuery(f)
+	}
+
+	// A term ends at a space or an operator
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" ()|", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	term := p.s[start:p.pos]
+	if term == "*" && field == nil {
+		return p.all(), nil
+	}
+	return p.matchTerms(field, Tokenize(term)), nil
+}
+
+func (p *searchQueryParser) parseFieldQuery(f *SearchField) (docSet, error) {
+	switch f.Type {
+	case SearchFieldNumeric:
+		if p.peek() != '[' {
+			return nil, ErrSearchSyntax
+		}
+		p.pos++
+		text, err := p.readUntil(']')
+		if err != nil {
+			return nil, err
+		}
+		bounds := strings.Fields(text)
+		if len(bounds) != 2 {
+			return nil, ErrSearchSyntax
+		}
+		min, err := parseNumericBound(bounds[0], false)
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseNumericBound(bounds[1], true)
+		if err != nil {
+			return nil, err
+		}
+		res := make(docSet)
+		for _, node := range p.idx.Numeric[f.Name].FindByRange(min, max) {
+			res[node.Member] = struct{}{}
+		}
+		return res, nil
+	case SearchFieldTag:
+		if p.peek() != '{' {
+			return nil, ErrSearchSyntax
+		}
+		p.pos++
+		text, err := p.readUntil('}')
+		if err != nil {
+			return nil, err
+		}
+		res := make(docSet)
+		for _, tag := range splitTags(text, "|") {
+			res = union(res, p.idx.Tags[f.Name][tag])
+		}
+		return res, nil
+	default:
+		return p.parseAtom(f)
+	}
+}
+
+// parseNumericBound parses a bound of a numeric range into a skip list score, where "(" makes it exclusive
+func parseNumericBound(s string, isMax bool) (int, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+
+	switch strings.ToLower(s) {
+	case "-inf":
+		return math.MinInt, nil
+	case "+inf", "inf":
+		return math.MaxInt, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, ErrSearchSyntax
+	}
+
+	score := numericScore(f)
+	if exclusive && isMax {
+		score--
+	} else if exclusive {
+		score++
+	}
+	return score, nil
+}
+
+// matchTerms returns the documents with all the terms in the field, or in any TEXT field if `field` is nil
+func (p *searchQueryParser) matchTerms(field *SearchField, terms []string) docSet {
+	if len(terms) == 0 {
+		return make(docSet)
+	}
+	var res docSet
+	for _, term := range terms {
+		matched := make(docSet)
+		for _, f := range p.idx.Fields {
+			if f.Type == SearchFieldText && (field == nil || f == field) {
+				matched = union(matched, p.idx.Terms[f.Name][term])
+			}
+		}
+		if res == nil {
+			res = matched
+		} else {
+			res = intersect(res, matched)
+		}
+	}
+	return res
+}
+
+// Search returns the keys of the documents matching the query
+func (idx *SearchIndex) Search(query string) (docSet, error) {
+	p := &searchQueryParser{idx: idx, s: query}
+	res, err := p.parseQuery(nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != 0 {
+		return nil, ErrSearchSyntax
+	}
+	return res, nil
+}
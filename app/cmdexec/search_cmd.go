@@ -0,0 +1,378 @@
+package cmdexec
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+const SearchDefaultLimit = 10
+
+var (
+	ErrSearchIndexExists   = errors.New("ERR Index already exists")
+	ErrSearchIndexNotFound = errors.New("ERR Unknown index name")
+	ErrSearchNoSchema      = errors.New("ERR Fields arguments are missing")
+)
+
+type searchCmdExecutor struct{}
+
+type searchOptions struct {
+	NoContent bool
+	Return    []string
+	SortBy    *SearchField
+	SortDesc  bool
+	Offset    int
+	Limit     int
+}
+
+/*
+Syntax: FT.CREATE index [ON HASH | JSON] [PREFIX count prefix [prefix ...]] SCHEMA identifier [AS alias] type [identifier [AS alias] type ...]
+Reply:
+  - Simple string reply: OK
+
+Same as Redis, hashes are indexed by default. Identifiers are field names of hashes or JSON paths of JSON documents,
+and fields are named by their aliases, or by the identifiers without aliases. Types are NUMERIC, TAG [SEPARATOR
+separator] or TEXT, and SORTABLE is accepted after any type. The index watches keys starting with any of the
+prefixes, or all keys without prefixes, and indexes the existing ones right away.
+*/
+func (e searchCmdExecutor) parseFtCreateCmdArgs(cmdArgs []*resp.RespValue, name *string, on *int, prefixes *[]string, fields *[]*SearchField) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	*name = cmdArgs[0].BulkStr
+
+	i := 1
+	for ; i < len(cmdArgs) && strings.ToUpper(cmdArgs[i].BulkStr) != "SCHEMA"; i++ {
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "ON":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			switch strings.ToUpper(cmdArgs[i+1].BulkStr) {
+			case "HASH":
+				*on = SearchOnHash
+			case "JSON":
+				*on = SearchOnJson
+			default:
+				return ErrSyntax
+			}
+			i++
+		case "PREFIX":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			n, err := parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil || n < 1 || n > int64(len(cmdArgs)-i-2) {
+				return ErrSyntax
+			}
+			for _, arg := range cmdArgs[i+2 : i+2+int(n)] {
+				*prefixes = append(*prefixes, arg.BulkStr)
+			}
+			i += 1 + int(n)
+		default:
+			return ErrSyntax
+		}
+	}
+	if i >= len(cmdArgs)-1 {
+		return ErrSearchNoSchema
+	}
+	if len(*prefixes) == 0 {
+		*prefixes = append(*prefixes, "")
+	}
+
+	args := cmdArgs[i+1:]
+	for j := 0; j < len(args); j++ {
+		path := hashFieldPath(args[j].BulkStr)
+		if *on == SearchOnJson {
+			var err error
+			path, err = ParseJsonPath(args[j].BulkStr)
+			if err != nil {
+				return err
+			}
+		}
+		f := &SearchField{Name: args[j].BulkStr, Path: path}
+
+		if j+2 < len(args) && strings.ToUpper(args[j+1].BulkStr) == "AS" {
+			f.Name = args[j+2].BulkStr
+			j += 2
+		}
+		if j+1 >= len(args) {
+			return ErrSyntax
+		}
+		j++
+		switch strings.ToUpper(args[j].BulkStr) {
+		case "NUMERIC":
+			f.Type = SearchFieldNumeric
+		case "TAG":
+			f.Type = SearchFieldTag
+			f.Separator = ","
+			if j+2 < len(args) && strings.ToUpper(args[j+1].BulkStr) == "SEPARATOR" {
+				f.Separator = args[j+2].BulkStr
+				j += 2
+			}
+		case "TEXT":
+			f.Type = SearchFieldText
+		default:
+			return fmt.Errorf("ERR Invalid field type for field `%s`", f.Name)
+		}
+		if j+1 < len(args) && strings.ToUpper(args[j+1].BulkStr) == "SORTABLE" {
+			j++
+		}
+
+		for _, other := range *fields {
+			if other.Name == f.Name {
+				return fmt.Errorf("ERR Duplicate field in schema - %s", f.Name)
+			}
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+func (e searchCmdExecutor) executeFtCreateCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		name     string
+		on       int = SearchOnHash
+		prefixes []string
+		fields   []*SearchField
+	)
+	err := e.parseFtCreateCmdArgs(cmdArgs, &name, &on, &prefixes, &fields)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if _, found := db.SearchIndexes[name]; found {
+		AddErrorReplyEvent(c, ErrSearchIndexExists)
+		return
+	}
+
+	idx := MakeSearchIndex(name, on, prefixes, fields)
+	keys := make([]string, 0)
+	if on == SearchOnJson {
+		for key := range db.JsonStore {
+			keys = append(keys, key)
+		}
+	} else {
+		for key := range db.HashStore {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if idx.Covers(key) {
+			doc, _ := idx.Document(key)
+			idx.Add(key, doc)
+		}
+	}
+	db.SearchIndexes[name] = idx
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax: FT.SEARCH index query [NOCONTENT] [RETURN count identifier [identifier ...]] [SORTBY field [ASC | DESC]] [LIMIT offset num]
+Reply:
+  - Array reply: the number of matching documents, followed by the key and the content of each document in the page.
+    The content is all fields of a hash, or the serialized JSON document under "$", or the fields in RETURN, or
+    nothing with NOCONTENT.
+
+Queries support:
+  - @field:[min max] for NUMERIC fields, where "(" makes a bound exclusive, and -inf and +inf are unbounded
+  - @field:{tag1 | tag2} for TAG fields
+  - @field:term or @field:(query) for TEXT fields, and terms without fields for all TEXT fields
+  - AND by spaces, OR by "|", NOT by "-", grouping by parentheses, and "*" for all documents
+
+Documents are ordered by keys without SORTBY. Documents without the SORTBY field come last.
+*/
+func (e searchCmdExecutor) parseFtSearchCmdArgs(cmdArgs []*resp.RespValue, idx *SearchIndex, opts *searchOptions) error {
+	opts.Limit = SearchDefaultLimit
+
+	for i := 0; i < len(cmdArgs); i++ {
+		switch strings.ToUpper(cmdArgs[i].BulkStr) {
+		case "NOCONTENT":
+			opts.NoContent = true
+		case "RETURN":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			n, err := parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil || n < 0 || n > int64(len(cmdArgs)-i-2) {
+				return ErrSyntax
+			}
+			opts.Return = make([]string, 0, n)
+			for _, arg := range cmdArgs[i+2 : i+2+int(n)] {
+				opts.Return = append(opts.Return, arg.BulkStr)
+			}
+			i += 1 + int(n)
+		case "SORTBY":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			opts.SortBy = idx.Field(cmdArgs[i+1].BulkStr)
+			if opts.SortBy == nil {
+				return fmt.Errorf("ERR Property `%s` not loaded nor in schema", cmdArgs[i+1].BulkStr)
+			}
+			i++
+			if i+1 < len(cmdArgs) {
+				switch strings.ToUpper(cmdArgs[i+1].BulkStr) {
+				case "ASC":
+					i++
+				case "DESC":
+					opts.SortDesc = true
+					i++
+				}
+			}
+		case "LIMIT":
+			if i+2 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			offset, err := parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil || offset < 0 {
+				return ErrSyntax
+			}
+			limit, err := parseInt(cmdArgs[i+2].BulkStr)
+			if err != nil || limit < 0 {
+				return ErrSyntax
+			}
+			opts.Offset, opts.Limit = int(offset), int(limit)
+			i += 2
+		default:
+			return ErrSyntax
+		}
+	}
+	return nil
+}
+
+// sortKeys sorts the keys by a field. NUMERIC fields are compared by their skip list scores, which have the same
+// order as the numbers, and other fields by their first string values.
+func (e searchCmdExecutor) sortKeys(idx *SearchIndex, keys []string, f *SearchField, desc bool) {
+	type sortValue struct {
+		found bool
+		score int
+		str   string
+	}
+	values := make(map[string]sortValue, len(keys))
+	for _, key := range keys {
+		if f.Type == SearchFieldNumeric {
+			if node, found := idx.Numeric[f.Name].MemberMap[key]; found {
+				values[key] = sortValue{found: true, score: node.Score}
+			}
+			continue
+		}
+		doc, _ := idx.Document(key)
+		for _, m := range f.Path.Eval(doc) {
+			if m.Value.Type == JsonTypeString {
+				values[key] = sortValue{found: true, str: m.Value.Str}
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := values[keys[i]], values[keys[j]]
+		if !a.found || !b.found {
+			return a.found && !b.found
+		}
+		if a.score != b.score {
+			return (a.score < b.score) != desc
+		}
+		if a.str != b.str {
+			return (a.str < b.str) != desc
+		}
+		return false
+	})
+}
+
+func (e searchCmdExecutor) makeContentReply(idx *SearchIndex, doc *JsonValue, opts *searchOptions) *resp.RespValue {
+	if len(opts.Return) == 0 && idx.On == SearchOnJson {
+		return resp.MakeArray([]*resp.RespValue{resp.MakeBulkString("$"), resp.MakeBulkString(doc.String())})
+	}
+	if len(opts.Return) == 0 {
+		res := make([]*resp.RespValue, 0, 2*len(doc.Keys))
+		for _, field := range doc.Keys {
+			res = append(res, resp.MakeBulkString(field), resp.MakeBulkString(doc.Fields[field].Str))
+		}
+		return resp.MakeArray(res)
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, name := range opts.Return {
+		var path *JsonPath
+		if f := idx.Field(name); f != nil {
+			path = f.Path
+		} else if idx.On == SearchOnHash {
+			path = hashFieldPath(name)
+		} else if p, err := ParseJsonPath(name); err == nil {
+			path = p
+		} else {
+			continue
+		}
+
+		// Strings are returned as is, and other values are serialized
+		matches := path.Eval(doc)
+		if len(matches) == 0 {
+			continue
+		}
+		v := matches[0].Value
+		value := v.String()
+		if v.Type == JsonTypeString {
+			value = v.Str
+		}
+		res = append(res, resp.MakeBulkString(name), resp.MakeBulkString(value))
+	}
+	return resp.MakeArray(res)
+}
+
+func (e searchCmdExecutor) executeFtSearchCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	idx, found := db.SearchIndexes[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrSearchIndexNotFound)
+		return
+	}
+	var opts searchOptions
+	err := e.parseFtSearchCmdArgs(cmdArgs[2:], idx, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	matched, err := idx.Search(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	keys := make([]string, 0, len(matched))
+	for key := range matched {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if opts.SortBy != nil {
+		e.sortKeys(idx, keys, opts.SortBy, opts.SortDesc)
+	}
+
+	res := []*resp.RespValue{resp.MakeInt(len(keys))}
+	start := min(opts.Offset, len(keys))
+	end := start + min(opts.Limit, len(keys)-start)
+	for _, key := range keys[start:end] {
+		res = append(res, resp.MakeBulkString(key))
+		if !opts.NoContent {
+			doc, _ := idx.Document(key)
+			res = append(res, e.makeContentReply(idx, doc, &opts))
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func (e searchCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "FT.CREATE":
+		e.executeFtCreateCmd(c, cmdArgs)
+	case "FT.SEARCH":
+		e.executeFtSearchCmd(c, cmdArgs)
+	}
+}
@@ -0,0 +1,92 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	c := &ClientInfo{}
+	e := searchCmdExecutor{}
+	je := jsonCmdExecutor{}
+
+	set := func(key string, doc string) {
+		Reset()
+		je.Execute(c, "JSON.SET", makeCmdArgs(key, "$", doc))
+	}
+
+	// Existing documents are indexed on creation
+	set("book:1", `{"title":"The Go Programming Language","year":2015,"genre":"tech"}`)
+	set("note:1", `{"title":"Go shopping","year":2020}`)
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "FT.CREATE", "idx", "ON", "XML", "SCHEMA", "$.title", "TEXT"))
+	assert.Equal(t, "-ERR Fields arguments are missing\r\n", runCmd(e, "FT.CREATE", "idx", "ON", "JSON"))
+	assert.Equal(t, "-ERR Invalid field type for field `year`\r\n", runCmd(e, "FT.CREATE", "idx", "SCHEMA", "$.year", "AS", "year", "DATE"))
+	assert.Equal(t, "-ERR Duplicate field in schema - t\r\n", runCmd(e, "FT.CREATE", "idx", "SCHEMA", "$.a", "AS", "t", "TEXT", "$.b", "AS", "t", "TAG"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "FT.CREATE", "idx", "ON", "JSON", "PREFIX", "1", "book:", "SCHEMA",
+		"$.title", "AS", "title", "TEXT", "$.year", "AS", "year", "NUMERIC", "SORTABLE", "$.genre", "AS", "genre", "TAG", "SEPARATOR", ";"))
+	assert.Equal(t, "-ERR Index already exists\r\n", runCmd(e, "FT.CREATE", "idx", "SCHEMA", "$.title", "TEXT"))
+	assert.Equal(t, "*3\r\n:1\r\n$6\r\nbook:1\r\n*2\r\n$1\r\n$\r\n$66\r\n"+`{"title":"The Go Programming Language","year":2015,"genre":"tech"}`+"\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "go"))
+
+	// Later writes are indexed too
+	set("book:2", `{"title":"Learning Go","year":2021,"genre":"tech;beginner"}`)
+	set("book:3", `{"title":"Dune","year":1965,"genre":"fiction"}`)
+	assert.Equal(t, "*3\r\n:2\r\n$6\r\nbook:1\r\n$6\r\nbook:2\r\n", runCmd(e, "FT.SEARCH", "idx", "go", "NOCONTENT"))
+	assert.Equal(t, "*2\r\n:1\r\n$6\r\nbook:2\r\n", runCmd(e, "FT.SEARCH", "idx", "@genre:{beginner}", "NOCONTENT"))
+	set("book:2", `{"title":"Learning Rust","year":2021}`)
+	assert.Equal(t, "*2\r\n:1\r\n$6\r\nbook:1\r\n", runCmd(e, "FT.SEARCH", "idx", "go", "NOCONTENT"))
+
+	assert.Equal(t, "*4\r\n:3\r\n$6\r\nbook:2\r\n$6\r\nbook:1\r\n$6\r\nbook:3\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "*", "NOCONTENT", "SORTBY", "year", "DESC"))
+	assert.Equal(t, "*3\r\n:3\r\n$6\r\nbook:2\r\n$6\r\nbook:1\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "@year:[2000 +inf] | dune", "NOCONTENT", "SORTBY", "title", "LIMIT", "1", "2"))
+	assert.Equal(t, "*3\r\n:1\r\n$6\r\nbook:3\r\n*4\r\n$5\r\ntitle\r\n$4\r\nDune\r\n$4\r\nyear\r\n$4\r\n1965\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "@year:[-inf (2000]", "RETURN", "2", "title", "year"))
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "FT.SEARCH", "idx", "@title:dune @genre:{tech}"))
+
+	assert.Equal(t, "-ERR Unknown index name\r\n", runCmd(e, "FT.SEARCH", "missing", "*"))
+	assert.Equal(t, "-ERR Syntax error in query\r\n", runCmd(e, "FT.SEARCH", "idx", "(go"))
+	assert.Equal(t, "-ERR Unknown field 'author'\r\n", runCmd(e, "FT.SEARCH", "idx", "@author:herbert"))
+	assert.Equal(t, "-ERR Property `author` not loaded nor in schema\r\n", runCmd(e, "FT.SEARCH", "idx", "*", "SORTBY", "author"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "FT.SEARCH", "idx", "*", "LIMIT", "0"))
+
+	// Counts larger than the arguments or the results
+	assert.Equal(t, "*3\r\n:3\r\n$6\r\nbook:2\r\n$6\r\nbook:3\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "*", "NOCONTENT", "LIMIT", "1", "9223372036854775807"))
+	assert.Equal(t, "*1\r\n:3\r\n", runCmd(e, "FT.SEARCH", "idx", "*", "NOCONTENT", "LIMIT", "9223372036854775807", "1"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "FT.SEARCH", "idx", "*", "RETURN", "9223372036854775807", "title"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "FT.CREATE", "idx2", "PREFIX", "9223372036854775807", "a", "SCHEMA", "t", "TEXT"))
+}
+
+func TestSearchCmdsOnHash(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := searchCmdExecutor{}
+	he := hashCmdExecutor{}
+
+	// Hashes are indexed by default, and identifiers are field names
+	runCmd(he, "HSET", "book:1", "title", "The Go Programming Language", "year", "2015", "genre", "tech")
+	runCmd(he, "HSET", "book:2", "title", "Dune", "year", "1965")
+	assert.Equal(t, "+OK\r\n", runCmd(e, "FT.CREATE", "idx", "PREFIX", "1", "book:", "SCHEMA",
+		"title", "TEXT", "year", "NUMERIC", "genre", "TAG"))
+	assert.Equal(t, "*3\r\n:1\r\n$6\r\nbook:1\r\n*6\r\n$5\r\ngenre\r\n$4\r\ntech\r\n$5\r\ntitle\r\n$27\r\nThe Go Programming Language\r\n$4\r\nyear\r\n$4\r\n2015\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "go"))
+	assert.Equal(t, "*3\r\n:2\r\n$6\r\nbook:2\r\n$6\r\nbook:1\r\n", runCmd(e, "FT.SEARCH", "idx", "@year:[1900 2020]", "NOCONTENT", "SORTBY", "year"))
+
+	// HSET and HDEL re-index hashes, and values of NUMERIC fields that are not numbers are not indexed
+	runCmd(he, "HSET", "book:2", "year", "unknown", "genre", "fiction")
+	assert.Equal(t, "*2\r\n:1\r\n$6\r\nbook:1\r\n", runCmd(e, "FT.SEARCH", "idx", "@year:[1900 2020]", "NOCONTENT"))
+	assert.Equal(t, "*3\r\n:1\r\n$6\r\nbook:2\r\n*2\r\n$5\r\ngenre\r\n$7\r\nfiction\r\n",
+		runCmd(e, "FT.SEARCH", "idx", "@genre:{fiction}", "RETURN", "2", "genre", "missing"))
+	runCmd(he, "HDEL", "book:1", "title")
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "FT.SEARCH", "idx", "go"))
+	runCmd(he, "HDEL", "book:1", "year", "genre")
+	assert.Equal(t, "*2\r\n:1\r\n$6\r\nbook:2\r\n", runCmd(e, "FT.SEARCH", "idx", "*", "NOCONTENT"))
+
+	// JSON documents are not indexed by hash indexes
+	runCmd(jsonCmdExecutor{}, "JSON.SET", "book:3", "$", `{"title":"Go"}`)
+	assert.Equal(t, "*1\r\n:0\r\n", runCmd(e, "FT.SEARCH", "idx", "go"))
+}
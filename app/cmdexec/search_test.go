@@ -0,0 +1,79 @@
+package cmdexec
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericScore(t *testing.T) {
+	nums := []float64{math.Inf(-1), -1e10, -2.5, -1, -1e-10, 0, 1e-10, 1, 2.5, 1e10, math.Inf(1)}
+	for i := 1; i < len(nums); i++ {
+		assert.Less(t, numericScore(nums[i-1]), numericScore(nums[i]))
+	}
+	assert.Equal(t, numericScore(0), numericScore(math.Copysign(0, -1)))
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"hello", "world", "42"}, Tokenize("Hello, World! 42"))
+	assert.Equal(t, []string{"a", "b"}, splitTags(" A ,, b", ","))
+}
+
+func TestSearchIndex(t *testing.T) {
+	InitRedisDb()
+	price := &SearchField{Name: "price", Path: mustParseJsonPath(t, "$.price"), Type: SearchFieldNumeric}
+	tags := &SearchField{Name: "tags", Path: mustParseJsonPath(t, "$.tags"), Type: SearchFieldTag, Separator: ","}
+	title := &SearchField{Name: "title", Path: mustParseJsonPath(t, "$.title"), Type: SearchFieldText}
+	idx := MakeSearchIndex("idx", SearchOnJson, []string{"item:"}, []*SearchField{price, tags, title})
+
+	docs := map[string]string{
+		"item:1": `{"title":"Red apple","price":3,"tags":"fruit,red"}`,
+		"item:2": `{"title":"Green apple","price":5,"tags":["fruit","green"]}`,
+		"item:3": `{"title":"Red car","price":20000,"tags":"vehicle, red"}`,
+		"item:4": `{"title":"Banana","price":-1}`,
+	}
+	for key, s := range docs {
+		doc, err := ParseJson(s)
+		assert.Nil(t, err)
+		db.JsonStore[key] = doc
+		idx.Add(key, doc)
+	}
+	assert.True(t, idx.Covers("item:9"))
+	assert.False(t, idx.Covers("user:1"))
+
+	search := func(query string) []string {
+		res, err := idx.Search(query)
+		assert.Nil(t, err)
+		keys := make([]string, 0)
+		for key := range res {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	assert.Equal(t, []string{"item:1", "item:2", "item:3", "item:4"}, search("*"))
+	assert.Equal(t, []string{"item:1", "item:2"}, search("apple"))
+	assert.Equal(t, []string{"item:1"}, search("red apple"))
+	assert.Equal(t, []string{"item:1", "item:3", "item:4"}, search("red | banana"))
+	assert.Equal(t, []string{"item:2", "item:4"}, search("-red"))
+	assert.Equal(t, []string{"item:2"}, search("apple -@tags:{red}"))
+	assert.Equal(t, []string{"item:1", "item:2"}, search("@price:[0 10]"))
+	assert.Equal(t, []string{"item:2"}, search("@price:[(3 10]"))
+	assert.Equal(t, []string{"item:3", "item:4"}, search("@price:[-inf 0] | @price:[1000 +inf]"))
+	assert.Equal(t, []string{"item:2", "item:3"}, search("@tags:{green | vehicle}"))
+	assert.Equal(t, []string{"item:1", "item:3"}, search("@title:(red (apple | car))"))
+	assert.Equal(t, []string{}, search("@title:pear"))
+
+	// Removed documents are not found anymore
+	idx.Remove("item:1")
+	assert.Equal(t, []string{"item:3"}, search("red"))
+	assert.Equal(t, []string{"item:2"}, search("@price:[0 10]"))
+	assert.Empty(t, idx.Tags["tags"]["red"]["item:1"])
+
+	for _, query := range []string{"(red", "@price:[1]", "@price:[a 1]", "@tags:{red", "@missing:red", "@price"} {
+		_, err := idx.Search(query)
+		assert.NotNil(t, err, query)
+	}
+}
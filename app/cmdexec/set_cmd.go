@@ -1,6 +1,9 @@
 package cmdexec
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -8,39 +11,67 @@ import (
 	"github.com/stanleygy/toy-redis/app/resp"
 )
 
+// Same as Redis's default proto-max-bulk-len
+const StringMaxBytes = 512 * 1024 * 1024
+
+var (
+	ErrStringTooLong    = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	ErrOffsetOutOfRange = errors.New("ERR offset is out of range")
+)
+
 /*
- * syntax: SET key value [NX] [EX seconds | PX milliseconds]
+ * syntax: SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
  */
 type setCmdExecutor struct{}
 
-func (e setCmdExecutor) parseSetCmdArgs(args []*resp.RespValue, key *string, val *string, nxFlag *bool, expiry *bool, ttl *time.Time) error {
+func (e setCmdExecutor) parseSetCmdArgs(args []*resp.RespValue, key *string, val *string, nxFlag *bool, xxFlag *bool, getFlag *bool, keepTTL *bool, expiry *bool, ttl *time.Time) error {
+	if len(args) < 2 {
+		return ErrInvalidArgs
+	}
+	var err error
+
 	*key = args[0].BulkStr
 	*val = args[1].BulkStr
 
 	for i := 2; i < len(args); i++ {
 		modifier := strings.ToUpper(args[i].BulkStr)
 
-		if modifier == "NX" {
+		switch modifier {
+		case "NX":
 			// NX - only set the key if it does not already exist
+			if *xxFlag {
+				return ErrSyntax
+			}
 			*nxFlag = true
-		} else if modifier == "PX" || modifier == "EX" {
-			// PX milliseconds - set the specified expire time in ms (a positive integer)
-			// EX milliseconds - set the specified expire time in secs (a positive integer)
-			expireTime, err := strconv.Atoi(args[i+1].BulkStr)
+		case "XX":
+			// XX - only set the key if it already exists
+			if *nxFlag {
+				return ErrSyntax
+			}
+			*xxFlag = true
+		case "GET":
+			// GET - return the old value
+			*getFlag = true
+		case "KEEPTTL":
+			// KEEPTTL - retain the TTL of the existing key
+			if *expiry {
+				return ErrSyntax
+			}
+			*keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			// EX seconds, PX milliseconds - set the expire time relative to now (a positive integer)
+			// EXAT seconds, PXAT milliseconds - set the expire time as a unix timestamp (a positive integer)
+			if *expiry || *keepTTL || i+1 >= len(args) {
+				return ErrSyntax
+			}
+			*ttl, err = e.parseExpireTime("SET", modifier, args[i+1].BulkStr)
 			if err != nil {
 				return err
 			}
-			if expireTime < 0 {
-				return ErrInvalidArgs
-			}
-
 			*expiry = true
-			if modifier == "PX" {
-				*ttl = time.Now().Add(time.Millisecond * time.Duration(expireTime))
-			} else {
-				*ttl = time.Now().Add(time.Second * time.Duration(expireTime))
-			}
 			i++
+		default:
+			return ErrSyntax
 		}
 	}
 	return nil
@@ -59,9 +90,7 @@ func (e setCmdExecutor) set(key string, val string, nxFlag bool, expiry bool, tt
 	if nxFlag && e.doesKeyExistOrUnexpire(key) {
 		return false
 	}
-	kvStoreVal := &DictStoreValue{
-		Value: val,
-	}
+	kvStoreVal := MakeDictStoreValue(val)
 	if expiry {
 		kvStoreVal.ExpireTime = ttl
 		kvStoreVal.WillExpire = true
@@ -72,18 +101,51 @@ func (e setCmdExecutor) set(key string, val string, nxFlag bool, expiry bool, tt
 
 func (e setCmdExecutor) executeSetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
 	var (
-		key    string
-		val    string
-		nxFlag bool = false
-		expiry bool = false
-		ttl    time.Time
+		key     string
+		val     string
+		nxFlag  bool
+		xxFlag  bool
+		getFlag bool
+		keepTTL bool
+		expiry  bool
+		ttl     time.Time
 	)
-	e.parseSetCmdArgs(cmdArgs, &key, &val, &nxFlag, &expiry, &ttl)
-	if !e.set(key, val, nxFlag, expiry, ttl) {
-		AddNullBulkStringReplyEvent(c)
+	err := e.parseSetCmdArgs(cmdArgs, &key, &val, &nxFlag, &xxFlag, &getFlag, &keepTTL, &expiry, &ttl)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
 		return
 	}
-	AddSimpleStringReplyEvent(c, "OK")
+
+	old, found := e.lookUp(key)
+	if found && keepTTL {
+		expiry = old.WillExpire
+		ttl = old.ExpireTime
+	}
+
+	// With GET, the old value is returned whether or not the key is set
+	var reply *resp.RespValue
+	if getFlag {
+		if found {
+			reply = resp.MakeBulkString(old.String())
+		} else {
+			reply = resp.MakeNilBulkString()
+		}
+	}
+
+	if (nxFlag && found) || (xxFlag && !found) {
+		if reply == nil {
+			reply = resp.MakeNilBulkString()
+		}
+		AddReplyEvent(c, reply)
+		return
+	}
+
+	e.set(key, val, false, expiry, ttl)
+	if reply == nil {
+		AddSimpleStringReplyEvent(c, "OK")
+		return
+	}
+	AddReplyEvent(c, reply)
 }
 
 func (e setCmdExecutor) executeGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
@@ -93,7 +155,419 @@ func (e setCmdExecutor) executeGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue)
 		return
 	}
 	val := db.DictStore[key]
-	AddBulkStringReplyEvent(c, val.Value)
+	AddBulkStringReplyEvent(c, val.String())
+}
+
+// lookUp returns the value at `key`, deleting it first if it has expired
+func (e setCmdExecutor) lookUp(key string) (*DictStoreValue, bool) {
+	if !e.doesKeyExistOrUnexpire(key) {
+		return nil, false
+	}
+	return db.DictStore[key], true
+}
+
+/*
+parseExpireTime parses the expire time of EX, PX, EXAT and PXAT options into a deadline. Same as Redis, the deadline
+is computed in milliseconds since the epoch, and expire times whose deadlines overflow are invalid.
+*/
+func (e setCmdExecutor) parseExpireTime(cmdName string, option string, arg string) (time.Time, error) {
+	v, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrNotInteger
+	}
+	errInvalid := fmt.Errorf("ERR invalid expire time in '%s' command", strings.ToLower(cmdName))
+	if v <= 0 {
+		return time.Time{}, errInvalid
+	}
+
+	if option == "EX" || option == "EXAT" {
+		if v > math.MaxInt64/1000 {
+			return time.Time{}, errInvalid
+		}
+		v *= 1000
+	}
+	if option == "EX" || option == "PX" {
+		now := time.Now().UnixMilli()
+		if v > math.MaxInt64-now {
+			return time.Time{}, errInvalid
+		}
+		v += now
+	}
+	return time.UnixMilli(v), nil
+}
+
+/*
+Syntax: APPEND key value
+Reply:
+  - Integer reply: the length of the string after the append
+*/
+func (e setCmdExecutor) executeAppendCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key, suffix := cmdArgs[0].BulkStr, cmdArgs[1].BulkStr
+
+	val, found := e.lookUp(key)
+	if !found {
+		e.set(key, suffix, false, false, time.Time{})
+		AddIntegerReplyEvent(c, len(suffix))
+		return
+	}
+
+	str := val.String()
+	if len(str)+len(suffix) > StringMaxBytes {
+		AddErrorReplyEvent(c, ErrStringTooLong)
+		return
+	}
+
+	// The TTL is kept
+	val.SetString(str + suffix)
+	AddIntegerReplyEvent(c, len(str)+len(suffix))
+}
+
+/*
+Syntax: STRLEN key
+Reply:
+  - Integer reply: the length of the string, or 0 when key does not exist
+*/
+func (e setCmdExecutor) executeStrLenCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	val, found := e.lookUp(cmdArgs[0].BulkStr)
+	if !found {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	AddIntegerReplyEvent(c, len(val.String()))
+}
+
+/*
+Syntax: GETRANGE key start end
+Reply:
+  - Bulk string reply: the substring between start and end inclusively. Negative offsets count from the end.
+*/
+func (e setCmdExecutor) parseGetRangeCmdArgs(cmdArgs []*resp.RespValue, key *string, start *int64, end *int64) error {
+	if len(cmdArgs) != 3 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*key = cmdArgs[0].BulkStr
+	*start, err = parseInt(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	*end, err = parseInt(cmdArgs[2].BulkStr)
+	return err
+}
+
+func (e setCmdExecutor) executeGetRangeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key   string
+		start int64
+		end   int64
+	)
+	err := e.parseGetRangeCmdArgs(cmdArgs, &key, &start, &end)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	val, found := e.lookUp(key)
+	if !found {
+		AddBulkStringReplyEvent(c, "")
+		return
+	}
+	str := val.String()
+	strLen := int64(len(str))
+
+	// Same as Redis, convert negative offsets and clamp them into the string. Ranges that are empty before
+	// clamping stay empty, so that both offsets are not clamped to the first byte.
+	if start < 0 && end < 0 && start > end {
+		AddBulkStringReplyEvent(c, "")
+		return
+	}
+	if start < 0 {
+		start = max(strLen+start, 0)
+	}
+	if end < 0 {
+		end = max(strLen+end, 0)
+	}
+	end = min(end, strLen-1)
+	if start > end || strLen == 0 {
+		AddBulkStringReplyEvent(c, "")
+		return
+	}
+	AddBulkStringReplyEvent(c, str[start:end+1])
+}
+
+/*
+Syntax: SETRANGE key offset value
+Reply:
+  - Integer reply: the length of the string after it is modified
+*/
+func (e setCmdExecutor) parseSetRangeCmdArgs(cmdArgs []*resp.RespValue, key *string, offset *int64, value *string) error {
+	if len(cmdArgs) != 3 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*key = cmdArgs[0].BulkStr
+	*offset, err = parseInt(cmdArgs[1].BulkStr)
+	if err != nil {
+		return err
+	}
+	if *offset < 0 {
+		return ErrOffsetOutOfRange
+	}
+	*value = cmdArgs[2].BulkStr
+	if *offset > StringMaxBytes-int64(len(*value)) {
+		return ErrStringTooLong
+	}
+	return nil
+}
+
+func (e setCmdExecutor) executeSetRangeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key    string
+		offset int64
+		value  string
+	)
+	err := e.parseSetRangeCmdArgs(cmdArgs, &key, &offset, &value)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	val, found := e.lookUp(key)
+	var str string
+	if found {
+		str = val.String()
+	}
+
+	// An empty value does not create or modify the key
+	if len(value) == 0 {
+		AddIntegerReplyEvent(c, len(str))
+		return
+	}
+
+	// Pad the string with zero bytes up to the offset
+	buf := []byte(str)
+	if newLen := int(offset) + len(value); newLen > len(buf) {
+		buf = append(buf, make([]byte, newLen-len(buf))...)
+	}
+	copy(buf[offset:], value)
+
+	// The TTL is kept
+	if found {
+		val.SetString(string(buf))
+	} else {
+		e.set(key, string(buf), false, false, time.Time{})
+	}
+	AddIntegerReplyEvent(c, len(buf))
+}
+
+/*
+Syntax: MGET key [key ...]
+Reply:
+  - Array reply: a list of values, with nil for keys that do not exist
+*/
+func (e setCmdExecutor) executeMGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs))
+	for _, arg := range cmdArgs {
+		val, found := e.lookUp(arg.BulkStr)
+		if found {
+			res = append(res, resp.MakeBulkString(val.String()))
+		} else {
+			res = append(res, resp.MakeNilBulkString())
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax:
+  - MSET key value [key value ...]
+  - MSETNX key value [key value ...]
+
+Reply:
+  - Simple string reply: OK for MSET
+  - Integer reply: 1 if all keys are set, or 0 if no key is set because some key already exists for MSETNX
+*/
+func (e setCmdExecutor) executeMSetCmd(c *ClientInfo, cmdArgs []*resp.RespValue, nxFlag bool) {
+	if len(cmdArgs) < 2 || len(cmdArgs)%2 != 0 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	if nxFlag {
+		for i := 0; i < len(cmdArgs); i += 2 {
+			if e.doesKeyExistOrUnexpire(cmdArgs[i].BulkStr) {
+				AddIntegerReplyEvent(c, 0)
+				return
+			}
+		}
+	}
+
+	for i := 0; i < len(cmdArgs); i += 2 {
+		e.set(cmdArgs[i].BulkStr, cmdArgs[i+1].BulkStr, false, false, time.Time{})
+	}
+	if nxFlag {
+		AddIntegerReplyEvent(c, 1)
+	} else {
+		AddSimpleStringReplyEvent(c, "OK")
+	}
+}
+
+/*
+Syntax:
+  - GETDEL key
+  - GETSET key value
+
+Reply:
+  - Bulk string reply: the old value, or nil when key does not exist
+*/
+func (e setCmdExecutor) executeGetDelCmd(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	if (cmdName == "GETDEL" && len(cmdArgs) != 1) || (cmdName == "GETSET" && len(cmdArgs) != 2) {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	val, found := e.lookUp(key)
+	if cmdName == "GETDEL" {
+		delete(db.DictStore, key)
+	} else {
+		// The TTL is discarded
+		e.set(key, cmdArgs[1].BulkStr, false, false, time.Time{})
+	}
+
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	AddBulkStringReplyEvent(c, val.String())
+}
+
+/*
+Syntax: GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+Reply:
+  - Bulk string reply: the value, or nil when key does not exist
+*/
+func (e setCmdExecutor) parseGetExCmdArgs(cmdArgs []*resp.RespValue, key *string, persist *bool, expiry *bool, ttl *time.Time) error {
+	if len(cmdArgs) < 1 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	switch len(cmdArgs) {
+	case 1:
+		return nil
+	case 2:
+		if strings.ToUpper(cmdArgs[1].BulkStr) != "PERSIST" {
+			return ErrSyntax
+		}
+		*persist = true
+	case 3:
+		option := strings.ToUpper(cmdArgs[1].BulkStr)
+		if option != "EX" && option != "PX" && option != "EXAT" && option != "PXAT" {
+			return ErrSyntax
+		}
+		*ttl, err = e.parseExpireTime("GETEX", option, cmdArgs[2].BulkStr)
+		if err != nil {
+			return err
+		}
+		*expiry = true
+	default:
+		return ErrSyntax
+	}
+	return nil
+}
+
+func (e setCmdExecutor) executeGetExCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key     string
+		persist bool
+		expiry  bool
+		ttl     time.Time
+	)
+	err := e.parseGetExCmdArgs(cmdArgs, &key, &persist, &expiry, &ttl)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	val, found := e.lookUp(key)
+	if !found {
+		AddNullBulkStringReplyEvent(c)
+		return
+	}
+	reply := val.String()
+
+	if persist {
+		val.WillExpire = false
+		val.ExpireTime = time.Time{}
+	} else if expiry {
+		// Same as Redis, a deadline in the past deletes the key
+		if ttl.Before(time.Now()) {
+			delete(db.DictStore, key)
+		} else {
+			val.WillExpire = true
+			val.ExpireTime = ttl
+		}
+	}
+	AddBulkStringReplyEvent(c, reply)
+}
+
+/*
+Syntax:
+  - SETNX key value
+  - SETEX key seconds value
+  - PSETEX key milliseconds value
+
+Reply:
+  - Integer reply: 1 if the key is set, or 0 otherwise for SETNX
+  - Simple string reply: OK for SETEX and PSETEX
+*/
+func (e setCmdExecutor) executeSetNXCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	if e.set(cmdArgs[0].BulkStr, cmdArgs[1].BulkStr, true, false, time.Time{}) {
+		AddIntegerReplyEvent(c, 1)
+	} else {
+		AddIntegerReplyEvent(c, 0)
+	}
+}
+
+func (e setCmdExecutor) executeSetExCmd(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	option := "EX"
+	if cmdName == "PSETEX" {
+		option = "PX"
+	}
+	ttl, err := e.parseExpireTime(cmdName, option, cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	e.set(cmdArgs[0].BulkStr, cmdArgs[2].BulkStr, false, true, ttl)
+	AddSimpleStringReplyEvent(c, "OK")
 }
 
 func (e setCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
@@ -102,5 +576,33 @@ func (e setCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.R
 		e.executeSetCmd(c, cmdArgs)
 	case "GET":
 		e.executeGetCmd(c, cmdArgs)
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		e.executeIncrCmd(c, cmdName, cmdArgs)
+	case "INCRBYFLOAT":
+		e.executeIncrByFloatCmd(c, cmdArgs)
+	case "APPEND":
+		e.executeAppendCmd(c, cmdArgs)
+	case "STRLEN":
+		e.executeStrLenCmd(c, cmdArgs)
+	case "GETRANGE":
+		e.executeGetRangeCmd(c, cmdArgs)
+	case "SETRANGE":
+		e.executeSetRangeCmd(c, cmdArgs)
+	case "MGET":
+		e.executeMGetCmd(c, cmdArgs)
+	case "MSET":
+		e.executeMSetCmd(c, cmdArgs, false)
+	case "MSETNX":
+		e.executeMSetCmd(c, cmdArgs, true)
+	case "GETDEL", "GETSET":
+		e.executeGetDelCmd(c, cmdName, cmdArgs)
+	case "GETEX":
+		e.executeGetExCmd(c, cmdArgs)
+	case "SETNX":
+		e.executeSetNXCmd(c, cmdArgs)
+	case "SETEX", "PSETEX":
+		e.executeSetExCmd(c, cmdName, cmdArgs)
+	case "LCS":
+		e.executeLcsCmd(c, cmdArgs)
 	}
 }
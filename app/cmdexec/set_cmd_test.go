@@ -0,0 +1,152 @@
+package cmdexec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := setCmdExecutor{}
+
+	assert.Equal(t, ":5\r\n", runCmd(e, "APPEND", "str", "Hello"))
+	assert.Equal(t, ":11\r\n", runCmd(e, "APPEND", "str", " World"))
+	assert.Equal(t, ":11\r\n", runCmd(e, "STRLEN", "str"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "STRLEN", "missing"))
+
+	assert.Equal(t, "$5\r\nHello\r\n", runCmd(e, "GETRANGE", "str", "0", "4"))
+	assert.Equal(t, "$5\r\nWorld\r\n", runCmd(e, "GETRANGE", "str", "-5", "-1"))
+	assert.Equal(t, "$11\r\nHello World\r\n", runCmd(e, "GETRANGE", "str", "-100", "100"))
+	assert.Equal(t, "$0\r\n\r\n", runCmd(e, "GETRANGE", "str", "5", "3"))
+	assert.Equal(t, "$0\r\n\r\n", runCmd(e, "GETRANGE", "missing", "0", "-1"))
+	runCmd(e, "SET", "k", "abc")
+	assert.Equal(t, "$0\r\n\r\n", runCmd(e, "GETRANGE", "k", "-100", "-200"))
+	assert.Equal(t, "$1\r\na\r\n", runCmd(e, "GETRANGE", "k", "-200", "-100"))
+
+	// Integers are appended as strings
+	runCmd(e, "SET", "num", "12")
+	assert.Equal(t, ":4\r\n", runCmd(e, "APPEND", "num", "34"))
+	assert.Equal(t, "$4\r\n1234\r\n", runCmd(e, "GET", "num"))
+
+	// SETRANGE pads with zero bytes
+	assert.Equal(t, ":11\r\n", runCmd(e, "SETRANGE", "str", "6", "Redis"))
+	assert.Equal(t, "$11\r\nHello Redis\r\n", runCmd(e, "GET", "str"))
+	assert.Equal(t, ":8\r\n", runCmd(e, "SETRANGE", "padded", "5", "abc"))
+	assert.Equal(t, "\x00\x00\x00\x00\x00abc", db.DictStore["padded"].String())
+	assert.Equal(t, ":0\r\n", runCmd(e, "SETRANGE", "missing", "5", ""))
+	assert.NotContains(t, db.DictStore, "missing")
+	assert.Equal(t, "-ERR offset is out of range\r\n", runCmd(e, "SETRANGE", "str", "-1", "a"))
+	assert.Equal(t, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n", runCmd(e, "SETRANGE", "str", "536870911", "ab"))
+	assert.Equal(t, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n", runCmd(e, "SETRANGE", "str", "9223372036854775807", "a"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "MSET", "k1", "v1", "k2", "v2"))
+	assert.Equal(t, "*3\r\n$2\r\nv1\r\n$-1\r\n$2\r\nv2\r\n", runCmd(e, "MGET", "k1", "missing", "k2"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "MSET", "k1", "v1", "k2"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "MSETNX", "k3", "v3", "k1", "v1"))
+	assert.NotContains(t, db.DictStore, "k3")
+	assert.Equal(t, ":1\r\n", runCmd(e, "MSETNX", "k3", "v3", "k4", "v4"))
+
+	assert.Equal(t, "$2\r\nv1\r\n", runCmd(e, "GETDEL", "k1"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "GETDEL", "k1"))
+	assert.Equal(t, "$2\r\nv2\r\n", runCmd(e, "GETSET", "k2", "new"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "GETSET", "k1", "new"))
+	assert.Equal(t, "$3\r\nnew\r\n", runCmd(e, "GET", "k1"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "SETNX", "nx", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "SETNX", "nx", "b"))
+	assert.Equal(t, "$1\r\na\r\n", runCmd(e, "GET", "nx"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "SETEX", "ex", "100", "v"))
+	assert.True(t, db.DictStore["ex"].WillExpire)
+	assert.Equal(t, "-ERR invalid expire time in 'setex' command\r\n", runCmd(e, "SETEX", "ex", "0", "v"))
+	assert.Equal(t, "-ERR invalid expire time in 'psetex' command\r\n", runCmd(e, "PSETEX", "ex", "-5", "v"))
+
+	// GETSET discards the TTL, while APPEND and SETRANGE keep it
+	expireTime := db.DictStore["ex"].ExpireTime
+	runCmd(e, "APPEND", "ex", "v")
+	runCmd(e, "SETRANGE", "ex", "0", "w")
+	assert.Equal(t, expireTime, db.DictStore["ex"].ExpireTime)
+	runCmd(e, "GETSET", "ex", "v")
+	assert.False(t, db.DictStore["ex"].WillExpire)
+
+	assert.Equal(t, "$1\r\nv\r\n", runCmd(e, "GETEX", "ex", "PX", "100000"))
+	assert.True(t, db.DictStore["ex"].WillExpire)
+	assert.Equal(t, "$1\r\nv\r\n", runCmd(e, "GETEX", "ex", "PERSIST"))
+	assert.False(t, db.DictStore["ex"].WillExpire)
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "GETEX", "ex", "EX", "10", "PERSIST"))
+	assert.Equal(t, "-ERR invalid expire time in 'getex' command\r\n", runCmd(e, "GETEX", "ex", "EXAT", "0"))
+	assert.Equal(t, "$1\r\nv\r\n", runCmd(e, "GETEX", "ex", "EXAT", "1"))
+	assert.NotContains(t, db.DictStore, "ex")
+
+	// Expired keys are treated as missing
+	runCmd(e, "SET", "old", strings.Repeat("a", 3), "EX", "100")
+	db.DictStore["old"].ExpireTime = time.Now().Add(-time.Second)
+	assert.Equal(t, ":0\r\n", runCmd(e, "STRLEN", "old"))
+	assert.Equal(t, "*1\r\n$-1\r\n", runCmd(e, "MGET", "old"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "APPEND", "old", "b"))
+	assert.False(t, db.DictStore["old"].WillExpire)
+}
+
+func TestSetCmdOptions(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := setCmdExecutor{}
+
+	assert.Equal(t, "$-1\r\n", runCmd(e, "SET", "k", "v1", "XX"))
+	assert.NotContains(t, db.DictStore, "k")
+	assert.Equal(t, "+OK\r\n", runCmd(e, "SET", "k", "v1", "NX"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "SET", "k", "v2", "NX"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "SET", "k", "v2", "XX"))
+
+	// GET replies with the old value even if the key is not set
+	assert.Equal(t, "$2\r\nv2\r\n", runCmd(e, "SET", "k", "v3", "GET"))
+	assert.Equal(t, "$2\r\nv3\r\n", runCmd(e, "SET", "k", "v4", "NX", "GET"))
+	assert.Equal(t, "$2\r\nv3\r\n", runCmd(e, "GET", "k"))
+	assert.Equal(t, "$-1\r\n", runCmd(e, "SET", "new", "v", "GET"))
+
+	// Overwriting discards the TTL unless KEEPTTL is provided
+	runCmd(e, "SET", "k", "v", "EX", "100")
+	expireTime := db.DictStore["k"].ExpireTime
+	runCmd(e, "SET", "k", "v", "KEEPTTL")
+	assert.True(t, db.DictStore["k"].WillExpire)
+	assert.Equal(t, expireTime, db.DictStore["k"].ExpireTime)
+	runCmd(e, "SET", "k", "v")
+	assert.False(t, db.DictStore["k"].WillExpire)
+
+	runCmd(e, "SET", "k", "v", "PXAT", "9999999999999")
+	assert.Equal(t, time.UnixMilli(9999999999999), db.DictStore["k"].ExpireTime)
+	runCmd(e, "SET", "k", "v", "EXAT", "1")
+	assert.Equal(t, "$-1\r\n", runCmd(e, "GET", "k"))
+
+	for _, args := range [][]string{
+		{"k", "v", "NX", "XX"},
+		{"k", "v", "EX", "10", "PX", "100"},
+		{"k", "v", "KEEPTTL", "EX", "10"},
+		{"k", "v", "EX", "10", "KEEPTTL"},
+		{"k", "v", "EX"},
+		{"k", "v", "UNKNOWN"},
+	} {
+		assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "SET", args...), args)
+	}
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", runCmd(e, "SET", "k", "v", "EX", "0"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", runCmd(e, "SET", "k", "v", "PX", "-1"))
+	assert.Equal(t, "-ERR value is not an integer or out of range\r\n", runCmd(e, "SET", "k", "v", "EX", "ten"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "SET", "k"))
+
+	// Deadlines that overflow milliseconds since the epoch are invalid
+	for _, args := range [][]string{
+		{"k", "v", "EX", "9223372036854775807"},
+		{"k", "v", "EX", "9223372036854776"},
+		{"k", "v", "PX", "9223372036854775807"},
+		{"k", "v", "EXAT", "9223372036854776"},
+	} {
+		assert.Equal(t, "-ERR invalid expire time in 'set' command\r\n", runCmd(e, "SET", args...), args)
+	}
+	assert.Equal(t, "-ERR invalid expire time in 'getex' command\r\n", runCmd(e, "GETEX", "k", "PX", "9223372036854775807"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "SET", "k", "v", "PXAT", "9223372036854775807"))
+	assert.Equal(t, "$1\r\nv\r\n", runCmd(e, "GET", "k"))
+}
@@ -1,83 +1,114 @@
 package cmdexec
 
 import (
-	"errors"
-	"fmt"
-	"math"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/stanleygy/toy-redis/app/algo"
 )
 
+const (
+	EncodingRaw = 0
+	EncodingInt = 1
+	// Bitmaps are stored as bytes, so that they are updated in place
+	EncodingBytes = 2
+)
+
+// Similar to Redis, strings that are canonical 64-bit integers are stored as integers
 type DictStoreValue struct {
+	Encoding   int
 	Value      string
+	IntValue   int64
+	Bytes      []byte
 	WillExpire bool
 	ExpireTime time.Time
 }
 
-type StreamID struct {
-	Ms  int64
-	Seq int
+func MakeDictStoreValue(val string) *DictStoreValue {
+	v := &DictStoreValue{}
+	v.SetString(val)
+	return v
 }
 
-func (s *StreamID) Incr() error {
-	// Increment the stream ID by one
-	if s.Seq == math.MaxInt {
-		return ErrOverflow
+func (v *DictStoreValue) SetString(val string) {
+	// Leading zeros, signs and spaces are not canonical, and the integer would be printed differently
+	intVal, err := strconv.ParseInt(val, 10, 64)
+	if err == nil && strconv.FormatInt(intVal, 10) == val {
+		v.SetInt(intVal)
+		return
 	}
-	s.Seq++
-	return nil
+	v.Encoding = EncodingRaw
+	v.Value = val
+	v.IntValue = 0
+	v.Bytes = nil
 }
 
-func (s StreamID) ToString() string {
-	return fmt.Sprintf("%d-%d", s.Ms, s.Seq)
+func (v *DictStoreValue) SetInt(val int64) {
+	v.Encoding = EncodingInt
+	v.Value = ""
+	v.IntValue = val
+	v.Bytes = nil
 }
 
-func ParseStreamID(id string) (*StreamID, error) {
-	var ms int64
-	var seq int
-	var err error
+func (v *DictStoreValue) SetBytes(val []byte) {
+	v.Encoding = EncodingBytes
+	v.Value = ""
+	v.IntValue = 0
+	v.Bytes = val
+}
 
-	parts := strings.Split(id, "-")
-	if len(parts) < 1 || len(parts) > 2 {
-		return nil, errors.New("failed to parse stream id")
+// GrowBytes converts the value to bytes, zero-extended to at least `size` bytes, and returns the bytes
+// to be updated in place
+func (v *DictStoreValue) GrowBytes(size int) []byte {
+	if v.Encoding != EncodingBytes {
+		v.SetBytes([]byte(v.String()))
 	}
-
-	ms, err = strconv.ParseInt(parts[0], 10, 64)
-	if len(parts) == 1 {
-		return &StreamID{Ms: ms}, err
+	if size > len(v.Bytes) {
+		v.Bytes = append(v.Bytes, make([]byte, size-len(v.Bytes))...)
 	}
-
-	seq, err = strconv.Atoi(parts[1])
-	return &StreamID{Ms: ms, Seq: seq}, err
-}
-
-type Stream struct {
-	Radix  *algo.RadixTree
-	LastId *StreamID
+	return v.Bytes
 }
 
-type GeoStoreValue struct {
-	Coord algo.GeoCoord
-	Hash  string
+func (v *DictStoreValue) String() string {
+	switch v.Encoding {
+	case EncodingInt:
+		return strconv.FormatInt(v.IntValue, 10)
+	case EncodingBytes:
+		return string(v.Bytes)
+	}
+	return v.Value
 }
 
 type RedisDb struct {
-	DictStore      map[string]*DictStoreValue
-	SortedSetStore map[string]*algo.SkipList
-	StreamStore    map[string]*Stream
-	GeoStore       map[string]map[string]*GeoStoreValue
+	DictStore       map[string]*DictStoreValue
+	SortedSetStore  map[string]*algo.SkipList
+	HashStore       map[string]map[string]string
+	StreamStore     map[string]*Stream
+	BloomStore      map[string]*algo.BloomFilter
+	CuckooStore     map[string]*algo.CuckooFilter
+	CmsStore        map[string]*algo.CountMinSketch
+	TopKStore       map[string]*algo.TopK
+	TimeSeriesStore map[string]*TimeSeries
+	JsonStore       map[string]*JsonValue
+	VectorSetStore  map[string]*algo.HNSW
+	SearchIndexes   map[string]*SearchIndex
 }
 
 var db *RedisDb
 
 func InitRedisDb() {
 	db = &RedisDb{
-		DictStore:      make(map[string]*DictStoreValue),
-		SortedSetStore: make(map[string]*algo.SkipList),
-		StreamStore:    make(map[string]*Stream),
-		GeoStore:       make(map[string]map[string]*GeoStoreValue),
+		DictStore:       make(map[string]*DictStoreValue),
+		SortedSetStore:  make(map[string]*algo.SkipList),
+		HashStore:       make(map[string]map[string]string),
+		StreamStore:     make(map[string]*Stream),
+		BloomStore:      make(map[string]*algo.BloomFilter),
+		CuckooStore:     make(map[string]*algo.CuckooFilter),
+		CmsStore:        make(map[string]*algo.CountMinSketch),
+		TopKStore:       make(map[string]*algo.TopK),
+		TimeSeriesStore: make(map[string]*TimeSeries),
+		JsonStore:       make(map[string]*JsonValue),
+		VectorSetStore:  make(map[string]*algo.HNSW),
+		SearchIndexes:   make(map[string]*SearchIndex),
 	}
 }
@@ -0,0 +1,331 @@
+package cmdexec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+)
+
+const (
+	// Similar to Redis's `stream-node-max-entries` and `stream-node-max-bytes`
+	StreamNodeMaxEntries = 100
+	StreamNodeMaxBytes   = 4096
+)
+
+const (
+	streamEntryFlagSameFields = 1
+)
+
+type StreamID struct {
+	Ms  int64
+	Seq int
+}
+
+var (
+	StreamMinID = StreamID{Ms: 0, Seq: 0}
+	StreamMaxID = StreamID{Ms: math.MaxInt64, Seq: math.MaxInt}
+)
+
+func (s *StreamID) Incr() error {
+	// Increment the stream ID by one, and move on to the next millisecond when the
+	// sequence number is exhausted
+	if s.Seq == math.MaxInt {
+		if s.Ms == math.MaxInt64 {
+			return ErrOverflow
+		}
+		s.Ms++
+		s.Seq = 0
+		return nil
+	}
+	s.Seq++
+	return nil
+}
+
+func (s StreamID) ToString() string {
+	return fmt.Sprintf("%d-%d", s.Ms, s.Seq)
+}
+
+func (s StreamID) Compare(o StreamID) int {
+	if s.Ms != o.Ms {
+		if s.Ms < o.Ms {
+			return -1
+		}
+		return 1
+	}
+	if s.Seq != o.Seq {
+		if s.Seq < o.Seq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// Encode converts the stream ID into a 128-bit big-endian key, so that the lexical order
+// of keys in the radix tree matches the numerical order of stream IDs
+func (s StreamID) Encode() string {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(s.Ms))
+	binary.BigEndian.PutUint64(buf[8:], uint64(s.Seq))
+	return string(buf[:])
+}
+
+func DecodeStreamID(key string) StreamID {
+	return StreamID{
+		Ms:  int64(binary.BigEndian.Uint64([]byte(key[:8]))),
+		Seq: int(binary.BigEndian.Uint64([]byte(key[8:]))),
+	}
+}
+
+func ParseStreamID(id string) (*StreamID, error) {
+	var ms int64
+	var seq int
+	var err error
+
+	parts := strings.Split(id, "-")
+	if len(parts) < 1 || len(parts) > 2 {
+		return nil, errors.New("failed to parse stream id")
+	}
+
+	ms, err = strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) == 1 {
+		return &StreamID{Ms: ms}, err
+	}
+
+	seq, err = strconv.Atoi(parts[1])
+	return &StreamID{Ms: ms, Seq: seq}, err
+}
+
+// ParseStreamRangeID parses the start or the end of a range query. "-" and "+" stand for the
+// smallest and the largest possible IDs, and an ID without a sequence number covers all
+// entries within that millisecond.
+func ParseStreamRangeID(id string, isEnd bool) (*StreamID, error) {
+	if id == "-" {
+		return &StreamID{Ms: StreamMinID.Ms, Seq: StreamMinID.Seq}, nil
+	}
+	if id == "+" {
+		return &StreamID{Ms: StreamMaxID.Ms, Seq: StreamMaxID.Seq}, nil
+	}
+	sid, err := ParseStreamID(id)
+	if err != nil {
+		return nil, err
+	}
+	if isEnd && !strings.Contains(id, "-") {
+		sid.Seq = math.MaxInt
+	}
+	return sid, nil
+}
+
+type StreamEntry struct {
+	Id          StreamID
+	FieldValues []string
+}
+
+/*
+streamBlock packs consecutive stream entries into one listpack, and is keyed by the ID of
+its first entry (the master ID) in the stream's radix tree. Similar to Redis, each entry
+is laid out in the listpack as:
+
+	flags | ms-diff | seq-diff | value_1 | ... | value_N                            (same fields)
+	flags | ms-diff | seq-diff | num-fields | field_1 | value_1 | ... | value_N  (otherwise)
+
+IDs are stored as deltas to the master ID, and the field names are omitted when an entry
+has the same fields as the first entry of the block.
+*/
+type streamBlock struct {
+	MasterId     StreamID
+	MasterFields []string
+	NumEntries   int
+	Entries      *algo.ListPack
+}
+
+func makeStreamBlock(masterId StreamID, fieldValues []string) *streamBlock {
+	masterFields := make([]string, 0, len(fieldValues)/2)
+	for i := 0; i < len(fieldValues); i += 2 {
+		masterFields = append(masterFields, fieldValues[i])
+	}
+	return &streamBlock{
+		MasterId:     masterId,
+		MasterFields: masterFields,
+		Entries:      algo.MakeListPack(),
+	}
+}
+
+func (b *streamBlock) isFull() bool {
+	return b.NumEntries >= StreamNodeMaxEntries || b.Entries.Size() >= StreamNodeMaxBytes
+}
+
+func (b *streamBlock) hasSameFields(fieldValues []string) bool {
+	if len(fieldValues) != len(b.MasterFields)*2 {
+		return false
+	}
+	for i, field := range b.MasterFields {
+		if fieldValues[i*2] != field {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *streamBlock) add(id StreamID, fieldValues []string) {
+	flags := 0
+	if b.hasSameFields(fieldValues) {
+		flags |= streamEntryFlagSameFields
+	}
+
+	lp := b.Entries
+	lp.AppendInt(int64(flags))
+	lp.AppendInt(id.Ms - b.MasterId.Ms)
+	lp.AppendInt(int64(id.Seq - b.MasterId.Seq))
+
+	if flags&streamEntryFlagSameFields != 0 {
+		for i := 1; i < len(fieldValues); i += 2 {
+			lp.AppendString(fieldValues[i])
+		}
+	} else {
+		lp.AppendInt(int64(len(fieldValues) / 2))
+		for _, v := range fieldValues {
+			lp.AppendString(v)
+		}
+	}
+	b.NumEntries++
+}
+
+// forEach decodes entries with IDs from `start` in order, and stops as soon as `fn` returns false
+func (b *streamBlock) forEach(start StreamID, fn func(entry *StreamEntry) bool) bool {
+	it := b.Entries.Iterator()
+	for it.HasNext() {
+		flags := it.NextInt()
+		id := StreamID{
+			Ms:  b.MasterId.Ms + it.NextInt(),
+			Seq: b.MasterId.Seq + int(it.NextInt()),
+		}
+
+		numFields := len(b.MasterFields)
+		if flags&streamEntryFlagSameFields == 0 {
+			numFields = int(it.NextInt())
+		}
+
+		// Skip entries before `start` without decoding their fields and values
+		if id.Compare(start) < 0 {
+			if flags&streamEntryFlagSameFields != 0 {
+				for i := 0; i < numFields; i++ {
+					it.SkipString()
+				}
+			} else {
+				for i := 0; i < numFields*2; i++ {
+					it.SkipString()
+				}
+			}
+			continue
+		}
+
+		entry := &StreamEntry{Id: id, FieldValues: make([]string, 0, numFields*2)}
+		if flags&streamEntryFlagSameFields != 0 {
+			for _, field := range b.MasterFields {
+				entry.FieldValues = append(entry.FieldValues, field, it.NextString())
+			}
+		} else {
+			for i := 0; i < numFields*2; i++ {
+				entry.FieldValues = append(entry.FieldValues, it.NextString())
+			}
+		}
+
+		if !fn(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+type Stream struct {
+	Radix  *algo.RadixTree
+	LastId *StreamID
+	Length int
+
+	// The block that new entries are appended to
+	lastBlock *streamBlock
+}
+
+func MakeStream() *Stream {
+	return &Stream{
+		Radix: algo.MakeRadixTree(),
+		LastId: &StreamID{
+			Ms:  0,
+			Seq: 0,
+		},
+	}
+}
+
+// Add appends an entry to the end of the stream. The caller must make sure `id` is
+// greater than the IDs of all existing entries.
+func (s *Stream) Add(id StreamID, fieldValues []string) {
+	if s.lastBlock == nil || s.lastBlock.isFull() {
+		s.lastBlock = makeStreamBlock(id, fieldValues)
+		s.Radix.Insert(id.Encode(), s.lastBlock)
+	}
+	s.lastBlock.add(id, fieldValues)
+	s.Length++
+}
+
+// Range returns at most `count` entries with IDs between `start` and `end` (both inclusive)
+func (s *Stream) Range(start StreamID, end StreamID, count int) []*StreamEntry {
+	entries := make([]*StreamEntry, 0)
+	if count <= 0 || start.Compare(end) > 0 {
+		return entries
+	}
+
+	// The block holding `start` is keyed by a master ID that is smaller than or equal to `start`
+	startKey := start.Encode()
+	if floor := s.Radix.Floor(startKey); floor != nil {
+		startKey = floor.Id
+	}
+
+	// Every block has at least one entry, and the first block might not have any entry in range
+	limit := count
+	if limit < math.MaxInt {
+		limit++
+	}
+
+	for _, result := range s.Radix.SearchByRange(startKey, end.Encode(), limit) {
+		block := result.Node.Value.(*streamBlock)
+		done := !block.forEach(start, func(entry *StreamEntry) bool {
+			if entry.Id.Compare(end) > 0 {
+				return false
+			}
+			entries = append(entries, entry)
+			return len(entries) < count
+		})
+		if done {
+			break
+		}
+	}
+	return entries
+}
+
+func (s *Stream) First() *StreamEntry {
+	entries := s.Range(StreamMinID, StreamMaxID, 1)
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[0]
+}
+
+func (s *Stream) Last() *StreamEntry {
+	result := s.Radix.Last()
+	if result == nil {
+		return nil
+	}
+
+	var last *StreamEntry
+	result.Node.Value.(*streamBlock).forEach(StreamMinID, func(entry *StreamEntry) bool {
+		last = entry
+		return true
+	})
+	return last
+}
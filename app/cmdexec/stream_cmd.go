@@ -1,12 +1,12 @@
 package cmdexec
 
 import (
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/stanleygy/toy-redis/app/algo"
 	"github.com/stanleygy/toy-redis/app/resp"
 )
 
@@ -16,7 +16,7 @@ func (e streamCmdExecutor) generateStreamId(stream *Stream, id *string) error {
 	if *id == "*" {
 		// Generate a stream ID
 		unixMs := time.Now().UnixMilli()
-		if unixMs == stream.LastId.Ms {
+		if unixMs <= stream.LastId.Ms {
 			err := stream.LastId.Incr()
 			if err != nil {
 				return err
@@ -76,13 +76,7 @@ func (e streamCmdExecutor) executeXAddCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 	stream, found := db.StreamStore[key]
 	if !found {
 		// If stream key does not exist, create one
-		stream = &Stream{
-			Radix: algo.MakeRadixTree(),
-			LastId: &StreamID{
-				Ms:  0,
-				Seq: 0,
-			},
-		}
+		stream = MakeStream()
 		db.StreamStore[key] = stream
 	}
 
@@ -93,8 +87,7 @@ func (e streamCmdExecutor) executeXAddCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 		return
 	}
 
-	stream.Radix.Insert(id, fieldValues)
-	stream.Radix.Visualize()
+	stream.Add(*stream.LastId, fieldValues)
 	AddBulkStringReplyEvent(c, id)
 
 	NotifyBlockedClientsOnKeySpace(&BlockKey{Source: BlockOnStream, Key: key})
@@ -124,24 +117,25 @@ func (e streamCmdExecutor) parseXRangeCmdArgs(cmdArgs []*resp.RespValue, key *st
 	return nil
 }
 
-func (e streamCmdExecutor) generateSearchResultsReplyEvent(c *ClientInfo, searchResults []*algo.RadixSearchResult) {
-	resps := make([]*resp.RespValue, len(searchResults))
+func (e streamCmdExecutor) makeStreamEntry(entry *StreamEntry) *resp.RespValue {
+	values := make([]*resp.RespValue, len(entry.FieldValues))
+	for j, v := range entry.FieldValues {
+		values[j] = resp.MakeBulkString(v)
+	}
 
-	for i, result := range searchResults {
-		fieldValues := result.Node.Value.([]string)
-		values := make([]*resp.RespValue, len(fieldValues))
-		for j, v := range fieldValues {
-			values[j] = resp.MakeBulkString(v)
-		}
+	return &resp.RespValue{
+		DataType: resp.TypeArrays,
+		Array: []*resp.RespValue{
+			resp.MakeBulkString(entry.Id.ToString()),   // stream id
+			{DataType: resp.TypeArrays, Array: values}, // field and values
+		},
+	}
+}
 
-		entry := &resp.RespValue{
-			DataType: resp.TypeArrays,
-			Array: []*resp.RespValue{
-				resp.MakeBulkString(result.Id),             // stream id
-				{DataType: resp.TypeArrays, Array: values}, // field and values
-			},
-		}
-		resps[i] = entry
+func (e streamCmdExecutor) generateSearchResultsReplyEvent(c *ClientInfo, entries []*StreamEntry) {
+	resps := make([]*resp.RespValue, len(entries))
+	for i, entry := range entries {
+		resps[i] = e.makeStreamEntry(entry)
 	}
 	AddArrayReplyEvent(c, resps)
 }
@@ -168,44 +162,51 @@ func (e streamCmdExecutor) executeXRangeCmd(c *ClientInfo, cmdArgs []*resp.RespV
 	}
 
 	// Perform the search
-	if start == "-" {
-		// Ascii value lower than all valid stream Ids
-		start = "0"
+	startId, err := ParseStreamRangeID(start, false)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
 	}
-	if end == "+" {
-		// Ascii value greater than all valid stream Ids
-		end = ":"
+	endId, err := ParseStreamRangeID(end, true)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
 	}
-	searchResults := stream.Radix.SearchByRange(start, end, count)
-	e.generateSearchResultsReplyEvent(c, searchResults)
+	entries := stream.Range(*startId, *endId, count)
+	e.generateSearchResultsReplyEvent(c, entries)
 }
 
 /*
 Syntax: XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key...] id [id...]
 Reply:
-  - Array reply: a list of stream entries with IDs matching the specified range
+  - Array reply: a list of [key, entries] pairs, one for each stream that has entries after the provided ID
+  - Null reply: no stream has any entries after the provided IDs, or the client times out
 */
 func (e streamCmdExecutor) parseXReadCmdArgs(cmdArgs []*resp.RespValue, count *int, timeout *int, keys *[]string, ids *[]string) error {
 	var err error
 	i := 0
 
 	// Parse options
-	for ; i < len(cmdArgs) && cmdArgs[i].BulkStr != "STREAMS"; i++ {
+	for ; i < len(cmdArgs) && strings.ToUpper(cmdArgs[i].BulkStr) != "STREAMS"; i++ {
 		option := strings.ToUpper(cmdArgs[i].BulkStr)
-		if option == "COUNT" || option == "BLOCK" {
-			if i+1 == len(cmdArgs) {
-				return ErrInvalidArgs
-			}
-			if option == "COUNT" {
-				*count, err = strconv.Atoi(cmdArgs[i+1].BulkStr)
-			} else {
-				*timeout, err = strconv.Atoi(cmdArgs[i+1].BulkStr)
-			}
-			if err != nil {
-				return err
+		if option != "COUNT" && option != "BLOCK" {
+			return ErrInvalidArgs
+		}
+		if i+1 == len(cmdArgs) {
+			return ErrInvalidArgs
+		}
+		if option == "COUNT" {
+			*count, err = strconv.Atoi(cmdArgs[i+1].BulkStr)
+		} else {
+			*timeout, err = strconv.Atoi(cmdArgs[i+1].BulkStr)
+			if err == nil && *timeout < 0 {
+				err = ErrInvalidArgs
 			}
-			i++
 		}
+		if err != nil {
+			return err
+		}
+		i++
 	}
 	if i >= len(cmdArgs) {
 		return ErrInvalidArgs
@@ -217,7 +218,7 @@ func (e streamCmdExecutor) parseXReadCmdArgs(cmdArgs []*resp.RespValue, count *i
 	for ; i < len(cmdArgs); i++ {
 		keysAndIds = append(keysAndIds, cmdArgs[i].BulkStr)
 	}
-	if len(keysAndIds)%2 != 0 {
+	if len(keysAndIds) == 0 || len(keysAndIds)%2 != 0 {
 		return ErrInvalidArgs
 	}
 	*keys = keysAndIds[:len(keysAndIds)/2]
@@ -238,36 +239,59 @@ func (e streamCmdExecutor) executeXReadCmd(c *ClientInfo, cmdArgs []*resp.RespVa
 		return
 	}
 
-	var searchResults []*algo.RadixSearchResult
-	for i := 0; i < len(keys); i++ {
-		key := keys[i]
-		startId := startIds[i]
-
-		// Loop up the stream at key
-		stream, found := db.StreamStore[key]
-		if !found {
-			AddEmptyArrayReplyEvent(c)
-			return
+	// The start id is exclusive, so incr the start id to make it inclusive for the search
+	sids := make([]*StreamID, len(keys))
+	for i, key := range keys {
+		if startIds[i] == "$" {
+			// "$" reads entries added after the last entry. Rewrite it in the client's request
+			// to the actual last ID, so that a blocked client does not skip entries added
+			// before it gets reprocessed.
+			lastId := StreamMinID
+			if stream, found := db.StreamStore[key]; found {
+				lastId = *stream.LastId
+			}
+			startIds[i] = lastId.ToString()
+			cmdArgs[len(cmdArgs)-len(keys)+i].BulkStr = startIds[i]
 		}
 
-		// The start id is exclusive, so incr the start id to make it inclusive for the search
-		sid, err := ParseStreamID(startId)
+		sids[i], err = ParseStreamID(startIds[i])
 		if err != nil {
 			AddErrorReplyEvent(c, err)
 			return
 		}
-		err = sid.Incr()
+		err = sids[i].Incr()
 		if err != nil {
 			AddErrorReplyEvent(c, err)
 			return
 		}
-		searchResults = append(searchResults, stream.Radix.SearchByRange(sid.ToString(), ":", count)...)
 	}
 
-	if len(searchResults) == 0 {
+	// Streams that do not exist are treated as empty streams, and COUNT applies to each stream
+	res := make([]*resp.RespValue, 0)
+	for i, key := range keys {
+		stream, found := db.StreamStore[key]
+		if !found {
+			continue
+		}
+		entries := stream.Range(*sids[i], StreamMaxID, count)
+		if len(entries) == 0 {
+			continue
+		}
+
+		values := make([]*resp.RespValue, len(entries))
+		for j, entry := range entries {
+			values[j] = e.makeStreamEntry(entry)
+		}
+		res = append(res, resp.MakeArray([]*resp.RespValue{
+			resp.MakeBulkString(key),
+			resp.MakeArray(values),
+		}))
+	}
+
+	if len(res) == 0 {
 		if timeout != -1 {
 			// If no results are returned, and client specify block option,
-			// Block the client until a key space occurs or client times out
+			// Block the client until any of its keys receives data or client times out
 			var bkeys []*BlockKey
 			for _, key := range keys {
 				bkeys = append(bkeys, &BlockKey{Source: BlockOnStream, Key: key})
@@ -275,13 +299,151 @@ func (e streamCmdExecutor) executeXReadCmd(c *ClientInfo, cmdArgs []*resp.RespVa
 			BlockClientForKeys(c, bkeys, timeout)
 		} else {
 			// Immediately reply to client
-			AddEmptyArrayReplyEvent(c)
+			AddNullArrayReplyEvent(c)
 		}
 		return
 	}
 
 	UnblockClient(c)
-	e.generateSearchResultsReplyEvent(c, searchResults)
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax:
+  - XINFO STREAM key [FULL [COUNT count]]
+  - XINFO GROUPS key
+  - XINFO CONSUMERS key group
+
+Reply:
+  - Array reply: a list of alternating field names and values
+*/
+func (e streamCmdExecutor) parseXInfoCmdArgs(cmdArgs []*resp.RespValue, subCmd *string, key *string, group *string, fullFlag *bool, count *int) error {
+	if len(cmdArgs) < 2 {
+		return ErrInvalidArgs
+	}
+	var err error
+
+	*subCmd = strings.ToUpper(cmdArgs[0].BulkStr)
+	*key = cmdArgs[1].BulkStr
+
+	switch *subCmd {
+	case "STREAM":
+		if len(cmdArgs) == 2 {
+			return nil
+		}
+		if strings.ToUpper(cmdArgs[2].BulkStr) != "FULL" {
+			return ErrInvalidArgs
+		}
+		*fullFlag = true
+
+		if len(cmdArgs) == 3 {
+			return nil
+		}
+		if len(cmdArgs) != 5 || strings.ToUpper(cmdArgs[3].BulkStr) != "COUNT" {
+			return ErrInvalidArgs
+		}
+		*count, err = strconv.Atoi(cmdArgs[4].BulkStr)
+		if err != nil {
+			return err
+		}
+		if *count < 0 {
+			return ErrInvalidArgs
+		}
+	case "GROUPS":
+		if len(cmdArgs) != 2 {
+			return ErrInvalidArgs
+		}
+	case "CONSUMERS":
+		if len(cmdArgs) != 3 {
+			return ErrInvalidArgs
+		}
+		*group = cmdArgs[2].BulkStr
+	default:
+		return ErrInvalidArgs
+	}
+	return nil
+}
+
+func (e streamCmdExecutor) makeStreamInfo(stream *Stream, fullFlag bool, count int) []*resp.RespValue {
+	res := []*resp.RespValue{
+		resp.MakeBulkString("length"),
+		resp.MakeInt(stream.Length),
+		resp.MakeBulkString("radix-tree-keys"),
+		resp.MakeInt(stream.Radix.NumElems),
+		resp.MakeBulkString("radix-tree-nodes"),
+		resp.MakeInt(stream.Radix.NumNodes()),
+		resp.MakeBulkString("last-generated-id"),
+		resp.MakeBulkString(stream.LastId.ToString()),
+	}
+
+	if fullFlag {
+		// COUNT 0 means all entries are returned
+		if count == 0 {
+			count = math.MaxInt
+		}
+		entries := make([]*resp.RespValue, 0)
+		for _, entry := range stream.Range(StreamMinID, StreamMaxID, count) {
+			entries = append(entries, e.makeStreamEntry(entry))
+		}
+
+		// Consumer groups are not supported yet, so a stream never has any groups or PELs
+		return append(res,
+			resp.MakeBulkString("entries"),
+			resp.MakeArray(entries),
+			resp.MakeBulkString("groups"),
+			resp.MakeArray([]*resp.RespValue{}),
+		)
+	}
+
+	res = append(res,
+		resp.MakeBulkString("groups"),
+		resp.MakeInt(0),
+		resp.MakeBulkString("first-entry"),
+	)
+	if first := stream.First(); first != nil {
+		res = append(res, e.makeStreamEntry(first))
+	} else {
+		res = append(res, resp.MakeNilBulkString())
+	}
+
+	res = append(res, resp.MakeBulkString("last-entry"))
+	if last := stream.Last(); last != nil {
+		res = append(res, e.makeStreamEntry(last))
+	} else {
+		res = append(res, resp.MakeNilBulkString())
+	}
+	return res
+}
+
+func (e streamCmdExecutor) executeXInfoCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		subCmd   string
+		key      string
+		group    string
+		fullFlag bool
+		count    int = 10
+	)
+	err := e.parseXInfoCmdArgs(cmdArgs, &subCmd, &key, &group, &fullFlag, &count)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	// Loop up the stream at key
+	stream, found := db.StreamStore[key]
+	if !found {
+		AddErrorReplyEvent(c, ErrNoSuchKey)
+		return
+	}
+
+	switch subCmd {
+	case "STREAM":
+		AddArrayReplyEvent(c, e.makeStreamInfo(stream, fullFlag, count))
+	case "GROUPS":
+		AddEmptyArrayReplyEvent(c)
+	case "CONSUMERS":
+		AddErrorReplyEvent(c, fmt.Errorf("NOGROUP No such consumer group '%s' for key name '%s'", group, key))
+	}
 }
 
 func (e streamCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
@@ -292,5 +454,7 @@ func (e streamCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*res
 		e.executeXRangeCmd(c, cmdArgs)
 	case "XREAD":
 		e.executeXReadCmd(c, cmdArgs)
+	case "XINFO":
+		e.executeXInfoCmd(c, cmdArgs)
 	}
 }
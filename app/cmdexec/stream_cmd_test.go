@@ -0,0 +1,138 @@
+package cmdexec
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stanleygy/toy-redis/app/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamEntryReply serializes an entry with one field like XRANGE and XREAD
+func streamEntryReply(id string, field string, value string) string {
+	return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(id), id, len(field), field, len(value), value)
+}
+
+func TestXReadCmd(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	MakeBlockList()
+	e := streamCmdExecutor{}
+
+	xadd := func(key string, field string, value string) string {
+		runCmd(e, "XADD", key, "*", field, value)
+		return EventBus[0].Resp.BulkStr
+	}
+	id1 := xadd("s1", "a", "1")
+	id2 := xadd("s1", "a", "2")
+	id3 := xadd("s2", "b", "1")
+
+	// Each stream with new entries gets a [key, entries] pair, and streams without any are skipped
+	assert.Equal(t, "*2\r\n"+
+		"*2\r\n$2\r\ns1\r\n*1\r\n"+streamEntryReply(id2, "a", "2")+
+		"*2\r\n$2\r\ns2\r\n*1\r\n"+streamEntryReply(id3, "b", "1"),
+		runCmd(e, "XREAD", "STREAMS", "s1", "s2", "missing", id1, "0-0", "0-0"))
+	assert.Equal(t, "*1\r\n*2\r\n$2\r\ns1\r\n*1\r\n"+streamEntryReply(id1, "a", "1"),
+		runCmd(e, "XREAD", "COUNT", "1", "STREAMS", "s1", "s2", "0-0", id3))
+	assert.Equal(t, "*-1\r\n", runCmd(e, "XREAD", "STREAMS", "s1", "s2", "$", "$"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "XREAD", "STREAMS", "s1", "s2", "0-0"))
+}
+
+func TestXReadCmdBlock(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	MakeBlockList()
+	e := streamCmdExecutor{}
+	c := &ClientInfo{ConnFd: 1}
+
+	xadd := func(key string, field string, value string) string {
+		runCmd(e, "XADD", key, "*", field, value)
+		return EventBus[0].Resp.BulkStr
+	}
+	block := func(args ...string) {
+		Reset()
+		c.ClientRequest = resp.MakeArray(makeCmdArgs(append([]string{"XREAD"}, args...)...))
+		Execute(c, c.ClientRequest)
+	}
+	id1 := xadd("s", "a", "1")
+
+	// Clients that time out get a nil reply
+	block("BLOCK", "1", "STREAMS", "s", "$")
+	assert.Equal(t, 0, len(EventBus))
+	assert.True(t, IsClientBlocked(c.ConnFd))
+	time.Sleep(5 * time.Millisecond)
+	HandleBlockedClientsTimeout()
+	assert.Equal(t, 1, len(EventBus))
+	assert.Equal(t, "*-1\r\n", string(EventBus[0].Resp.ToByteArray()))
+	assert.False(t, IsClientBlocked(c.ConnFd))
+
+	// "$" is resolved to the last ID when the client blocks, so that entries added before the client is
+	// reprocessed are not skipped
+	block("BLOCK", "0", "STREAMS", "s", "$")
+	assert.Equal(t, id1, c.ClientRequest.Array[len(c.ClientRequest.Array)-1].BulkStr)
+	id2 := xadd("s", "a", "2")
+	id3 := xadd("s", "a", "3")
+
+	// Blocked clients are woken by XADD to any of their keys
+	Reset()
+	ReprocessPendingClients()
+	assert.Equal(t, 1, len(EventBus))
+	assert.Equal(t, c, EventBus[0].Client)
+	assert.Equal(t, "*1\r\n*2\r\n$1\r\ns\r\n*2\r\n"+streamEntryReply(id2, "a", "2")+streamEntryReply(id3, "a", "3"),
+		string(EventBus[0].Resp.ToByteArray()))
+	assert.False(t, IsClientBlocked(c.ConnFd))
+
+	// Clients that disconnect are removed from the block lists, even if they are pending
+	block("BLOCK", "0", "STREAMS", "s", "$")
+	RemoveBlockedClient(c.ConnFd)
+	assert.False(t, IsClientBlocked(c.ConnFd))
+	assert.Empty(t, blockClientsOnKeySpace)
+	xadd("s", "a", "4")
+	assert.Empty(t, pendingClients)
+
+	block("BLOCK", "0", "STREAMS", "s", "$")
+	xadd("s", "a", "5")
+	RemoveBlockedClient(c.ConnFd)
+	assert.Empty(t, pendingClients)
+	assert.Empty(t, blockClientKeys)
+	Reset()
+}
+
+func TestXInfoCmd(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := streamCmdExecutor{}
+
+	runCmd(e, "XADD", "s", "*", "a", "1")
+	id := EventBus[0].Resp.BulkStr
+	entry := streamEntryReply(id, "a", "1")
+	idReply := fmt.Sprintf("$%d\r\n%s\r\n", len(id), id)
+
+	// The only entry is both the first and last entry, and streams never have consumer groups
+	assert.Equal(t, "*14\r\n"+
+		"$6\r\nlength\r\n:1\r\n"+
+		"$15\r\nradix-tree-keys\r\n:1\r\n"+
+		"$16\r\nradix-tree-nodes\r\n:2\r\n"+
+		"$17\r\nlast-generated-id\r\n"+idReply+
+		"$6\r\ngroups\r\n:0\r\n"+
+		"$11\r\nfirst-entry\r\n"+entry+
+		"$10\r\nlast-entry\r\n"+entry,
+		runCmd(e, "XINFO", "STREAM", "s"))
+	assert.Equal(t, "*12\r\n"+
+		"$6\r\nlength\r\n:1\r\n"+
+		"$15\r\nradix-tree-keys\r\n:1\r\n"+
+		"$16\r\nradix-tree-nodes\r\n:2\r\n"+
+		"$17\r\nlast-generated-id\r\n"+idReply+
+		"$7\r\nentries\r\n*1\r\n"+entry+
+		"$6\r\ngroups\r\n*0\r\n",
+		runCmd(e, "XINFO", "STREAM", "s", "FULL"))
+
+	assert.Equal(t, "*0\r\n", runCmd(e, "XINFO", "GROUPS", "s"))
+	assert.Equal(t, "-NOGROUP No such consumer group 'g' for key name 's'\r\n", runCmd(e, "XINFO", "CONSUMERS", "s", "g"))
+
+	assert.Equal(t, "-ERR no such key\r\n", runCmd(e, "XINFO", "STREAM", "missing"))
+	assert.Equal(t, "-ERR no such key\r\n", runCmd(e, "XINFO", "GROUPS", "missing"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "XINFO", "STREAM", "s", "FULL", "COUNT", "-1"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "XINFO", "HELP", "s"))
+}
@@ -0,0 +1,154 @@
+package cmdexec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamAddRange(t *testing.T) {
+	s := MakeStream()
+
+	// Millisecond values with different numbers of digits are still ordered correctly
+	ids := []StreamID{{Ms: 5, Seq: 0}, {Ms: 999, Seq: 0}, {Ms: 999, Seq: 1}, {Ms: 1000, Seq: 0}, {Ms: 10000, Seq: 7}}
+	for i, id := range ids {
+		s.Add(id, []string{"field", strconv.Itoa(i)})
+	}
+	s.Add(StreamID{Ms: 10001, Seq: 0}, []string{"other", "x", "field", "y"})
+
+	entries := s.Range(StreamMinID, StreamMaxID, math.MaxInt)
+	assert.Equal(t, 6, len(entries))
+	for i, id := range ids {
+		assert.Equal(t, id, entries[i].Id)
+		assert.Equal(t, []string{"field", strconv.Itoa(i)}, entries[i].FieldValues)
+	}
+	assert.Equal(t, []string{"other", "x", "field", "y"}, entries[5].FieldValues)
+
+	entries = s.Range(StreamID{Ms: 999, Seq: 1}, StreamID{Ms: 10000, Seq: 7}, 2)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, StreamID{Ms: 999, Seq: 1}, entries[0].Id)
+	assert.Equal(t, StreamID{Ms: 1000, Seq: 0}, entries[1].Id)
+
+	assert.Equal(t, ids[0], s.First().Id)
+	assert.Equal(t, StreamID{Ms: 10001, Seq: 0}, s.Last().Id)
+}
+
+func TestStreamRangeAcrossBlocks(t *testing.T) {
+	s := MakeStream()
+	numEntries := StreamNodeMaxEntries*5 + 3
+	for i := 0; i < numEntries; i++ {
+		s.Add(StreamID{Ms: int64(i / 3), Seq: i % 3}, []string{"i", strconv.Itoa(i)})
+	}
+	assert.Equal(t, numEntries, s.Length)
+	assert.Equal(t, 6, s.Radix.NumElems)
+
+	for i := 0; i < 50; i++ {
+		start := rand.Intn(numEntries)
+		count := rand.Intn(3*StreamNodeMaxEntries) + 1
+
+		entries := s.Range(StreamID{Ms: int64(start / 3), Seq: start % 3}, StreamMaxID, count)
+		assert.Equal(t, min(count, numEntries-start), len(entries))
+		for j, entry := range entries {
+			assert.Equal(t, strconv.Itoa(start+j), entry.FieldValues[1])
+		}
+	}
+}
+
+// legacyStream keys every entry by its decimal ID, and stores field values as a slice of strings
+type legacyStream struct {
+	Radix *algo.RadixTree
+}
+
+func (s *legacyStream) Add(id StreamID, fieldValues []string) {
+	s.Radix.Insert(id.ToString(), fieldValues)
+}
+
+func makeBenchmarkEntry(i int) (StreamID, []string) {
+	id := StreamID{Ms: 1700000000000 + int64(i/4), Seq: i % 4}
+	return id, []string{"sensor", "temperature", "value", strconv.Itoa(20 + i%10), "unit", "celsius"}
+}
+
+func measureHeapBytes(build func() any) (uint64, any) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	return after.HeapAlloc - before.HeapAlloc, v
+}
+
+func BenchmarkStreamMemory(b *testing.B) {
+	numEntries := 100000
+
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bytes, s := measureHeapBytes(func() any {
+				s := &legacyStream{Radix: algo.MakeRadixTree()}
+				for j := 0; j < numEntries; j++ {
+					s.Add(makeBenchmarkEntry(j))
+				}
+				return s
+			})
+			b.ReportMetric(float64(bytes)/float64(numEntries), "B/entry")
+			runtime.KeepAlive(s)
+		}
+	})
+
+	b.Run("compact", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bytes, s := measureHeapBytes(func() any {
+				s := MakeStream()
+				for j := 0; j < numEntries; j++ {
+					s.Add(makeBenchmarkEntry(j))
+				}
+				return s
+			})
+			b.ReportMetric(float64(bytes)/float64(numEntries), "B/entry")
+			runtime.KeepAlive(s)
+		}
+	})
+}
+
+func BenchmarkStreamRange(b *testing.B) {
+	// Both layouts build the same XRANGE reply, so the benchmark includes the cost of
+	// decoding entries from blocks in the compact layout
+	e := streamCmdExecutor{}
+	numEntries := 100000
+	legacy := &legacyStream{Radix: algo.MakeRadixTree()}
+	compact := MakeStream()
+	for i := 0; i < numEntries; i++ {
+		legacy.Add(makeBenchmarkEntry(i))
+		compact.Add(makeBenchmarkEntry(i))
+	}
+
+	for _, count := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("legacy/count=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				start, _ := makeBenchmarkEntry(rand.Intn(numEntries - count))
+				results := legacy.Radix.SearchByRange(start.ToString(), ":", count)
+				reply := make([]*resp.RespValue, len(results))
+				for j, r := range results {
+					reply[j] = e.makeStreamEntry(&StreamEntry{Id: start, FieldValues: r.Node.Value.([]string)})
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("compact/count=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				start, _ := makeBenchmarkEntry(rand.Intn(numEntries - count))
+				entries := compact.Range(start, StreamMaxID, count)
+				reply := make([]*resp.RespValue, len(entries))
+				for j, entry := range entries {
+					reply[j] = e.makeStreamEntry(entry)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package cmdexec
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+)
+
+// Similar to RedisTimeSeries's `CHUNK_SIZE`
+const TimeSeriesChunkMaxBytes = 4096
+
+const (
+	DuplicatePolicyBlock = "BLOCK"
+	DuplicatePolicyFirst = "FIRST"
+	DuplicatePolicyLast  = "LAST"
+	DuplicatePolicyMin   = "MIN"
+	DuplicatePolicyMax   = "MAX"
+	DuplicatePolicySum   = "SUM"
+)
+
+const (
+	AggregatorAvg   = "AVG"
+	AggregatorSum   = "SUM"
+	AggregatorMin   = "MIN"
+	AggregatorMax   = "MAX"
+	AggregatorCount = "COUNT"
+)
+
+var (
+	ErrTsOlderThanRetention = errors.New("ERR TSDB: Timestamp is older than retention")
+	ErrTsDuplicateBlocked   = errors.New("ERR TSDB: Error at upsert, update is not supported when DUPLICATE_POLICY is set to BLOCK mode")
+)
+
+type TimeSeriesLabel struct {
+	Name  string
+	Value string
+}
+
+// compactionRule downsamples the samples of a series into `DestKey`. A bucket is added to the
+// destination once a sample of a later bucket arrives, since more samples might still arrive.
+type compactionRule struct {
+	DestKey        string
+	Aggregator     string
+	BucketDuration int64
+
+	// The start of the latest bucket, which is still open
+	bucketStart int64
+	hasBucket   bool
+}
+
+// bucketOf returns the start of the bucket holding `ts`. Buckets are aligned to timestamp 0.
+func bucketOf(ts int64, bucketDuration int64) int64 {
+	r := ts % bucketDuration
+	if r < 0 {
+		r += bucketDuration
+	}
+	return ts - r
+}
+
+type aggregation struct {
+	Aggregator string
+	Count      int
+	Sum        float64
+	Min        float64
+	Max        float64
+}
+
+func makeAggregation(aggregator string) *aggregation {
+	return &aggregation{Aggregator: aggregator, Min: math.Inf(1), Max: math.Inf(-1)}
+}
+
+func (a *aggregation) add(v float64) {
+	a.Count++
+	a.Sum += v
+	a.Min = min(a.Min, v)
+	a.Max = max(a.Max, v)
+}
+
+func (a *aggregation) result() float64 {
+	switch a.Aggregator {
+	case AggregatorAvg:
+		return a.Sum / float64(a.Count)
+	case AggregatorSum:
+		return a.Sum
+	case AggregatorMin:
+		return a.Min
+	case AggregatorMax:
+		return a.Max
+	default:
+		return float64(a.Count)
+	}
+}
+
+// Aggregate groups samples ordered by timestamps into buckets, and returns a sample per non-empty
+// bucket, which is timestamped by the start of the bucket
+func Aggregate(samples []algo.Sample, aggregator string, bucketDuration int64) []algo.Sample {
+	res := make([]algo.Sample, 0)
+	var agg *aggregation
+	var start int64
+
+	for _, s := range samples {
+		bucket := bucketOf(s.Timestamp, bucketDuration)
+		if agg != nil && bucket != start {
+			res = append(res, algo.Sample{Timestamp: start, Value: agg.result()})
+			agg = nil
+		}
+		if agg == nil {
+			agg = makeAggregation(aggregator)
+			start = bucket
+		}
+		agg.add(s.Value)
+	}
+	if agg != nil {
+		res = append(res, algo.Sample{Timestamp: start, Value: agg.result()})
+	}
+	return res
+}
+
+/*
+TimeSeries stores samples ordered by timestamps in Gorilla-compressed chunks, which are sorted
+by their first timestamps. Samples are usually appended to the last chunk. Updating or inserting
+an earlier sample decompresses its chunk, and compresses the samples again.
+*/
+type TimeSeries struct {
+	Chunks          []*algo.GorillaChunk
+	NumSamples      int
+	Retention       int64
+	DuplicatePolicy string
+	Labels          []TimeSeriesLabel
+	Rules           []*compactionRule
+
+	// The key that is downsampled into this series, if any
+	SrcKey string
+}
+
+func MakeTimeSeries(retention int64, duplicatePolicy string, labels []TimeSeriesLabel) *TimeSeries {
+	return &TimeSeries{
+		Chunks:          make([]*algo.GorillaChunk, 0),
+		Retention:       retention,
+		DuplicatePolicy: duplicatePolicy,
+		Labels:          labels,
+	}
+}
+
+func (s *TimeSeries) Last() (algo.Sample, bool) {
+	if s.NumSamples == 0 {
+		return algo.Sample{}, false
+	}
+	last := s.Chunks[len(s.Chunks)-1]
+	return algo.Sample{Timestamp: last.LastTimestamp, Value: last.LastValue()}, true
+}
+
+// minTimestamp returns the smallest timestamp within the retention period
+func (s *TimeSeries) minTimestamp() int64 {
+	if s.Retention == 0 || s.NumSamples == 0 {
+		return math.MinInt64
+	}
+	return s.Chunks[len(s.Chunks)-1].LastTimestamp - s.Retention
+}
+
+func (s *TimeSeries) Label(name string) (string, bool) {
+	for _, l := range s.Labels {
+		if l.Name == name {
+			return l.Value, true
+		}
+	}
+	return "", false
+}
+
+// Add adds a sample, and resolves a sample with the same timestamp by `policy`
+func (s *TimeSeries) Add(ts int64, v float64, policy string) error {
+	if ts < s.minTimestamp() {
+		return ErrTsOlderThanRetention
+	}
+
+	if s.NumSamples == 0 || ts > s.Chunks[len(s.Chunks)-1].LastTimestamp {
+		var last *algo.GorillaChunk
+		if len(s.Chunks) > 0 {
+			last = s.Chunks[len(s.Chunks)-1]
+		}
+		if last == nil || last.Size() >= TimeSeriesChunkMaxBytes {
+			last = algo.MakeGorillaChunk()
+			s.Chunks = append(s.Chunks, last)
+		}
+		last.Append(ts, v)
+		s.NumSamples++
+		s.trim()
+		return nil
+	}
+	return s.upsert(ts, v, policy)
+}
+
+func (s *TimeSeries) upsert(ts int64, v float64, policy string) error {
+	// The chunk holding `ts` is the last chunk starting at or before `ts`
+	i := sort.Search(len(s.Chunks), func(i int) bool { return s.Chunks[i].FirstTimestamp > ts })
+	i = max(i-1, 0)
+
+	samples := s.Chunks[i].Samples()
+	j := sort.Search(len(samples), func(j int) bool { return samples[j].Timestamp >= ts })
+	if j < len(samples) && samples[j].Timestamp == ts {
+		old := &samples[j].Value
+		switch policy {
+		case DuplicatePolicyBlock:
+			return ErrTsDuplicateBlocked
+		case DuplicatePolicyFirst:
+			return nil
+		case DuplicatePolicyLast:
+			*old = v
+		case DuplicatePolicyMin:
+			*old = min(*old, v)
+		case DuplicatePolicyMax:
+			*old = max(*old, v)
+		case DuplicatePolicySum:
+			*old += v
+		}
+	} else {
+		samples = append(samples[:j], append([]algo.Sample{{Timestamp: ts, Value: v}}, samples[j:]...)...)
+		s.NumSamples++
+	}
+
+	// Compress the samples again, which might not fit in one chunk anymore
+	chunks := []*algo.GorillaChunk{algo.MakeGorillaChunk()}
+	for _, sample := range samples {
+		if chunks[len(chunks)-1].Size() >= TimeSeriesChunkMaxBytes {
+			chunks = append(chunks, algo.MakeGorillaChunk())
+		}
+		chunks[len(chunks)-1].Append(sample.Timestamp, sample.Value)
+	}
+	s.Chunks = append(s.Chunks[:i], append(chunks, s.Chunks[i+1:]...)...)
+	return nil
+}
+
+// trim drops the chunks whose samples are all older than the retention period
+func (s *TimeSeries) trim() {
+	minTs := s.minTimestamp()
+	n := 0
+	for n < len(s.Chunks)-1 && s.Chunks[n].LastTimestamp < minTs {
+		s.NumSamples -= s.Chunks[n].NumSamples
+		n++
+	}
+	s.Chunks = s.Chunks[n:]
+}
+
+// Range returns the samples with timestamps between `from` and `to` (both inclusive), excluding
+// samples older than the retention period
+func (s *TimeSeries) Range(from int64, to int64) []algo.Sample {
+	from = max(from, s.minTimestamp())
+	samples := make([]algo.Sample, 0)
+
+	for _, c := range s.Chunks {
+		if c.LastTimestamp < from {
+			continue
+		}
+		if c.FirstTimestamp > to {
+			break
+		}
+		c.ForEach(func(sample algo.Sample) bool {
+			if sample.Timestamp > to {
+				return false
+			}
+			if sample.Timestamp >= from {
+				samples = append(samples, sample)
+			}
+			return true
+		})
+	}
+	return samples
+}
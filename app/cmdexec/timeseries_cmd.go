@@ -0,0 +1,658 @@
+package cmdexec
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+var (
+	ErrTsKeyExists       = errors.New("ERR TSDB: key already exists")
+	ErrTsKeyNotFound     = errors.New("ERR TSDB: the key does not exist")
+	ErrTsTimestamp       = errors.New("ERR TSDB: invalid timestamp")
+	ErrTsValue           = errors.New("ERR TSDB: invalid value")
+	ErrTsRetention       = errors.New("ERR TSDB: Couldn't parse RETENTION")
+	ErrTsDuplicatePolicy = errors.New("ERR TSDB: Unknown DUPLICATE_POLICY")
+	ErrTsLabels          = errors.New("ERR TSDB: Couldn't parse LABELS")
+	ErrTsCount           = errors.New("ERR TSDB: Couldn't parse COUNT")
+	ErrTsAggregator      = errors.New("ERR TSDB: Unknown aggregation type")
+	ErrTsBucketDuration  = errors.New("ERR TSDB: bucketDuration must be greater than zero")
+	ErrTsFilter          = errors.New("ERR TSDB: failed parsing labels")
+	ErrTsMissingFilter   = errors.New("ERR TSDB: missing FILTER argument")
+	ErrTsNoMatcher       = errors.New("ERR TSDB: please provide at least one matcher")
+	ErrTsSameKey         = errors.New("ERR TSDB: the source key and destination key should be different")
+	ErrTsSrcHasSrcRule   = errors.New("ERR TSDB: the source key already has a source rule")
+	ErrTsDestHasSrcRule  = errors.New("ERR TSDB: the destination key already has a src rule")
+	ErrTsDestHasDestRule = errors.New("ERR TSDB: the destination key already has a dst rule")
+	ErrTsRuleNotFound    = errors.New("ERR TSDB: compaction rule does not exist")
+)
+
+type tsCmdExecutor struct{}
+
+type tsCreateOptions struct {
+	Retention       int64
+	DuplicatePolicy string
+	OnDuplicate     string
+	Labels          []TimeSeriesLabel
+}
+
+type tsRangeOptions struct {
+	Count          int
+	Aggregator     string
+	BucketDuration int64
+	WithLabels     bool
+	Matchers       []*tsLabelMatcher
+}
+
+// tsLabelMatcher matches series by a label, where an empty value matches series without the label
+type tsLabelMatcher struct {
+	Name   string
+	Values []string
+	Negate bool
+}
+
+func (m *tsLabelMatcher) match(s *TimeSeries) bool {
+	value, _ := s.Label(m.Name)
+	return slices.Contains(m.Values, value) != m.Negate
+}
+
+func parseTsTimestamp(arg string) (int64, error) {
+	if arg == "*" {
+		return time.Now().UnixMilli(), nil
+	}
+	ts, err := parseInt(arg)
+	if err != nil || ts < 0 {
+		return 0, ErrTsTimestamp
+	}
+	return ts, nil
+}
+
+func parseTsRangeTimestamp(arg string) (int64, error) {
+	switch arg {
+	case "-":
+		return 0, nil
+	case "+":
+		return math.MaxInt64, nil
+	}
+	return parseTsTimestamp(arg)
+}
+
+func parseTsValue(arg string) (float64, error) {
+	v, err := parseFloat(arg)
+	if err != nil {
+		return 0, ErrTsValue
+	}
+	return v, nil
+}
+
+func parseDuplicatePolicy(arg string) (string, error) {
+	policy := strings.ToUpper(arg)
+	switch policy {
+	case DuplicatePolicyBlock, DuplicatePolicyFirst, DuplicatePolicyLast, DuplicatePolicyMin, DuplicatePolicyMax, DuplicatePolicySum:
+		return policy, nil
+	}
+	return "", ErrTsDuplicatePolicy
+}
+
+func parseAggregation(cmdArgs []*resp.RespValue, aggregator *string, bucketDuration *int64) error {
+	if len(cmdArgs) < 2 {
+		return ErrSyntax
+	}
+	*aggregator = strings.ToUpper(cmdArgs[0].BulkStr)
+	switch *aggregator {
+	case AggregatorAvg, AggregatorSum, AggregatorMin, AggregatorMax, AggregatorCount:
+	default:
+		return ErrTsAggregator
+	}
+	var err error
+	*bucketDuration, err = parseInt(cmdArgs[1].BulkStr)
+	if err != nil || *bucketDuration <= 0 {
+		return ErrTsBucketDuration
+	}
+	return nil
+}
+
+// parseLabelMatcher parses `label=value`, `label!=value`, `label=(value1,value2,...)` or `label!=(value1,value2,...)`
+func parseLabelMatcher(arg string) (*tsLabelMatcher, error) {
+	i := strings.Index(arg, "=")
+	if i <= 0 {
+		return nil, ErrTsFilter
+	}
+	m := &tsLabelMatcher{Name: arg[:i]}
+	if strings.HasSuffix(m.Name, "!") {
+		m.Name = m.Name[:len(m.Name)-1]
+		m.Negate = true
+	}
+	if m.Name == "" {
+		return nil, ErrTsFilter
+	}
+
+	value := arg[i+1:]
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		m.Values = strings.Split(value[1:len(value)-1], ",")
+	} else {
+		m.Values = []string{value}
+	}
+	return m, nil
+}
+
+/*
+Options:
+  - RETENTION retentionPeriod: the maximum age of samples compared to the latest sample in milliseconds, where 0 keeps all samples
+  - DUPLICATE_POLICY policy: how to resolve samples with the same timestamp, one of BLOCK, FIRST, LAST, MIN, MAX or SUM
+  - ON_DUPLICATE policy: overrides the duplicate policy of the series for one sample, only for TS.ADD
+  - LABELS label value [label value ...]: must be the last option
+*/
+func (e tsCmdExecutor) parseTsCreateOptions(cmdArgs []*resp.RespValue, allowOnDuplicate bool, opts *tsCreateOptions) error {
+	opts.DuplicatePolicy = DuplicatePolicyBlock
+	opts.Labels = make([]TimeSeriesLabel, 0)
+
+	for i := 0; i < len(cmdArgs); i++ {
+		option := strings.ToUpper(cmdArgs[i].BulkStr)
+		if option == "LABELS" {
+			rest := cmdArgs[i+1:]
+			if len(rest)%2 != 0 {
+				return ErrTsLabels
+			}
+			for j := 0; j < len(rest); j += 2 {
+				opts.Labels = append(opts.Labels, TimeSeriesLabel{Name: rest[j].BulkStr, Value: rest[j+1].BulkStr})
+			}
+			return nil
+		}
+
+		if i+1 >= len(cmdArgs) {
+			return ErrSyntax
+		}
+		arg := cmdArgs[i+1].BulkStr
+		var err error
+
+		switch {
+		case option == "RETENTION":
+			opts.Retention, err = parseInt(arg)
+			if err != nil || opts.Retention < 0 {
+				return ErrTsRetention
+			}
+		case option == "DUPLICATE_POLICY":
+			opts.DuplicatePolicy, err = parseDuplicatePolicy(arg)
+		case option == "ON_DUPLICATE" && allowOnDuplicate:
+			opts.OnDuplicate, err = parseDuplicatePolicy(arg)
+		default:
+			return ErrSyntax
+		}
+		if err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+/*
+Syntax: TS.CREATE key [RETENTION retentionPeriod] [DUPLICATE_POLICY policy] [LABELS label value [label value ...]]
+Reply:
+  - Simple string reply: OK
+*/
+func (e tsCmdExecutor) executeTsCreateCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	var opts tsCreateOptions
+	err := e.parseTsCreateOptions(cmdArgs[1:], false, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if _, found := db.TimeSeriesStore[key]; found {
+		AddErrorReplyEvent(c, ErrTsKeyExists)
+		return
+	}
+	db.TimeSeriesStore[key] = MakeTimeSeries(opts.Retention, opts.DuplicatePolicy, opts.Labels)
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+// compact aggregates the bucket starting at `bucketStart` into the destination of the rule
+func (e tsCmdExecutor) compact(series *TimeSeries, rule *compactionRule, bucketStart int64) {
+	dest, found := db.TimeSeriesStore[rule.DestKey]
+	if !found {
+		return
+	}
+	samples := series.Range(bucketStart, bucketStart+rule.BucketDuration-1)
+	for _, s := range Aggregate(samples, rule.Aggregator, rule.BucketDuration) {
+		// Buckets older than the retention period of the destination are dropped
+		_ = dest.Add(s.Timestamp, s.Value, DuplicatePolicyLast)
+	}
+}
+
+// addSample adds a sample to a series, and downsamples the buckets closed by the sample by the
+// compaction rules. A sample of an earlier bucket closes the bucket again, which is recomputed.
+func (e tsCmdExecutor) addSample(series *TimeSeries, ts int64, v float64, policy string) error {
+	err := series.Add(ts, v, policy)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range series.Rules {
+		bucket := bucketOf(ts, rule.BucketDuration)
+		if !rule.hasBucket {
+			rule.bucketStart, rule.hasBucket = bucket, true
+			continue
+		}
+		if bucket > rule.bucketStart {
+			e.compact(series, rule, rule.bucketStart)
+			rule.bucketStart = bucket
+		} else if bucket < rule.bucketStart {
+			e.compact(series, rule, bucket)
+		}
+	}
+	return nil
+}
+
+/*
+Syntax: TS.ADD key timestamp value [RETENTION retentionPeriod] [DUPLICATE_POLICY policy] [ON_DUPLICATE policy] [LABELS label value [label value ...]]
+Reply:
+  - Integer reply: the timestamp of the sample
+
+The timestamp is the current time in milliseconds if it is "*". The series is created with the options if it does not
+exist, and the options other than ON_DUPLICATE are ignored otherwise.
+*/
+func (e tsCmdExecutor) executeTsAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	key := cmdArgs[0].BulkStr
+
+	ts, err := parseTsTimestamp(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	v, err := parseTsValue(cmdArgs[2].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	var opts tsCreateOptions
+	err = e.parseTsCreateOptions(cmdArgs[3:], true, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	series, found := db.TimeSeriesStore[key]
+	if !found {
+		series = MakeTimeSeries(opts.Retention, opts.DuplicatePolicy, opts.Labels)
+		db.TimeSeriesStore[key] = series
+	}
+	policy := series.DuplicatePolicy
+	if opts.OnDuplicate != "" {
+		policy = opts.OnDuplicate
+	}
+
+	err = e.addSample(series, ts, v, policy)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	AddIntegerReplyEvent(c, int(ts))
+}
+
+/*
+Syntax: TS.MADD key timestamp value [key timestamp value ...]
+Reply:
+  - Array reply: a list of the timestamps of each sample, or errors if the samples cannot be added
+*/
+func (e tsCmdExecutor) executeTsMAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 3 || len(cmdArgs)%3 != 0 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)/3)
+	for i := 0; i < len(cmdArgs); i += 3 {
+		err := func() error {
+			series, found := db.TimeSeriesStore[cmdArgs[i].BulkStr]
+			if !found {
+				return ErrTsKeyNotFound
+			}
+			ts, err := parseTsTimestamp(cmdArgs[i+1].BulkStr)
+			if err != nil {
+				return err
+			}
+			v, err := parseTsValue(cmdArgs[i+2].BulkStr)
+			if err != nil {
+				return err
+			}
+			err = e.addSample(series, ts, v, series.DuplicatePolicy)
+			if err != nil {
+				return err
+			}
+			res = append(res, resp.MakeInt(int(ts)))
+			return nil
+		}()
+		if err != nil {
+			res = append(res, resp.MakeErorr(err.Error()))
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func makeSampleReply(s algo.Sample) *resp.RespValue {
+	return resp.MakeArray([]*resp.RespValue{
+		resp.MakeInt(int(s.Timestamp)),
+		resp.MakeSimpleString(strconv.FormatFloat(s.Value, 'f', -1, 64)),
+	})
+}
+
+/*
+Syntax: TS.GET key
+Reply:
+  - Array reply: the timestamp and the value of the latest sample, or an empty array if the series is empty
+*/
+func (e tsCmdExecutor) executeTsGetCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	series, found := db.TimeSeriesStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrTsKeyNotFound)
+		return
+	}
+	last, ok := series.Last()
+	if !ok {
+		AddEmptyArrayReplyEvent(c)
+		return
+	}
+	AddReplyEvent(c, makeSampleReply(last))
+}
+
+/*
+Options:
+  - COUNT count: the maximum number of samples or buckets to return
+  - AGGREGATION aggregator bucketDuration: aggregates samples into buckets of `bucketDuration` milliseconds aligned
+    to timestamp 0 by one of AVG, SUM, MIN, MAX or COUNT
+  - WITHLABELS: returns the labels of each series, only for TS.MRANGE
+  - FILTER filter [filter ...]: selects series by labels, which must be the last option and is required by TS.MRANGE
+*/
+func (e tsCmdExecutor) parseTsRangeOptions(cmdArgs []*resp.RespValue, multi bool, opts *tsRangeOptions) error {
+	opts.Count = math.MaxInt
+
+	for i := 0; i < len(cmdArgs); i++ {
+		switch option := strings.ToUpper(cmdArgs[i].BulkStr); {
+		case option == "COUNT":
+			if i+1 >= len(cmdArgs) {
+				return ErrSyntax
+			}
+			count, err := parseInt(cmdArgs[i+1].BulkStr)
+			if err != nil || count < 0 {
+				return ErrTsCount
+			}
+			opts.Count = int(count)
+			i++
+		case option == "AGGREGATION":
+			err := parseAggregation(cmdArgs[i+1:], &opts.Aggregator, &opts.BucketDuration)
+			if err != nil {
+				return err
+			}
+			i += 2
+		case option == "WITHLABELS" && multi:
+			opts.WithLabels = true
+		case option == "FILTER" && multi:
+			for _, arg := range cmdArgs[i+1:] {
+				m, err := parseLabelMatcher(arg.BulkStr)
+				if err != nil {
+					return err
+				}
+				opts.Matchers = append(opts.Matchers, m)
+			}
+			return nil
+		default:
+			return ErrSyntax
+		}
+	}
+	if multi {
+		return ErrTsMissingFilter
+	}
+	return nil
+}
+
+func (e tsCmdExecutor) queryRange(series *TimeSeries, from int64, to int64, reverse bool, opts *tsRangeOptions) *resp.RespValue {
+	samples := series.Range(from, to)
+	if opts.Aggregator != "" {
+		samples = Aggregate(samples, opts.Aggregator, opts.BucketDuration)
+	}
+	if reverse {
+		slices.Reverse(samples)
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, s := range samples[:min(opts.Count, len(samples))] {
+		res = append(res, makeSampleReply(s))
+	}
+	return resp.MakeArray(res)
+}
+
+/*
+Syntax:
+  - TS.RANGE key fromTimestamp toTimestamp [COUNT count] [AGGREGATION aggregator bucketDuration]
+  - TS.REVRANGE key fromTimestamp toTimestamp [COUNT count] [AGGREGATION aggregator bucketDuration]
+
+Reply:
+  - Array reply: a list of the timestamps and the values of samples or buckets, in reverse order for TS.REVRANGE
+
+The timestamps are inclusive, and "-" and "+" stand for the smallest and the largest possible timestamps.
+*/
+func (e tsCmdExecutor) executeTsRangeCmd(c *ClientInfo, cmdArgs []*resp.RespValue, reverse bool) {
+	if len(cmdArgs) < 3 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	from, err := parseTsRangeTimestamp(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	to, err := parseTsRangeTimestamp(cmdArgs[2].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	var opts tsRangeOptions
+	err = e.parseTsRangeOptions(cmdArgs[3:], false, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	series, found := db.TimeSeriesStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrTsKeyNotFound)
+		return
+	}
+	AddReplyEvent(c, e.queryRange(series, from, to, reverse, &opts))
+}
+
+/*
+Syntax: TS.MRANGE fromTimestamp toTimestamp [WITHLABELS] [COUNT count] [AGGREGATION aggregator bucketDuration] FILTER filter [filter ...]
+Reply:
+  - Array reply: a list of the key, the labels and the samples of each matching series ordered by keys.
+    The labels are empty without WITHLABELS.
+
+Each filter is one of:
+  - label=value, label=(value1,value2,...): the label is one of the values
+  - label!=value, label!=(value1,value2,...): the label is none of the values or is missing
+  - label=: the label is missing
+  - label!=: the label exists
+
+At least one filter of the label=value form is required.
+*/
+func (e tsCmdExecutor) executeTsMRangeCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	from, err := parseTsRangeTimestamp(cmdArgs[0].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	to, err := parseTsRangeTimestamp(cmdArgs[1].BulkStr)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	var opts tsRangeOptions
+	err = e.parseTsRangeOptions(cmdArgs[2:], true, &opts)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if !slices.ContainsFunc(opts.Matchers, func(m *tsLabelMatcher) bool { return !m.Negate && !slices.Equal(m.Values, []string{""}) }) {
+		AddErrorReplyEvent(c, ErrTsNoMatcher)
+		return
+	}
+
+	keys := make([]string, 0)
+	for key, series := range db.TimeSeriesStore {
+		if !slices.ContainsFunc(opts.Matchers, func(m *tsLabelMatcher) bool { return !m.match(series) }) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	res := make([]*resp.RespValue, 0, len(keys))
+	for _, key := range keys {
+		series := db.TimeSeriesStore[key]
+		labels := make([]*resp.RespValue, 0)
+		if opts.WithLabels {
+			for _, l := range series.Labels {
+				labels = append(labels, resp.MakeArray([]*resp.RespValue{resp.MakeBulkString(l.Name), resp.MakeBulkString(l.Value)}))
+			}
+		}
+		res = append(res, resp.MakeArray([]*resp.RespValue{
+			resp.MakeBulkString(key),
+			resp.MakeArray(labels),
+			e.queryRange(series, from, to, false, &opts),
+		}))
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: TS.CREATERULE sourceKey destKey AGGREGATION aggregator bucketDuration
+Reply:
+  - Simple string reply: OK
+
+Samples added to the source afterwards are aggregated into buckets, and each bucket is added to the destination
+once it is closed by a sample of a later bucket. Both series must exist, and the destination can only have one
+source. Rules cannot be chained.
+*/
+func (e tsCmdExecutor) executeTsCreateRuleCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 5 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	srcKey, destKey := cmdArgs[0].BulkStr, cmdArgs[1].BulkStr
+	if strings.ToUpper(cmdArgs[2].BulkStr) != "AGGREGATION" {
+		AddErrorReplyEvent(c, ErrSyntax)
+		return
+	}
+	rule := &compactionRule{DestKey: destKey}
+	err := parseAggregation(cmdArgs[3:], &rule.Aggregator, &rule.BucketDuration)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	if srcKey == destKey {
+		AddErrorReplyEvent(c, ErrTsSameKey)
+		return
+	}
+	src, srcFound := db.TimeSeriesStore[srcKey]
+	dest, destFound := db.TimeSeriesStore[destKey]
+	if !srcFound || !destFound {
+		AddErrorReplyEvent(c, ErrTsKeyNotFound)
+		return
+	}
+	if src.SrcKey != "" {
+		AddErrorReplyEvent(c, ErrTsSrcHasSrcRule)
+		return
+	}
+	if dest.SrcKey != "" {
+		AddErrorReplyEvent(c, ErrTsDestHasSrcRule)
+		return
+	}
+	if len(dest.Rules) > 0 {
+		AddErrorReplyEvent(c, ErrTsDestHasDestRule)
+		return
+	}
+
+	src.Rules = append(src.Rules, rule)
+	dest.SrcKey = srcKey
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax: TS.DELETERULE sourceKey destKey
+Reply:
+  - Simple string reply: OK
+
+The samples already added to the destination are kept.
+*/
+func (e tsCmdExecutor) executeTsDeleteRuleCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	srcKey, destKey := cmdArgs[0].BulkStr, cmdArgs[1].BulkStr
+
+	src, found := db.TimeSeriesStore[srcKey]
+	if !found {
+		AddErrorReplyEvent(c, ErrTsKeyNotFound)
+		return
+	}
+	i := slices.IndexFunc(src.Rules, func(r *compactionRule) bool { return r.DestKey == destKey })
+	if i < 0 {
+		AddErrorReplyEvent(c, ErrTsRuleNotFound)
+		return
+	}
+	src.Rules = slices.Delete(src.Rules, i, i+1)
+	if dest, found := db.TimeSeriesStore[destKey]; found {
+		dest.SrcKey = ""
+	}
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+func (e tsCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "TS.CREATE":
+		e.executeTsCreateCmd(c, cmdArgs)
+	case "TS.ADD":
+		e.executeTsAddCmd(c, cmdArgs)
+	case "TS.MADD":
+		e.executeTsMAddCmd(c, cmdArgs)
+	case "TS.GET":
+		e.executeTsGetCmd(c, cmdArgs)
+	case "TS.RANGE":
+		e.executeTsRangeCmd(c, cmdArgs, false)
+	case "TS.REVRANGE":
+		e.executeTsRangeCmd(c, cmdArgs, true)
+	case "TS.MRANGE":
+		e.executeTsMRangeCmd(c, cmdArgs)
+	case "TS.CREATERULE":
+		e.executeTsCreateRuleCmd(c, cmdArgs)
+	case "TS.DELETERULE":
+		e.executeTsDeleteRuleCmd(c, cmdArgs)
+	}
+}
@@ -0,0 +1,87 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSeriesCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := tsCmdExecutor{}
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "TS.CREATE", "temp:1", "RETENTION", "0", "LABELS", "sensor", "temp", "room", "kitchen"))
+	assert.Equal(t, "-ERR TSDB: key already exists\r\n", runCmd(e, "TS.CREATE", "temp:1"))
+	assert.Equal(t, "*0\r\n", runCmd(e, "TS.GET", "temp:1"))
+	assert.Equal(t, ":1000\r\n", runCmd(e, "TS.ADD", "temp:1", "1000", "20.5"))
+	assert.Equal(t, "*3\r\n:2000\r\n:3000\r\n-ERR TSDB: the key does not exist\r\n",
+		runCmd(e, "TS.MADD", "temp:1", "2000", "21", "temp:1", "3000", "23.5", "missing", "1000", "1"))
+	assert.Equal(t, "*2\r\n:3000\r\n+23.5\r\n", runCmd(e, "TS.GET", "temp:1"))
+
+	assert.Equal(t, "-ERR TSDB: Error at upsert, update is not supported when DUPLICATE_POLICY is set to BLOCK mode\r\n",
+		runCmd(e, "TS.ADD", "temp:1", "2000", "30"))
+	assert.Equal(t, ":2000\r\n", runCmd(e, "TS.ADD", "temp:1", "2000", "22", "ON_DUPLICATE", "SUM"))
+
+	assert.Equal(t, "*3\r\n*2\r\n:1000\r\n+20.5\r\n*2\r\n:2000\r\n+43\r\n*2\r\n:3000\r\n+23.5\r\n", runCmd(e, "TS.RANGE", "temp:1", "-", "+"))
+	assert.Equal(t, "*2\r\n*2\r\n:2000\r\n+43\r\n*2\r\n:3000\r\n+23.5\r\n", runCmd(e, "TS.RANGE", "temp:1", "1500", "+"))
+	assert.Equal(t, "*1\r\n*2\r\n:3000\r\n+23.5\r\n", runCmd(e, "TS.REVRANGE", "temp:1", "-", "+", "COUNT", "1"))
+	assert.Equal(t, "*2\r\n*2\r\n:0\r\n+20.5\r\n*2\r\n:2000\r\n+33.25\r\n", runCmd(e, "TS.RANGE", "temp:1", "-", "+", "AGGREGATION", "avg", "2000"))
+	assert.Equal(t, "*2\r\n*2\r\n:2000\r\n+2\r\n*2\r\n:0\r\n+1\r\n", runCmd(e, "TS.REVRANGE", "temp:1", "-", "+", "AGGREGATION", "count", "2000"))
+
+	// TS.ADD creates the series with the options
+	assert.Equal(t, ":1000\r\n", runCmd(e, "TS.ADD", "temp:2", "1000", "15", "LABELS", "sensor", "temp", "room", "garage"))
+	assert.Equal(t, ":1000\r\n", runCmd(e, "TS.ADD", "humidity:1", "1000", "40", "LABELS", "sensor", "humidity", "room", "kitchen"))
+	assert.Equal(t, "*2\r\n"+
+		"*3\r\n$6\r\ntemp:1\r\n*0\r\n*1\r\n*2\r\n:0\r\n+43\r\n"+
+		"*3\r\n$6\r\ntemp:2\r\n*0\r\n*1\r\n*2\r\n:0\r\n+15\r\n",
+		runCmd(e, "TS.MRANGE", "-", "+", "AGGREGATION", "MAX", "10000", "FILTER", "sensor=temp"))
+	assert.Equal(t, "*1\r\n*3\r\n$10\r\nhumidity:1\r\n*2\r\n*2\r\n$6\r\nsensor\r\n$8\r\nhumidity\r\n*2\r\n$4\r\nroom\r\n$7\r\nkitchen\r\n*1\r\n*2\r\n:1000\r\n+40\r\n",
+		runCmd(e, "TS.MRANGE", "-", "+", "WITHLABELS", "FILTER", "room=(kitchen,garage)", "sensor!=temp"))
+	assert.Equal(t, "*0\r\n", runCmd(e, "TS.MRANGE", "-", "+", "FILTER", "sensor=temp", "room="))
+	assert.Equal(t, "-ERR TSDB: please provide at least one matcher\r\n", runCmd(e, "TS.MRANGE", "-", "+", "FILTER", "room!=garage"))
+	assert.Equal(t, "-ERR TSDB: missing FILTER argument\r\n", runCmd(e, "TS.MRANGE", "-", "+"))
+
+	assert.Equal(t, "-ERR TSDB: invalid timestamp\r\n", runCmd(e, "TS.ADD", "temp:1", "-1", "1"))
+	assert.Equal(t, "-ERR TSDB: invalid value\r\n", runCmd(e, "TS.ADD", "temp:1", "1", "warm"))
+	assert.Equal(t, "-ERR TSDB: Unknown DUPLICATE_POLICY\r\n", runCmd(e, "TS.CREATE", "new", "DUPLICATE_POLICY", "NEWEST"))
+	assert.Equal(t, "-ERR TSDB: Couldn't parse LABELS\r\n", runCmd(e, "TS.CREATE", "new", "LABELS", "room"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "TS.CREATE", "new", "ON_DUPLICATE", "LAST"))
+	assert.Equal(t, "-ERR TSDB: Unknown aggregation type\r\n", runCmd(e, "TS.RANGE", "temp:1", "-", "+", "AGGREGATION", "median", "10"))
+	assert.Equal(t, "-ERR TSDB: bucketDuration must be greater than zero\r\n", runCmd(e, "TS.RANGE", "temp:1", "-", "+", "AGGREGATION", "avg", "0"))
+}
+
+func TestTimeSeriesCompaction(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := tsCmdExecutor{}
+
+	runCmd(e, "TS.CREATE", "raw", "DUPLICATE_POLICY", "LAST")
+	runCmd(e, "TS.CREATE", "avg")
+	runCmd(e, "TS.CREATE", "other")
+	assert.Equal(t, "+OK\r\n", runCmd(e, "TS.CREATERULE", "raw", "avg", "AGGREGATION", "avg", "60"))
+	assert.Equal(t, "-ERR TSDB: the destination key already has a src rule\r\n", runCmd(e, "TS.CREATERULE", "other", "avg", "AGGREGATION", "sum", "60"))
+	assert.Equal(t, "-ERR TSDB: the source key already has a source rule\r\n", runCmd(e, "TS.CREATERULE", "avg", "other", "AGGREGATION", "sum", "60"))
+	assert.Equal(t, "-ERR TSDB: the destination key already has a dst rule\r\n", runCmd(e, "TS.CREATERULE", "other", "raw", "AGGREGATION", "sum", "60"))
+	assert.Equal(t, "-ERR TSDB: the source key and destination key should be different\r\n", runCmd(e, "TS.CREATERULE", "raw", "raw", "AGGREGATION", "sum", "60"))
+	assert.Equal(t, "-ERR TSDB: the key does not exist\r\n", runCmd(e, "TS.CREATERULE", "raw", "missing", "AGGREGATION", "sum", "60"))
+
+	runCmd(e, "TS.MADD", "raw", "0", "1", "raw", "30", "3", "raw", "59", "5")
+	// The bucket is still open
+	assert.Equal(t, "*0\r\n", runCmd(e, "TS.RANGE", "avg", "-", "+"))
+	runCmd(e, "TS.ADD", "raw", "61", "10")
+	assert.Equal(t, "*1\r\n*2\r\n:0\r\n+3\r\n", runCmd(e, "TS.RANGE", "avg", "-", "+"))
+
+	// Late samples update closed buckets
+	runCmd(e, "TS.ADD", "raw", "30", "7")
+	assert.Equal(t, "*1\r\n*2\r\n:0\r\n+4.333333333333333\r\n", runCmd(e, "TS.RANGE", "avg", "-", "+"))
+
+	runCmd(e, "TS.ADD", "raw", "200", "0")
+	assert.Equal(t, "*2\r\n*2\r\n:0\r\n+4.333333333333333\r\n*2\r\n:60\r\n+10\r\n", runCmd(e, "TS.RANGE", "avg", "-", "+"))
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "TS.DELETERULE", "raw", "avg"))
+	assert.Equal(t, "-ERR TSDB: compaction rule does not exist\r\n", runCmd(e, "TS.DELETERULE", "raw", "avg"))
+	runCmd(e, "TS.ADD", "raw", "300", "0")
+	assert.Equal(t, "*2\r\n*2\r\n:0\r\n+4.333333333333333\r\n*2\r\n:60\r\n+10\r\n", runCmd(e, "TS.RANGE", "avg", "-", "+"))
+	assert.Equal(t, "+OK\r\n", runCmd(e, "TS.CREATERULE", "other", "avg", "AGGREGATION", "sum", "60"))
+}
@@ -0,0 +1,83 @@
+package cmdexec
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSeriesAddRange(t *testing.T) {
+	s := MakeTimeSeries(0, DuplicatePolicyBlock, nil)
+
+	// Enough samples to fill several chunks
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, s.Add(int64(i*10), math.Sin(float64(i)), DuplicatePolicyBlock))
+	}
+	assert.Greater(t, len(s.Chunks), 1)
+	assert.Equal(t, 10000, s.NumSamples)
+
+	samples := s.Range(0, math.MaxInt64)
+	assert.Len(t, samples, 10000)
+	assert.Equal(t, algo.Sample{Timestamp: 99990, Value: math.Sin(9999)}, samples[9999])
+	assert.Equal(t, []algo.Sample{{Timestamp: 50, Value: math.Sin(5)}, {Timestamp: 60, Value: math.Sin(6)}}, s.Range(45, 60))
+
+	// Samples can be inserted in between
+	assert.NoError(t, s.Add(55, 1, DuplicatePolicyBlock))
+	assert.Equal(t, []algo.Sample{{Timestamp: 50, Value: math.Sin(5)}, {Timestamp: 55, Value: 1}, {Timestamp: 60, Value: math.Sin(6)}}, s.Range(45, 60))
+	assert.Equal(t, 10001, s.NumSamples)
+
+	last, ok := s.Last()
+	assert.True(t, ok)
+	assert.Equal(t, algo.Sample{Timestamp: 99990, Value: math.Sin(9999)}, last)
+}
+
+func TestTimeSeriesDuplicatePolicy(t *testing.T) {
+	s := MakeTimeSeries(0, DuplicatePolicyBlock, nil)
+	s.Add(1, 10, DuplicatePolicyBlock)
+	s.Add(2, 10, DuplicatePolicyBlock)
+
+	assert.Equal(t, ErrTsDuplicateBlocked, s.Add(1, 5, DuplicatePolicyBlock))
+	for _, tc := range []struct {
+		policy   string
+		v        float64
+		expected float64
+	}{
+		{DuplicatePolicyFirst, 5, 10},
+		{DuplicatePolicyMin, 5, 5},
+		{DuplicatePolicyMax, 7, 7},
+		{DuplicatePolicySum, 3, 10},
+		{DuplicatePolicyLast, 1, 1},
+	} {
+		assert.NoError(t, s.Add(1, tc.v, tc.policy))
+		assert.Equal(t, []algo.Sample{{Timestamp: 1, Value: tc.expected}}, s.Range(1, 1))
+	}
+	assert.Equal(t, 2, s.NumSamples)
+}
+
+func TestTimeSeriesRetention(t *testing.T) {
+	s := MakeTimeSeries(100, DuplicatePolicyLast, nil)
+	for i := 0; i < 5000; i++ {
+		s.Add(int64(i), float64(i), DuplicatePolicyLast)
+	}
+	assert.Equal(t, ErrTsOlderThanRetention, s.Add(4898, 0, DuplicatePolicyLast))
+	assert.NoError(t, s.Add(4899, 0, DuplicatePolicyLast))
+
+	// Old chunks are dropped, and samples older than the retention period are excluded
+	assert.Less(t, s.NumSamples, 5000)
+	samples := s.Range(0, math.MaxInt64)
+	assert.Len(t, samples, 101)
+	assert.Equal(t, algo.Sample{Timestamp: 4899, Value: 0}, samples[0])
+}
+
+func TestAggregate(t *testing.T) {
+	samples := []algo.Sample{{Timestamp: -5, Value: 1}, {Timestamp: 0, Value: 2}, {Timestamp: 3, Value: 6}, {Timestamp: 25, Value: 4}}
+
+	assert.Equal(t, []algo.Sample{{Timestamp: -10, Value: 1}, {Timestamp: 0, Value: 4}, {Timestamp: 20, Value: 4}}, Aggregate(samples, AggregatorAvg, 10))
+	assert.Equal(t, []algo.Sample{{Timestamp: -10, Value: 1}, {Timestamp: 0, Value: 8}, {Timestamp: 20, Value: 4}}, Aggregate(samples, AggregatorSum, 10))
+	assert.Equal(t, []algo.Sample{{Timestamp: -10, Value: 1}, {Timestamp: 0, Value: 2}, {Timestamp: 20, Value: 4}}, Aggregate(samples, AggregatorMin, 10))
+	assert.Equal(t, []algo.Sample{{Timestamp: -100, Value: 1}, {Timestamp: 0, Value: 6}}, Aggregate(samples, AggregatorMax, 100))
+	assert.Equal(t, []algo.Sample{{Timestamp: -100, Value: 1}, {Timestamp: 0, Value: 3}}, Aggregate(samples, AggregatorCount, 100))
+	assert.Empty(t, Aggregate(nil, AggregatorCount, 100))
+}
@@ -0,0 +1,215 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+// Same as RedisBloom, TOPK.RESERVE uses these parameters if they are not given
+const (
+	TopKDefaultWidth = 8
+	TopKDefaultDepth = 7
+	TopKDefaultDecay = 0.9
+	TopKMaxIncrement = 100000
+)
+
+var (
+	ErrTopKKeyExists   = errors.New("TopK: key already exists")
+	ErrTopKKeyNotFound = errors.New("TopK: key does not exist")
+	ErrTopKK           = errors.New("TopK: invalid k")
+	ErrTopKWidth       = errors.New("TopK: invalid width")
+	ErrTopKDepth       = errors.New("TopK: invalid depth")
+	ErrTopKDecay       = errors.New("TopK: invalid decay value. must be '<= 1' & '> 0'")
+	ErrTopKIncrement   = errors.New("TopK: increment must be an integer between 1 and 100000")
+	ErrTopKTooLarge    = errors.New("TopK: k or width*depth is too large")
+)
+
+type topkCmdExecutor struct{}
+
+/*
+Syntax: TOPK.RESERVE key topk [width depth decay]
+Reply:
+  - Simple string reply: OK
+*/
+func (e topkCmdExecutor) parseTopKReserveCmdArgs(cmdArgs []*resp.RespValue, key *string, k *int64, width *int64, depth *int64, decay *float64) error {
+	if len(cmdArgs) != 2 && len(cmdArgs) != 5 {
+		return ErrInvalidArgs
+	}
+	var err error
+	*key = cmdArgs[0].BulkStr
+
+	*k, err = parseInt(cmdArgs[1].BulkStr)
+	if err != nil || *k <= 0 {
+		return ErrTopKK
+	}
+
+	*width, *depth, *decay = TopKDefaultWidth, TopKDefaultDepth, TopKDefaultDecay
+	if len(cmdArgs) == 2 {
+		return nil
+	}
+	*width, err = parseInt(cmdArgs[2].BulkStr)
+	if err != nil || *width <= 0 {
+		return ErrTopKWidth
+	}
+	*depth, err = parseInt(cmdArgs[3].BulkStr)
+	if err != nil || *depth <= 0 {
+		return ErrTopKDepth
+	}
+	*decay, err = parseFloat(cmdArgs[4].BulkStr)
+	if err != nil || *decay <= 0 || *decay > 1 {
+		return ErrTopKDecay
+	}
+	return nil
+}
+
+func (e topkCmdExecutor) executeTopKReserveCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key   string
+		k     int64
+		width int64
+		depth int64
+		decay float64
+	)
+	err := e.parseTopKReserveCmdArgs(cmdArgs, &key, &k, &width, &depth, &decay)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+	if _, found := db.TopKStore[key]; found {
+		AddErrorReplyEvent(c, ErrTopKKeyExists)
+		return
+	}
+	if !algo.TopKFits(int(k), uint64(width), uint64(depth)) {
+		AddErrorReplyEvent(c, ErrTopKTooLarge)
+		return
+	}
+	db.TopKStore[key] = algo.MakeTopK(int(k), uint64(width), uint64(depth), decay)
+	AddSimpleStringReplyEvent(c, "OK")
+}
+
+/*
+Syntax:
+  - TOPK.ADD key item [item ...]
+  - TOPK.INCRBY key item increment [item increment ...]
+
+Reply:
+  - Array reply: a list of the items expelled from the top K by each item, or nil if no item is expelled
+*/
+func (e topkCmdExecutor) executeTopKIncrByCmd(c *ClientInfo, cmdArgs []*resp.RespValue, withIncrements bool) {
+	if len(cmdArgs) < 2 || (withIncrements && len(cmdArgs)%2 != 1) {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	topK, found := db.TopKStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrTopKKeyNotFound)
+		return
+	}
+
+	items := make([]string, 0, len(cmdArgs)-1)
+	incrs := make([]uint64, 0, len(cmdArgs)-1)
+	for i := 1; i < len(cmdArgs); i++ {
+		items = append(items, cmdArgs[i].BulkStr)
+		if !withIncrements {
+			incrs = append(incrs, 1)
+			continue
+		}
+		incr, err := parseInt(cmdArgs[i+1].BulkStr)
+		if err != nil || incr < 1 || incr > TopKMaxIncrement {
+			AddErrorReplyEvent(c, ErrTopKIncrement)
+			return
+		}
+		incrs = append(incrs, uint64(incr))
+		i++
+	}
+
+	res := make([]*resp.RespValue, 0, len(items))
+	for n, item := range items {
+		expelled, ok := topK.IncrBy([]byte(item), incrs[n])
+		if ok {
+			res = append(res, resp.MakeBulkString(expelled))
+		} else {
+			res = append(res, resp.MakeNilBulkString())
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: TOPK.QUERY key item [item ...]
+Reply:
+  - Array reply: a list of 1 if the item is in the top K, or 0 otherwise, for each item
+*/
+func (e topkCmdExecutor) executeTopKQueryCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	topK, found := db.TopKStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrTopKKeyNotFound)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(cmdArgs)-1)
+	for _, arg := range cmdArgs[1:] {
+		if topK.Contains([]byte(arg.BulkStr)) {
+			res = append(res, resp.MakeInt(1))
+		} else {
+			res = append(res, resp.MakeInt(0))
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: TOPK.LIST key [WITHCOUNT]
+Reply:
+  - Array reply: a list of the top K items from the heaviest, each followed by its estimated count if WITHCOUNT is given
+*/
+func (e topkCmdExecutor) executeTopKListCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 1 || len(cmdArgs) > 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	withCount := false
+	if len(cmdArgs) == 2 {
+		if strings.ToUpper(cmdArgs[1].BulkStr) != "WITHCOUNT" {
+			AddErrorReplyEvent(c, ErrSyntax)
+			return
+		}
+		withCount = true
+	}
+	topK, found := db.TopKStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrTopKKeyNotFound)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, item := range topK.List() {
+		res = append(res, resp.MakeBulkString(item.Item))
+		if withCount {
+			res = append(res, resp.MakeInt(int(item.Count)))
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func (e topkCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "TOPK.RESERVE":
+		e.executeTopKReserveCmd(c, cmdArgs)
+	case "TOPK.ADD":
+		e.executeTopKIncrByCmd(c, cmdArgs, false)
+	case "TOPK.INCRBY":
+		e.executeTopKIncrByCmd(c, cmdArgs, true)
+	case "TOPK.QUERY":
+		e.executeTopKQueryCmd(c, cmdArgs)
+	case "TOPK.LIST":
+		e.executeTopKListCmd(c, cmdArgs)
+	}
+}
@@ -0,0 +1,32 @@
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopKCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := topkCmdExecutor{}
+
+	assert.Equal(t, "+OK\r\n", runCmd(e, "TOPK.RESERVE", "paths", "2", "50", "5", "0.9"))
+	assert.Equal(t, "-TopK: key already exists\r\n", runCmd(e, "TOPK.RESERVE", "paths", "2"))
+	assert.Equal(t, "*3\r\n$-1\r\n$-1\r\n$-1\r\n", runCmd(e, "TOPK.ADD", "paths", "/a", "/b", "/a"))
+	assert.Equal(t, "*2\r\n$2\r\n/a\r\n$2\r\n/b\r\n", runCmd(e, "TOPK.LIST", "paths"))
+
+	// /c outweighs /b and expels it
+	assert.Equal(t, "*1\r\n$2\r\n/b\r\n", runCmd(e, "TOPK.INCRBY", "paths", "/c", "3"))
+	assert.Equal(t, "*4\r\n$2\r\n/c\r\n:3\r\n$2\r\n/a\r\n:2\r\n", runCmd(e, "TOPK.LIST", "paths", "WITHCOUNT"))
+	assert.Equal(t, "*3\r\n:1\r\n:0\r\n:1\r\n", runCmd(e, "TOPK.QUERY", "paths", "/a", "/b", "/c"))
+
+	assert.Equal(t, "-TopK: increment must be an integer between 1 and 100000\r\n", runCmd(e, "TOPK.INCRBY", "paths", "/d", "0"))
+	assert.Equal(t, "-TopK: key does not exist\r\n", runCmd(e, "TOPK.ADD", "missing", "/a"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "TOPK.LIST", "paths", "WITHCOUNTS"))
+	assert.Equal(t, "-TopK: invalid k\r\n", runCmd(e, "TOPK.RESERVE", "new", "0"))
+	assert.Equal(t, "-TopK: k or width*depth is too large\r\n", runCmd(e, "TOPK.RESERVE", "new", "9223372036854775807"))
+	assert.Equal(t, "-TopK: k or width*depth is too large\r\n", runCmd(e, "TOPK.RESERVE", "new", "10", "4611686018427387904", "4", "0.9"))
+	assert.Equal(t, "-TopK: invalid decay value. must be '<= 1' & '> 0'\r\n", runCmd(e, "TOPK.RESERVE", "new", "10", "8", "7", "1.5"))
+	assert.Equal(t, "-invalid args\r\n", runCmd(e, "TOPK.RESERVE", "new", "10", "8"))
+}
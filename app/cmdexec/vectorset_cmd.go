@@ -0,0 +1,387 @@
+package cmdexec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/stanleygy/toy-redis/app/algo"
+	"github.com/stanleygy/toy-redis/app/resp"
+)
+
+const (
+	VectorSetDefaultM       = 16
+	VectorSetDefaultBuildEf = 200
+	VectorSetDefaultEf      = 100
+	VectorSetDefaultCount   = 10
+
+	// Larger values are rejected, so that clients cannot make the server allocate huge buffers
+	VectorSetMaxM     = 1024
+	VectorSetMaxEf    = 1000000
+	VectorSetMaxCount = 1000000
+)
+
+var (
+	ErrVectorSetKeyNotFound     = errors.New("ERR key does not exist")
+	ErrVectorSetElementNotFound = errors.New("ERR element not found in set")
+	ErrVectorSetInvalidVector   = errors.New("ERR invalid vector specification")
+)
+
+var vectorMetrics = map[string]int{
+	"COSINE": algo.VectorMetricCosine,
+	"L2":     algo.VectorMetricL2,
+	"IP":     algo.VectorMetricIP,
+}
+
+type vectorSetCmdExecutor struct{}
+
+// parseVector parses `FP32 blob` or `VALUES num value [value ...]` and returns the number of arguments used
+func (e vectorSetCmdExecutor) parseVector(cmdArgs []*resp.RespValue) ([]float32, int, error) {
+	if len(cmdArgs) < 2 {
+		return nil, 0, ErrInvalidArgs
+	}
+	switch strings.ToUpper(cmdArgs[0].BulkStr) {
+	case "FP32":
+		// Little-endian float32 values
+		blob := []byte(cmdArgs[1].BulkStr)
+		if len(blob) == 0 || len(blob)%4 != 0 {
+			return nil, 0, ErrVectorSetInvalidVector
+		}
+		vector := make([]float32, len(blob)/4)
+		for i := range vector {
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[i*4:]))
+			if math.IsNaN(float64(vector[i])) || math.IsInf(float64(vector[i]), 0) {
+				return nil, 0, ErrVectorSetInvalidVector
+			}
+		}
+		return vector, 2, nil
+	case "VALUES":
+		n, err := parseInt(cmdArgs[1].BulkStr)
+		if err != nil || n < 1 || int(n) > len(cmdArgs)-2 {
+			return nil, 0, ErrVectorSetInvalidVector
+		}
+		vector := make([]float32, n)
+		for i := range vector {
+			f, err := parseFloat(cmdArgs[2+i].BulkStr)
+			if err != nil {
+				return nil, 0, ErrVectorSetInvalidVector
+			}
+			// Values beyond the range of float32 become infinities
+			vector[i] = float32(f)
+			if math.IsInf(float64(vector[i]), 0) {
+				return nil, 0, ErrVectorSetInvalidVector
+			}
+		}
+		return vector, 2 + int(n), nil
+	}
+	return nil, 0, ErrVectorSetInvalidVector
+}
+
+/*
+Syntax: VADD key (FP32 blob | VALUES num value [value ...]) element [METRIC COSINE | L2 | IP] [M numlinks] [EF build-exploration-factor]
+Reply:
+  - Integer reply: 1 if the element is added, or 0 if its vector is updated
+
+METRIC, M and EF only take effect when the key is created. Same as Redis, the default metric is COSINE.
+*/
+func (e vectorSetCmdExecutor) parseVAddCmdArgs(cmdArgs []*resp.RespValue, key *string, vector *[]float32, element *string, metric *int, m *int64, ef *int64) error {
+	if len(cmdArgs) < 4 {
+		return ErrInvalidArgs
+	}
+	*key = cmdArgs[0].BulkStr
+
+	var (
+		n   int
+		err error
+	)
+	*vector, n, err = e.parseVector(cmdArgs[1:])
+	if err != nil {
+		return err
+	}
+	if 1+n >= len(cmdArgs) {
+		return ErrInvalidArgs
+	}
+	*element = cmdArgs[1+n].BulkStr
+
+	*metric, *m, *ef = algo.VectorMetricCosine, VectorSetDefaultM, VectorSetDefaultBuildEf
+	args := cmdArgs[2+n:]
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return ErrSyntax
+		}
+		switch strings.ToUpper(args[i].BulkStr) {
+		case "METRIC":
+			var found bool
+			*metric, found = vectorMetrics[strings.ToUpper(args[i+1].BulkStr)]
+			if !found {
+				return ErrSyntax
+			}
+		case "M":
+			*m, err = parseInt(args[i+1].BulkStr)
+			if err != nil || *m < 2 || *m > VectorSetMaxM {
+				return errors.New("ERR invalid M")
+			}
+		case "EF":
+			*ef, err = parseInt(args[i+1].BulkStr)
+			if err != nil || *ef < 1 || *ef > VectorSetMaxEf {
+				return errors.New("ERR invalid EF")
+			}
+		default:
+			return ErrSyntax
+		}
+	}
+	return nil
+}
+
+func (e vectorSetCmdExecutor) executeVAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	var (
+		key     string
+		vector  []float32
+		element string
+		metric  int
+		m       int64
+		ef      int64
+	)
+	err := e.parseVAddCmdArgs(cmdArgs, &key, &vector, &element, &metric, &m, &ef)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	set, found := db.VectorSetStore[key]
+	if !found {
+		set = algo.MakeHNSW(len(vector), metric, int(m), int(ef))
+		db.VectorSetStore[key] = set
+	} else if set.Dim != len(vector) {
+		AddErrorReplyEvent(c, fmt.Errorf("ERR Vector dimension mismatch - got %d but set has %d", len(vector), set.Dim))
+		return
+	}
+
+	if set.Insert(element, vector) {
+		AddIntegerReplyEvent(c, 1)
+	} else {
+		AddIntegerReplyEvent(c, 0)
+	}
+}
+
+/*
+Syntax: VREM key element
+Reply:
+  - Integer reply: 1 if the element is removed, or 0 if it does not exist
+*/
+func (e vectorSetCmdExecutor) executeVRemCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	set, found := db.VectorSetStore[cmdArgs[0].BulkStr]
+	if !found || !set.Remove(cmdArgs[1].BulkStr) {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	if set.Len() == 0 {
+		delete(db.VectorSetStore, cmdArgs[0].BulkStr)
+	}
+	AddIntegerReplyEvent(c, 1)
+}
+
+/*
+Syntax: VSIM key (ELE element | FP32 blob | VALUES num value [value ...]) [WITHSCORES] [COUNT num] [EF search-exploration-factor] [TRUTH]
+Reply:
+  - Array reply: the most similar elements, from the most similar one, followed by their scores with WITHSCORES
+
+Scores are the cosine similarity mapped to [0, 1] for COSINE like Redis, the euclidean distance for L2, and the
+inner product for IP. TRUTH compares the query with all vectors for exact results instead of searching the HNSW graph.
+*/
+func (e vectorSetCmdExecutor) parseVSimCmdArgs(cmdArgs []*resp.RespValue, set *algo.HNSW, query *[]float32, withScores *bool, count *int64, ef *int64, truth *bool) error {
+	var (
+		n   int
+		err error
+	)
+	if strings.ToUpper(cmdArgs[1].BulkStr) == "ELE" {
+		if len(cmdArgs) < 3 {
+			return ErrInvalidArgs
+		}
+		var found bool
+		*query, found = set.Get(cmdArgs[2].BulkStr)
+		if !found {
+			return ErrVectorSetElementNotFound
+		}
+		n = 2
+	} else {
+		*query, n, err = e.parseVector(cmdArgs[1:])
+		if err != nil {
+			return err
+		}
+		if len(*query) != set.Dim {
+			return fmt.Errorf("ERR Vector dimension mismatch - got %d but set has %d", len(*query), set.Dim)
+		}
+	}
+
+	*count, *ef = VectorSetDefaultCount, VectorSetDefaultEf
+	args := cmdArgs[1+n:]
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i].BulkStr) {
+		case "WITHSCORES":
+			*withScores = true
+		case "TRUTH":
+			*truth = true
+		case "COUNT":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			*count, err = parseInt(args[i+1].BulkStr)
+			if err != nil || *count < 1 || *count > VectorSetMaxCount {
+				return errors.New("ERR invalid COUNT")
+			}
+			i++
+		case "EF":
+			if i+1 >= len(args) {
+				return ErrSyntax
+			}
+			*ef, err = parseInt(args[i+1].BulkStr)
+			if err != nil || *ef < 1 || *ef > VectorSetMaxEf {
+				return errors.New("ERR invalid EF")
+			}
+			i++
+		default:
+			return ErrSyntax
+		}
+	}
+	return nil
+}
+
+func (e vectorSetCmdExecutor) score(metric int, distance float64) float64 {
+	switch metric {
+	case algo.VectorMetricL2:
+		return math.Sqrt(distance)
+	case algo.VectorMetricIP:
+		return -distance
+	}
+	return 1 - distance/2
+}
+
+func (e vectorSetCmdExecutor) executeVSimCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) < 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	set, found := db.VectorSetStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddEmptyArrayReplyEvent(c)
+		return
+	}
+	var (
+		query      []float32
+		withScores bool
+		count      int64
+		ef         int64
+		truth      bool
+	)
+	err := e.parseVSimCmdArgs(cmdArgs, set, &query, &withScores, &count, &ef, &truth)
+	if err != nil {
+		AddErrorReplyEvent(c, err)
+		return
+	}
+
+	var results []algo.VectorResult
+	if truth {
+		results = set.SearchExact(query, int(count))
+	} else {
+		results = set.Search(query, int(count), int(ef))
+	}
+
+	res := make([]*resp.RespValue, 0)
+	for _, r := range results {
+		res = append(res, resp.MakeBulkString(r.Name))
+		if withScores {
+			res = append(res, resp.MakeBulkString(strconv.FormatFloat(e.score(set.Metric, r.Distance), 'f', -1, 64)))
+		}
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+/*
+Syntax: VCARD key
+Reply:
+  - Integer reply: the number of elements, or 0 if the key does not exist
+*/
+func (e vectorSetCmdExecutor) executeVCardCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	set, found := db.VectorSetStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddIntegerReplyEvent(c, 0)
+		return
+	}
+	AddIntegerReplyEvent(c, set.Len())
+}
+
+/*
+Syntax: VDIM key
+Reply:
+  - Integer reply: the dimension of the vectors
+*/
+func (e vectorSetCmdExecutor) executeVDimCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 1 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	set, found := db.VectorSetStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddErrorReplyEvent(c, ErrVectorSetKeyNotFound)
+		return
+	}
+	AddIntegerReplyEvent(c, set.Dim)
+}
+
+/*
+Syntax: VEMB key element
+Reply:
+  - Array reply: the vector of the element
+  - Nil reply: if the key or the element does not exist
+*/
+func (e vectorSetCmdExecutor) executeVEmbCmd(c *ClientInfo, cmdArgs []*resp.RespValue) {
+	if len(cmdArgs) != 2 {
+		AddErrorReplyEvent(c, ErrInvalidArgs)
+		return
+	}
+	set, found := db.VectorSetStore[cmdArgs[0].BulkStr]
+	if !found {
+		AddNullArrayReplyEvent(c)
+		return
+	}
+	vector, found := set.Get(cmdArgs[1].BulkStr)
+	if !found {
+		AddNullArrayReplyEvent(c)
+		return
+	}
+
+	res := make([]*resp.RespValue, 0, len(vector))
+	for _, x := range vector {
+		res = append(res, resp.MakeBulkString(strconv.FormatFloat(float64(x), 'f', -1, 32)))
+	}
+	AddArrayReplyEvent(c, res)
+}
+
+func (e vectorSetCmdExecutor) Execute(c *ClientInfo, cmdName string, cmdArgs []*resp.RespValue) {
+	switch cmdName {
+	case "VADD":
+		e.executeVAddCmd(c, cmdArgs)
+	case "VREM":
+		e.executeVRemCmd(c, cmdArgs)
+	case "VSIM":
+		e.executeVSimCmd(c, cmdArgs)
+	case "VCARD":
+		e.executeVCardCmd(c, cmdArgs)
+	case "VDIM":
+		e.executeVDimCmd(c, cmdArgs)
+	case "VEMB":
+		e.executeVEmbCmd(c, cmdArgs)
+	}
+}
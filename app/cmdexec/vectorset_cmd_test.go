@@ -0,0 +1,75 @@
+package cmdexec
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVectorSetCmds(t *testing.T) {
+	InitRedisDb()
+	MakeEventBus()
+	e := vectorSetCmdExecutor{}
+
+	bulk := func(s string) string {
+		return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+	}
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1", "0", "a"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1", "1", "b"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "0", "1", "c"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "-1", "0", "c"))
+
+	blob := make([]byte, 8)
+	binary.LittleEndian.PutUint32(blob, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(blob[4:], math.Float32bits(-0.25))
+	assert.Equal(t, ":1\r\n", runCmd(e, "VADD", "points", "FP32", string(blob), "d"))
+	assert.Equal(t, "*2\r\n"+bulk("0.5")+bulk("-0.25"), runCmd(e, "VEMB", "points", "d"))
+	assert.Equal(t, "*2\r\n"+bulk("-1")+bulk("0"), runCmd(e, "VEMB", "points", "c"))
+	assert.Equal(t, "*-1\r\n", runCmd(e, "VEMB", "points", "missing"))
+	assert.Equal(t, "*-1\r\n", runCmd(e, "VEMB", "missing", "a"))
+
+	assert.Equal(t, ":4\r\n", runCmd(e, "VCARD", "points"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "VCARD", "missing"))
+	assert.Equal(t, ":2\r\n", runCmd(e, "VDIM", "points"))
+	assert.Equal(t, "-ERR key does not exist\r\n", runCmd(e, "VDIM", "missing"))
+
+	// Cosine similarity is mapped to [0, 1]
+	assert.Equal(t, "*2\r\n"+bulk("c")+bulk("1"), runCmd(e, "VSIM", "points", "VALUES", "2", "-2", "0", "WITHSCORES", "COUNT", "1"))
+	assert.Equal(t, "*4\r\n"+bulk("a")+bulk("d")+bulk("b")+bulk("c"), runCmd(e, "VSIM", "points", "ELE", "a"))
+	assert.Equal(t, "*4\r\n"+bulk("a")+bulk("d")+bulk("b")+bulk("c"), runCmd(e, "VSIM", "points", "ELE", "a", "TRUTH"))
+	assert.Equal(t, "*2\r\n"+bulk("a")+bulk("1"), runCmd(e, "VSIM", "points", "VALUES", "2", "3", "0", "WITHSCORES", "COUNT", "1", "EF", "5"))
+	assert.Equal(t, "*0\r\n", runCmd(e, "VSIM", "missing", "ELE", "a"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "VREM", "points", "a"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "VREM", "points", "a"))
+	assert.Equal(t, "*1\r\n"+bulk("d"), runCmd(e, "VSIM", "points", "VALUES", "2", "1", "0.1", "COUNT", "1"))
+
+	// L2 scores are distances, and IP scores are inner products
+	runCmd(e, "VADD", "l2", "VALUES", "2", "0", "0", "origin", "METRIC", "L2")
+	runCmd(e, "VADD", "l2", "VALUES", "2", "3", "4", "far")
+	assert.Equal(t, "*4\r\n"+bulk("far")+bulk("0")+bulk("origin")+bulk("5"), runCmd(e, "VSIM", "l2", "ELE", "far", "WITHSCORES"))
+	runCmd(e, "VADD", "ip", "VALUES", "2", "1", "2", "x", "METRIC", "IP", "M", "4", "EF", "10")
+	runCmd(e, "VADD", "ip", "VALUES", "2", "3", "-1", "y")
+	assert.Equal(t, "*4\r\n"+bulk("y")+bulk("5")+bulk("x")+bulk("4"), runCmd(e, "VSIM", "ip", "VALUES", "2", "2", "1", "WITHSCORES"))
+
+	assert.Equal(t, ":1\r\n", runCmd(e, "VREM", "ip", "x"))
+	assert.Equal(t, ":1\r\n", runCmd(e, "VREM", "ip", "y"))
+	assert.Equal(t, ":0\r\n", runCmd(e, "VCARD", "ip"))
+
+	assert.Equal(t, "-ERR Vector dimension mismatch - got 3 but set has 2\r\n", runCmd(e, "VADD", "points", "VALUES", "3", "1", "2", "3", "e"))
+	assert.Equal(t, "-ERR Vector dimension mismatch - got 1 but set has 2\r\n", runCmd(e, "VSIM", "points", "VALUES", "1", "1"))
+	assert.Equal(t, "-ERR invalid vector specification\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1", "x", "e"))
+	assert.Equal(t, "-ERR invalid vector specification\r\n", runCmd(e, "VADD", "points", "FP32", "abc", "e"))
+	assert.Equal(t, "-ERR invalid vector specification\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1e39", "1", "e"))
+	assert.Equal(t, "-ERR invalid vector specification\r\n", runCmd(e, "VSIM", "points", "VALUES", "2", "-1e39", "1"))
+	assert.Equal(t, "-ERR element not found in set\r\n", runCmd(e, "VSIM", "points", "ELE", "missing"))
+	assert.Equal(t, "-ERR syntax error\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1", "2", "e", "METRIC", "HAMMING"))
+	assert.Equal(t, "-ERR invalid COUNT\r\n", runCmd(e, "VSIM", "points", "ELE", "b", "COUNT", "0"))
+	assert.Equal(t, "-ERR invalid COUNT\r\n", runCmd(e, "VSIM", "points", "VALUES", "2", "1", "0", "COUNT", "9223372036854775807"))
+	assert.Equal(t, "-ERR invalid EF\r\n", runCmd(e, "VSIM", "points", "VALUES", "2", "1", "0", "EF", "9223372036854775807"))
+	assert.Equal(t, "-ERR invalid M\r\n", runCmd(e, "VADD", "points", "VALUES", "2", "1", "2", "e", "M", "100000"))
+}
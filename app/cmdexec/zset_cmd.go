@@ -1,6 +1,7 @@
 package cmdexec
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -11,21 +12,41 @@ import (
 
 type zsetCmdExecutor struct{}
 
+// parseScore parses a score of `sortedSet`, which might not exist. Sorted sets with a score scale, like
+// the distances stored by STOREDIST, take fractional scores.
+func (e zsetCmdExecutor) parseScore(sortedSet *algo.SkipList, arg string) (int, error) {
+	if sortedSet == nil || sortedSet.ScoreScale == 0 {
+		return strconv.Atoi(arg)
+	}
+	f, err := parseFloat(arg)
+	if err != nil {
+		return 0, err
+	}
+	score := math.Round(f * float64(sortedSet.ScoreScale))
+	if score <= math.MinInt64 || score >= math.MaxInt64 {
+		return 0, ErrNotFloat
+	}
+	return int(score), nil
+}
+
+func (e zsetCmdExecutor) formatScore(sortedSet *algo.SkipList, score int) string {
+	if sortedSet.ScoreScale == 0 {
+		return strconv.Itoa(score)
+	}
+	return strconv.FormatFloat(float64(score)/float64(sortedSet.ScoreScale), 'f', -1, 64)
+}
+
 /*
  * syntax: ZADD key [NX] score member [score member ...]
  */
-func (e zsetCmdExecutor) parseZAddCmdArgs(cmdArgs []*resp.RespValue, key *string, members *[]string, scores *[]int, nxFlag *bool) error {
+func (e zsetCmdExecutor) parseZAddCmdArgs(cmdArgs []*resp.RespValue, key *string, members *[]string, scores *[]string, nxFlag *bool) error {
 	*key = cmdArgs[0].BulkStr
 
 	for i := 1; i < len(cmdArgs); i++ {
 		if cmdArgs[i].BulkStr == "NX" {
 			*nxFlag = true
 		} else {
-			score, err := strconv.Atoi(cmdArgs[i].BulkStr)
-			if err != nil {
-				return err
-			}
-			*scores = append(*scores, score)
+			*scores = append(*scores, cmdArgs[i].BulkStr)
 			*members = append(*members, cmdArgs[i+1].BulkStr)
 			i++
 		}
@@ -37,7 +58,7 @@ func (e zsetCmdExecutor) executeZAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue
 	var (
 		key     string
 		members []string = make([]string, 0)
-		scores  []int    = make([]int, 0)
+		scores  []string = make([]string, 0)
 		nxFlag  bool
 	)
 
@@ -47,8 +68,19 @@ func (e zsetCmdExecutor) executeZAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue
 		return
 	}
 
-	// If a new key is requested, a new skip list will be created
+	// Scores are parsed with the scale of the sorted set before any member is added
 	sortedSet, found := db.SortedSetStore[key]
+	parsedScores := make([]int, 0, len(scores))
+	for _, arg := range scores {
+		score, err := e.parseScore(sortedSet, arg)
+		if err != nil {
+			AddErrorReplyEvent(c, err)
+			return
+		}
+		parsedScores = append(parsedScores, score)
+	}
+
+	// If a new key is requested, a new skip list will be created
 	if !found {
 		db.SortedSetStore[key] = algo.MakeSkipList(time.Now().Unix())
 		sortedSet = db.SortedSetStore[key]
@@ -56,7 +88,7 @@ func (e zsetCmdExecutor) executeZAddCmd(c *ClientInfo, cmdArgs []*resp.RespValue
 
 	numAdded := 0
 	for i := 0; i < len(members); i++ {
-		if sortedSet.Add(members[i], scores[i], nxFlag) {
+		if sortedSet.Add(members[i], parsedScores[i], nxFlag) {
 			numAdded++
 		}
 	}
@@ -92,6 +124,7 @@ func (e zsetCmdExecutor) executeZRemCmd(c *ClientInfo, cmdArgs []*resp.RespValue
 	for i := 0; i < len(members); i++ {
 		if sortedSet.Remove(members[i]) {
 			numRemoved++
+			NotifyGeofencesOnRemove(key, members[i])
 		}
 	}
 	AddIntegerReplyEvent(c, numRemoved)
@@ -128,7 +161,7 @@ func (e zsetCmdExecutor) executeZScoreCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 	}
 
 	score := sortedSet.GetScore(member)
-	AddBulkStringReplyEvent(c, strconv.Itoa(score))
+	AddBulkStringReplyEvent(c, e.formatScore(sortedSet, score))
 }
 
 /*
@@ -142,12 +175,13 @@ func (e zsetCmdExecutor) parseZCountCmdArgs(cmdArgs []*resp.RespValue, key *stri
 	var err error
 
 	*key = cmdArgs[0].BulkStr
-	*min, err = strconv.Atoi(cmdArgs[1].BulkStr)
+	sortedSet := db.SortedSetStore[*key]
+	*min, err = e.parseScore(sortedSet, cmdArgs[1].BulkStr)
 	if err != nil {
 		return err
 	}
 
-	*max, err = strconv.Atoi(cmdArgs[2].BulkStr)
+	*max, err = e.parseScore(sortedSet, cmdArgs[2].BulkStr)
 	if err != nil {
 		return err
 	}
@@ -186,12 +220,13 @@ func (e zsetCmdExecutor) parseZRangeByScoreCmdArgs(cmdArgs []*resp.RespValue, ke
 	}
 	var err error
 	*key = cmdArgs[0].BulkStr
-	*min, err = strconv.Atoi(cmdArgs[1].BulkStr)
+	sortedSet := db.SortedSetStore[*key]
+	*min, err = e.parseScore(sortedSet, cmdArgs[1].BulkStr)
 	if err != nil {
 		return err
 	}
 
-	*max, err = strconv.Atoi(cmdArgs[2].BulkStr)
+	*max, err = e.parseScore(sortedSet, cmdArgs[2].BulkStr)
 	if err != nil {
 		return err
 	}
@@ -233,7 +268,7 @@ func (e zsetCmdExecutor) executeZRangeByScoreCmd(c *ClientInfo, cmdArgs []*resp.
 	for _, node := range nodes {
 		res = append(res, resp.MakeBulkString(node.Member))
 		if withScoresFlag {
-			res = append(res, resp.MakeBulkString(strconv.Itoa(node.Score)))
+			res = append(res, resp.MakeBulkString(e.formatScore(sortedSet, node.Score)))
 		}
 	}
 	AddArrayReplyEvent(c, res)
@@ -295,7 +330,7 @@ func (e zsetCmdExecutor) executeZRankCmd(c *ClientInfo, cmdArgs []*resp.RespValu
 	}
 	AddArrayReplyEvent(c, []*resp.RespValue{
 		resp.MakeInt(rank),
-		resp.MakeBulkString(strconv.Itoa(node.Score)),
+		resp.MakeBulkString(e.formatScore(sortedSet, node.Score)),
 	})
 }
 
@@ -361,7 +396,7 @@ func (e zsetCmdExecutor) executeZRangeCmd(c *ClientInfo, cmdArgs []*resp.RespVal
 	for _, node := range nodes {
 		res = append(res, resp.MakeBulkString(node.Member))
 		if withScoreFlag {
-			res = append(res, resp.MakeBulkString(strconv.Itoa(node.Score)))
+			res = append(res, resp.MakeBulkString(e.formatScore(sortedSet, node.Score)))
 		}
 	}
 	AddArrayReplyEvent(c, res)
@@ -0,0 +1,487 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownDirective = errors.New("unknown directive")
+	ErrWrongNumArgs     = errors.New("wrong number of arguments")
+	ErrUnbalanced       = errors.New("unbalanced quotes in configuration line")
+	ErrNotSupported     = errors.New("directive is not supported and is ignored")
+	ErrIncludeLoop      = errors.New("config file includes itself")
+)
+
+var LogLevels = []string{"debug", "verbose", "notice", "warning", "nothing"}
+
+/*
+Config is the configuration of the server. Persistence is not supported yet, but its paths are accepted so that
+existing redis.conf files can be loaded. Log levels other than "nothing" are accepted, but all messages are logged
+regardless of their levels.
+*/
+type Config struct {
+	Port           int
+	Bind           []string
+	TcpBacklog     int
+	MaxClients     int
+	Timeout        int
+	Hz             int
+	LogLevel       string
+	LogFile        string
+	Dir            string
+	DbFilename     string
+	AppendOnly     bool
+	AppendFilename string
+
+	// Warnings are the directives of Redis that are ignored, with their positions
+	Warnings []string
+	// includes are the config files being loaded, to detect include loops
+	includes []string
+}
+
+// Current is the effective configuration of the running server
+var Current = Default()
+
+// Default returns the configuration used without a config file. Same as Redis except for `tcp-backlog`
+// and `hz`, which keep the listen backlog of 1024 and the 50 milliseconds epoll timeout of the server.
+func Default() *Config {
+	return &Config{
+		Port:           6379,
+		Bind:           []string{"0.0.0.0"},
+		TcpBacklog:     1024,
+		MaxClients:     10000,
+		Timeout:        0,
+		Hz:             20,
+		LogLevel:       "notice",
+		LogFile:        "",
+		Dir:            ".",
+		DbFilename:     "dump.rdb",
+		AppendOnly:     false,
+		AppendFilename: "appendonly.aof",
+	}
+}
+
+// ConfigError reports the line of a config file, or the option of the command line, that cannot be applied
+type ConfigError struct {
+	Source string
+	Line   int
+	Text   string
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d: '%s': %s", e.Source, e.Line, e.Text, e.Err.Error())
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+type directive struct {
+	get func(cfg *Config) string
+	set func(cfg *Config, args []string) error
+}
+
+func intDirective(field func(cfg *Config) *int, min int, max int) directive {
+	return directive{
+		get: func(cfg *Config) string {
+			return strconv.Itoa(*field(cfg))
+		},
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			v, err := strconv.Atoi(args[0])
+			if err != nil {
+				return errors.New("argument couldn't be parsed into an integer")
+			}
+			if v < min || v > max {
+				return fmt.Errorf("argument must be between %d and %d inclusive", min, max)
+			}
+			*field(cfg) = v
+			return nil
+		},
+	}
+}
+
+func stringDirective(field func(cfg *Config) *string) directive {
+	return directive{
+		get: func(cfg *Config) string {
+			return *field(cfg)
+		},
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			*field(cfg) = args[0]
+			return nil
+		},
+	}
+}
+
+var directives = map[string]directive{
+	"port":        intDirective(func(cfg *Config) *int { return &cfg.Port }, 1, 65535),
+	"tcp-backlog": intDirective(func(cfg *Config) *int { return &cfg.TcpBacklog }, 1, 65535),
+	"maxclients":  intDirective(func(cfg *Config) *int { return &cfg.MaxClients }, 1, 1<<20),
+	"timeout":     intDirective(func(cfg *Config) *int { return &cfg.Timeout }, 0, 1<<31-1),
+	"hz":          intDirective(func(cfg *Config) *int { return &cfg.Hz }, 1, 500),
+	"logfile":     stringDirective(func(cfg *Config) *string { return &cfg.LogFile }),
+	"dir":         stringDirective(func(cfg *Config) *string { return &cfg.Dir }),
+	"dbfilename": {
+		get: func(cfg *Config) string { return cfg.DbFilename },
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			if filepath.Base(args[0]) != args[0] {
+				return errors.New("dbfilename can't be a path, just a filename")
+			}
+			cfg.DbFilename = args[0]
+			return nil
+		},
+	},
+	"appendfilename": {
+		get: func(cfg *Config) string { return cfg.AppendFilename },
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			if filepath.Base(args[0]) != args[0] {
+				return errors.New("appendfilename can't be a path, just a filename")
+			}
+			cfg.AppendFilename = args[0]
+			return nil
+		},
+	},
+	"appendonly": {
+		get: func(cfg *Config) string {
+			if cfg.AppendOnly {
+				return "yes"
+			}
+			return "no"
+		},
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			switch strings.ToLower(args[0]) {
+			case "yes":
+				cfg.AppendOnly = true
+			case "no":
+				cfg.AppendOnly = false
+			default:
+				return errors.New("argument must be 'yes' or 'no'")
+			}
+			return nil
+		},
+	},
+	"loglevel": {
+		get: func(cfg *Config) string { return cfg.LogLevel },
+		set: func(cfg *Config, args []string) error {
+			if len(args) != 1 {
+				return ErrWrongNumArgs
+			}
+			level := strings.ToLower(args[0])
+			for _, l := range LogLevels {
+				if l == level {
+					cfg.LogLevel = level
+					return nil
+				}
+			}
+			return fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(LogLevels, ", "))
+		},
+	},
+	"bind": {
+		get: func(cfg *Config) string { return strings.Join(cfg.Bind, " ") },
+		set: func(cfg *Config, args []string) error {
+			if len(args) == 0 {
+				return ErrWrongNumArgs
+			}
+			for _, addr := range args {
+				if _, _, err := ParseBindAddr(addr); err != nil {
+					return err
+				}
+			}
+			cfg.Bind = args
+			return nil
+		},
+	},
+}
+
+// unsupportedDirectives are directives of Redis that are not supported, but accepted with warnings so that
+// existing redis.conf files can be loaded
+var unsupportedDirectives = map[string]bool{
+	"protected-mode": true, "daemonize": true, "supervised": true, "pidfile": true, "databases": true,
+	"always-show-logo": true, "set-proc-title": true, "proc-title-template": true, "locale-collate": true,
+	"unixsocket": true, "unixsocketperm": true, "tcp-keepalive": true, "syslog-enabled": true,
+	"syslog-ident": true, "syslog-facility": true, "crash-log-enabled": true, "crash-memcheck-enabled": true,
+	"save": true, "stop-writes-on-bgsave-error": true, "rdbcompression": true, "rdbchecksum": true,
+	"rdb-del-sync-files": true, "sanitize-dump-payload": true, "rdb-save-incremental-fsync": true,
+	"appenddirname": true, "appendfsync": true, "no-appendfsync-on-rewrite": true,
+	"auto-aof-rewrite-percentage": true, "auto-aof-rewrite-min-size": true, "aof-load-truncated": true,
+	"aof-use-rdb-preamble": true, "aof-timestamp-enabled": true, "aof-rewrite-incremental-fsync": true,
+	"replicaof": true, "slaveof": true, "masterauth": true, "masteruser": true, "replica-serve-stale-data": true,
+	"replica-read-only": true, "repl-diskless-sync": true, "repl-diskless-sync-delay": true,
+	"repl-diskless-sync-max-replicas": true, "repl-diskless-load": true, "repl-ping-replica-period": true,
+	"repl-timeout": true, "repl-disable-tcp-nodelay": true, "repl-backlog-size": true, "repl-backlog-ttl": true,
+	"replica-priority": true, "replica-announce-ip": true, "replica-announce-port": true,
+	"min-replicas-to-write": true, "min-replicas-max-lag": true, "replica-lazy-flush": true,
+	"replica-ignore-maxmemory": true, "requirepass": true, "aclfile": true, "acllog-max-len": true,
+	"user": true, "rename-command": true, "enable-protected-configs": true, "enable-debug-command": true,
+	"enable-module-command": true, "loadmodule": true, "maxmemory": true, "maxmemory-policy": true,
+	"maxmemory-samples": true, "maxmemory-eviction-tenacity": true, "active-expire-effort": true,
+	"lazyfree-lazy-eviction": true, "lazyfree-lazy-expire": true, "lazyfree-lazy-server-del": true,
+	"lazyfree-lazy-user-del": true, "lazyfree-lazy-user-flush": true, "io-threads": true,
+	"io-threads-do-reads": true, "oom-score-adj": true, "oom-score-adj-values": true, "disable-thp": true,
+	"lua-time-limit": true, "busy-reply-threshold": true, "cluster-enabled": true, "cluster-config-file": true,
+	"cluster-node-timeout": true, "slowlog-log-slower-than": true, "slowlog-max-len": true,
+	"latency-monitor-threshold": true, "latency-tracking": true, "latency-tracking-info-percentiles": true,
+	"notify-keyspace-events": true, "hash-max-listpack-entries": true, "hash-max-listpack-value": true,
+	"hash-max-ziplist-entries": true, "hash-max-ziplist-value": true, "list-max-listpack-size": true,
+	"list-max-ziplist-size": true, "list-compress-depth": true, "set-max-intset-entries": true,
+	"set-max-listpack-entries": true, "set-max-listpack-value": true, "zset-max-listpack-entries": true,
+	"zset-max-listpack-value": true, "zset-max-ziplist-entries": true, "zset-max-ziplist-value": true,
+	"hll-sparse-max-bytes": true, "stream-node-max-bytes": true, "stream-node-max-entries": true,
+	"activerehashing": true, "activedefrag": true, "client-output-buffer-limit": true,
+	"client-query-buffer-limit": true, "proto-max-bulk-len": true, "dynamic-hz": true,
+	"jemalloc-bg-thread": true, "lfu-log-factor": true, "lfu-decay-time": true, "shutdown-timeout": true,
+	"shutdown-on-sigint": true, "shutdown-on-sigterm": true, "tls-port": true, "tls-cert-file": true,
+	"tls-key-file": true, "tls-ca-cert-file": true, "ignore-warnings": true,
+}
+
+/*
+ParseBindAddr parses a bind address into an IPv4 address. Same as Redis, "*" binds all addresses, and addresses
+prefixed by "-" are optional, so that the server still starts if they cannot be bound. Only IPv4 is supported,
+so IPv6 addresses are accepted only if they are optional, and `ok` is false for them.
+*/
+func ParseBindAddr(addr string) (ip [4]byte, ok bool, err error) {
+	optional := strings.HasPrefix(addr, "-")
+	addr = strings.TrimPrefix(addr, "-")
+	if addr == "*" {
+		return ip, true, nil
+	}
+	if addr == "::*" {
+		return ip, false, nil
+	}
+
+	parsed := net.ParseIP(addr)
+	if parsed == nil {
+		return ip, false, fmt.Errorf("invalid bind address '%s'", addr)
+	}
+	if parsed.To4() == nil {
+		if optional {
+			return ip, false, nil
+		}
+		return ip, false, fmt.Errorf("IPv6 bind address '%s' is not supported", addr)
+	}
+	copy(ip[:], parsed.To4())
+	return ip, true, nil
+}
+
+// Set applies a directive, which is case-insensitive. Returns ErrNotSupported for directives of Redis that are ignored.
+func (cfg *Config) Set(name string, args []string) error {
+	d, found := directives[strings.ToLower(name)]
+	if !found {
+		if unsupportedDirectives[strings.ToLower(name)] {
+			return ErrNotSupported
+		}
+		return ErrUnknownDirective
+	}
+	return d.set(cfg, args)
+}
+
+func (cfg *Config) Get(name string) (string, bool) {
+	d, found := directives[strings.ToLower(name)]
+	if !found {
+		return "", false
+	}
+	return d.get(cfg), true
+}
+
+// Names returns the names of all directives in order
+func Names() []string {
+	names := make([]string, 0, len(directives))
+	for name := range directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+SplitArgs splits a config line into arguments like `sdssplitargs` of Redis. Arguments are separated by spaces, and
+can be quoted by double quotes, which support escapes like "\n" and "\x41", or by single quotes, which only support
+"\'". Quoted arguments must be followed by spaces or the end of the line.
+*/
+func SplitArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t' || line[i] == '\r' || line[i] == '\n') {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var (
+			arg    strings.Builder
+			quote  byte
+			closed bool
+		)
+		for start := i; i < len(line); i++ {
+			ch := line[i]
+			if quote == 0 {
+				if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' {
+					break
+				}
+				if (ch == '"' || ch == '\'') && i == start {
+					quote = ch
+					continue
+				}
+				arg.WriteByte(ch)
+				continue
+			}
+
+			if ch == quote {
+				if i+1 < len(line) && line[i+1] != ' ' && line[i+1] != '\t' {
+					return nil, ErrUnbalanced
+				}
+				i++
+				closed = true
+				break
+			}
+			if ch == '\\' && i+1 < len(line) {
+				next := line[i+1]
+				if quote == '\'' {
+					if next == '\'' {
+						ch = next
+						i++
+					}
+				} else if next == 'x' && i+3 < len(line) {
+					if b, err := strconv.ParseUint(line[i+2:i+4], 16, 8); err == nil {
+						ch = byte(b)
+						i += 3
+					} else {
+						ch = next
+						i++
+					}
+				} else {
+					ch = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'b': '\b', 'a': '\a'}[next]
+					if ch == 0 {
+						ch = next
+					}
+					i++
+				}
+			}
+			arg.WriteByte(ch)
+		}
+		if quote != 0 && !closed {
+			return nil, ErrUnbalanced
+		}
+		args = append(args, arg.String())
+	}
+}
+
+/*
+LoadString applies the lines of a config file. Empty lines and lines starting with "#" are skipped, and
+"include path" applies another config file at its position. Directives of Redis that are not supported are
+recorded in `Warnings`.
+*/
+func (cfg *Config) LoadString(content string, source string) error {
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		args, err := SplitArgs(line)
+		if err == nil && strings.ToLower(args[0]) == "include" {
+			if len(args) != 2 {
+				err = ErrWrongNumArgs
+			} else {
+				err = cfg.LoadFile(args[1])
+			}
+			var configErr *ConfigError
+			if errors.As(err, &configErr) {
+				return err
+			}
+		} else if err == nil {
+			err = cfg.Set(args[0], args[1:])
+		}
+		if errors.Is(err, ErrNotSupported) {
+			cfg.Warnings = append(cfg.Warnings, (&ConfigError{Source: source, Line: i + 1, Text: line, Err: err}).Error())
+			continue
+		}
+		if err != nil {
+			return &ConfigError{Source: source, Line: i + 1, Text: line, Err: err}
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) LoadFile(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range cfg.includes {
+		if p == absPath {
+			return ErrIncludeLoop
+		}
+	}
+	cfg.includes = append(cfg.includes, absPath)
+	defer func() { cfg.includes = cfg.includes[:len(cfg.includes)-1] }()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return cfg.LoadString(string(content), path)
+}
+
+/*
+Load loads the configuration from the command line, in the same format as Redis:
+
+	toy-redis [/path/to/redis.conf] [--directive arg [arg ...] ...]
+
+Directives on the command line override the config file, and are reported by their positions in errors.
+*/
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		err := cfg.LoadFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+
+	for i := 0; i < len(args); {
+		if !strings.HasPrefix(args[i], "--") || len(args[i]) == 2 {
+			return nil, &ConfigError{Source: "command line", Line: i + 1, Text: args[i], Err: ErrUnknownDirective}
+		}
+		j := i + 1
+		for j < len(args) && !strings.HasPrefix(args[j], "--") {
+			j++
+		}
+		err := cfg.Set(args[i][2:], args[i+1:j])
+		if err != nil {
+			text := strings.Join(append([]string{args[i][2:]}, args[i+1:j]...), " ")
+			configErr := &ConfigError{Source: "command line", Line: i + 1, Text: text, Err: err}
+			if !errors.Is(err, ErrNotSupported) {
+				return nil, configErr
+			}
+			cfg.Warnings = append(cfg.Warnings, configErr.Error())
+		}
+		i = j
+	}
+	return cfg, nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := SplitArgs(`  bind 127.0.0.1   "-::1" `)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bind", "127.0.0.1", "-::1"}, args)
+
+	args, err = SplitArgs(`logfile "a b\n\x41" 'c\'d\n' ""`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"logfile", "a b\nA", `c'd\n`, ""}, args)
+
+	for _, line := range []string{`dir "abc`, `dir 'abc`, `dir "abc"def`} {
+		_, err = SplitArgs(line)
+		assert.Equal(t, ErrUnbalanced, err, line)
+	}
+}
+
+func TestParseBindAddr(t *testing.T) {
+	ip, ok, err := ParseBindAddr("127.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, [4]byte{127, 0, 0, 1}, ip)
+
+	ip, ok, err = ParseBindAddr("*")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, [4]byte{}, ip)
+
+	// IPv6 addresses are skipped if they are optional
+	for _, addr := range []string{"-::1", "::*", "-::*"} {
+		_, ok, err = ParseBindAddr(addr)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	}
+	_, _, err = ParseBindAddr("::1")
+	assert.EqualError(t, err, "IPv6 bind address '::1' is not supported")
+	_, _, err = ParseBindAddr("localhost")
+	assert.EqualError(t, err, "invalid bind address 'localhost'")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "redis.conf")
+	included := filepath.Join(dir, "included.conf")
+	assert.Nil(t, os.WriteFile(included, []byte("maxclients 100\n"), 0644))
+	assert.Nil(t, os.WriteFile(path, []byte(`
+# Comments and empty lines are skipped
+PORT 6380
+bind 127.0.0.1 -::1
+timeout 300
+loglevel WARNING
+logfile ""
+appendonly yes
+dbfilename "my dump.rdb"
+include `+included+`
+`), 0644))
+
+	cfg, err := Load([]string{path, "--port", "7000", "--hz", "10", "--bind", "*"})
+	assert.Nil(t, err)
+	assert.Equal(t, 7000, cfg.Port)
+	assert.Equal(t, []string{"*"}, cfg.Bind)
+	assert.Equal(t, 1024, cfg.TcpBacklog)
+	assert.Equal(t, 100, cfg.MaxClients)
+	assert.Equal(t, 300, cfg.Timeout)
+	assert.Equal(t, 10, cfg.Hz)
+	assert.Equal(t, "warning", cfg.LogLevel)
+	assert.Equal(t, "", cfg.LogFile)
+	assert.True(t, cfg.AppendOnly)
+	assert.Equal(t, "my dump.rdb", cfg.DbFilename)
+
+	v, found := cfg.Get("appendonly")
+	assert.True(t, found)
+	assert.Equal(t, "yes", v)
+	v, _ = cfg.Get("BIND")
+	assert.Equal(t, "*", v)
+	_, found = cfg.Get("save")
+	assert.False(t, found)
+	assert.Contains(t, Names(), "maxclients")
+
+	cfg, err = Load(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, Default(), cfg)
+
+	// Directives of Redis that are not supported are ignored with warnings
+	assert.Nil(t, os.WriteFile(path, []byte("port 6380\n\nsave 900 1\nprotected-mode yes\n"), 0644))
+	cfg, err = Load([]string{path, "--daemonize", "no"})
+	assert.Nil(t, err)
+	assert.Equal(t, 6380, cfg.Port)
+	assert.Equal(t, []string{
+		path + ":3: 'save 900 1': directive is not supported and is ignored",
+		path + ":4: 'protected-mode yes': directive is not supported and is ignored",
+		"command line:1: 'daemonize no': directive is not supported and is ignored",
+	}, cfg.Warnings)
+
+	// Errors point to the directives
+	assert.Nil(t, os.WriteFile(path, []byte("port 6380\n\nsava 900 1\n"), 0644))
+	_, err = Load([]string{path})
+	assert.EqualError(t, err, path+":3: 'sava 900 1': unknown directive")
+	assert.True(t, errors.Is(err, ErrUnknownDirective))
+
+	assert.Nil(t, os.WriteFile(included, []byte("hz 0\n"), 0644))
+	assert.Nil(t, os.WriteFile(path, []byte("include "+included+"\n"), 0644))
+	_, err = Load([]string{path})
+	assert.EqualError(t, err, included+":1: 'hz 0': argument must be between 1 and 500 inclusive")
+
+	// Config files cannot include themselves, directly or through other files
+	assert.Nil(t, os.WriteFile(included, []byte("include "+path+"\n"), 0644))
+	_, err = Load([]string{path})
+	assert.EqualError(t, err, included+":1: 'include "+path+"': config file includes itself")
+	assert.ErrorIs(t, err, ErrIncludeLoop)
+
+	assert.Nil(t, os.WriteFile(included, []byte("port 6381\n"), 0644))
+	assert.Nil(t, os.WriteFile(path, []byte("include "+included+"\ninclude "+included+"\n"), 0644))
+	cfg, err = Load([]string{path})
+	assert.Nil(t, err)
+	assert.Equal(t, 6381, cfg.Port)
+
+	assert.Nil(t, os.WriteFile(path, []byte("include "+filepath.Join(dir, "missing.conf")+"\n"), 0644))
+	_, err = Load([]string{path})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Load([]string{filepath.Join(dir, "missing.conf")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Load([]string{"--port", "abc"})
+	assert.EqualError(t, err, "command line:1: 'port abc': argument couldn't be parsed into an integer")
+	_, err = Load([]string{"--maxclients", "10", "--dbfilename", "/tmp/dump.rdb"})
+	assert.EqualError(t, err, "command line:3: 'dbfilename /tmp/dump.rdb': dbfilename can't be a path, just a filename")
+	_, err = Load([]string{"--loglevel"})
+	assert.EqualError(t, err, "command line:1: 'loglevel': wrong number of arguments")
+	_, err = Load([]string{"--loglevel", "loud"})
+	assert.EqualError(t, err, "command line:1: 'loglevel loud': argument(s) must be one of the following: debug, verbose, notice, warning, nothing")
+	_, err = Load([]string{"--appendonly", "maybe"})
+	assert.EqualError(t, err, "command line:1: 'appendonly maybe': argument must be 'yes' or 'no'")
+	_, err = Load([]string{"--bind", "::1"})
+	assert.EqualError(t, err, "command line:1: 'bind ::1': IPv6 bind address '::1' is not supported")
+	_, err = Load([]string{"--foo", "bar"})
+	assert.EqualError(t, err, "command line:1: 'foo bar': unknown directive")
+}
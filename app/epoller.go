@@ -7,14 +7,10 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-const (
-	EpollTimeoutDefault = 50
-)
-
 type Epoller struct {
-	ServerFd int
-	EpollFd  int
-	Conns    map[int]bool
+	ServerFds map[int]bool
+	EpollFd   int
+	Conns     map[int]bool
 }
 
 func (el *Epoller) AddListener(serverFd int) error {
@@ -29,7 +25,7 @@ func (el *Epoller) AddListener(serverFd int) error {
 	if err != nil {
 		return err
 	}
-	el.ServerFd = serverFd
+	el.ServerFds[serverFd] = true
 	return nil
 }
 
@@ -99,7 +95,8 @@ func MakeEpoller() (*Epoller, error) {
 		return nil, err
 	}
 	return &Epoller{
-		EpollFd: epollFd,
-		Conns:   make(map[int]bool),
+		ServerFds: make(map[int]bool),
+		EpollFd:   epollFd,
+		Conns:     make(map[int]bool),
 	}, nil
 }
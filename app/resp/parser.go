@@ -3,11 +3,22 @@ package resp
 import (
 	"bytes"
 	"errors"
+	"io"
+	"math"
 	"strconv"
 )
 
+// Same as Redis, bulk strings are limited by proto-max-bulk-len, and arrays by the size of an int
+const (
+	MaxBulkLen  = 512 * 1024 * 1024
+	MaxArrayLen = math.MaxInt32
+)
+
 var (
-	ErrInvalidArgs = errors.New("invalid args")
+	ErrInvalidArgs  = errors.New("invalid args")
+	ErrIncomplete   = errors.New("incomplete value")
+	ErrBulkLen      = errors.New("Protocol error: invalid bulk length")
+	ErrMultiBulkLen = errors.New("Protocol error: invalid multibulk length")
 )
 
 func readUntilLineBreak(r *bytes.Reader) ([]byte, error) {
@@ -19,7 +30,10 @@ func readUntilLineBreak(r *bytes.Reader) ([]byte, error) {
 			return nil, err
 		}
 		if c == '\r' {
-			r.ReadByte()
+			_, err = r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
 			break
 		}
 		buf = append(buf, c)
@@ -37,15 +51,23 @@ func parseBulkString(r *bytes.Reader) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	// Get actual string
-	rawStr, err := readUntilLineBreak(r)
+	if expectedNumBytes < 0 || expectedNumBytes > MaxBulkLen {
+		return "", ErrBulkLen
+	}
+	// Partial strings are not allocated until all their bytes arrive
+	if r.Len() < expectedNumBytes+2 {
+		return "", io.ErrUnexpectedEOF
+	}
+	// Get actual string. Strings are binary safe, so read by length instead of until a line break.
+	rawStr := make([]byte, expectedNumBytes+2)
+	_, err = io.ReadFull(r, rawStr)
 	if err != nil {
 		return "", err
 	}
-	if len(rawStr) != expectedNumBytes {
+	if rawStr[expectedNumBytes] != '\r' || rawStr[expectedNumBytes+1] != '\n' {
 		return "", ErrInvalidArgs
 	}
-	return string(rawStr), nil
+	return string(rawStr[:expectedNumBytes]), nil
 }
 
 func parseInteger(r *bytes.Reader) (int, error) {
@@ -68,12 +90,18 @@ func parseArray(r *bytes.Reader) ([]*RespValue, error) {
 		return nil, err
 	}
 
-	vals := make([]*RespValue, expectedLen)
+	if expectedLen < 0 || expectedLen > MaxArrayLen {
+		return nil, ErrMultiBulkLen
+	}
+
+	// Each value takes at least one byte, so the values of partial arrays are not allocated upfront
+	vals := make([]*RespValue, 0, min(expectedLen, r.Len()))
 	for i := 0; i < expectedLen; i++ {
-		vals[i], err = parseType(r)
+		val, err := parseType(r)
 		if err != nil {
 			return nil, err
 		}
+		vals = append(vals, val)
 	}
 	return vals, nil
 }
@@ -94,6 +122,8 @@ func parseType(r *bytes.Reader) (*RespValue, error) {
 		val.Int, err = parseInteger(r)
 	case TypeArrays:
 		val.Array, err = parseArray(r)
+	default:
+		err = ErrInvalidArgs
 	}
 	return &val, err
 }
@@ -106,3 +136,17 @@ func Parse(buf []byte) *RespValue {
 	}
 	return resp
 }
+
+// ParsePrefix parses a value at the start of `buf`, and returns the number of bytes consumed.
+// ErrIncomplete is returned if `buf` ends before the value does.
+func ParsePrefix(buf []byte) (*RespValue, int, error) {
+	r := bytes.NewReader(buf)
+	resp, err := parseType(r)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, 0, ErrIncomplete
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp, len(buf) - r.Len(), nil
+}
@@ -25,3 +25,97 @@ func TestParseInteger(t *testing.T) {
 	_, err = parseInteger(mockReader)
 	assert.Error(t, err)
 }
+
+func TestParsePrefix(t *testing.T) {
+	t.Log("Test parsing binary safe bulk strings")
+	req := []byte("*2\r\n$3\r\nGET\r\n$4\r\na\r\nb\r\n")
+	val, n, err := ParsePrefix(req)
+	assert.NoError(t, err)
+	assert.Equal(t, len(req), n)
+	assert.Equal(t, "a\r\nb", val.Array[1].BulkStr)
+
+	t.Log("Test parsing pipelined requests")
+	val, n, err = ParsePrefix(append(req, "*1\r\n$4\r\nPING\r\n"...))
+	assert.NoError(t, err)
+	assert.Equal(t, len(req), n)
+	assert.Equal(t, "GET", val.Array[0].BulkStr)
+
+	t.Log("Test parsing incomplete requests")
+	for i := 1; i < len(req); i++ {
+		_, _, err = ParsePrefix(req[:i])
+		assert.Equal(t, ErrIncomplete, err, i)
+	}
+
+	t.Log("Test parsing bulk strings with wrong lengths")
+	_, _, err = ParsePrefix([]byte("*1\r\n$2\r\nGET\r\n"))
+	assert.Equal(t, ErrInvalidArgs, err)
+
+	t.Log("Test parsing lengths out of range")
+	_, _, err = ParsePrefix([]byte("*1\r\n$9223372036854775807\r\nab\r\n"))
+	assert.Equal(t, ErrBulkLen, err)
+	_, _, err = ParsePrefix([]byte("*1\r\n$536870913\r\nab\r\n"))
+	assert.Equal(t, ErrBulkLen, err)
+	_, _, err = ParsePrefix([]byte("*1\r\n$-2\r\n"))
+	assert.Equal(t, ErrBulkLen, err)
+	_, _, err = ParsePrefix([]byte("*9223372036854775807\r\n$1\r\na\r\n"))
+	assert.Equal(t, ErrMultiBulkLen, err)
+	_, _, err = ParsePrefix([]byte("*-1\r\n"))
+	assert.Equal(t, ErrMultiBulkLen, err)
+
+	t.Log("Test parsing large lengths that are valid but incomplete")
+	_, _, err = ParsePrefix([]byte("*1\r\n$536870912\r\nab\r\n"))
+	assert.Equal(t, ErrIncomplete, err)
+	_, _, err = ParsePrefix([]byte("*2147483647\r\n$1\r\na\r\n"))
+	assert.Equal(t, ErrIncomplete, err)
+}
+
+// parseReads parses frames from reads the same way as the server, keeping bytes that are not parsed yet
+// until the next read
+func parseReads(t *testing.T, reads ...[]byte) []*RespValue {
+	var (
+		pending []byte
+		res     []*RespValue
+	)
+	for _, read := range reads {
+		pending = append(pending, read...)
+		for len(pending) > 0 {
+			val, n, err := ParsePrefix(pending)
+			if err == ErrIncomplete {
+				break
+			}
+			assert.NoError(t, err)
+			res = append(res, val)
+			pending = pending[n:]
+		}
+	}
+	assert.Empty(t, pending)
+	return res
+}
+
+func TestParsePrefixSplitFrames(t *testing.T) {
+	t.Log("Test parsing pipelined frames split at every position")
+	set := []byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$3\r\na\r\n\r\n")
+	get := []byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")
+	buf := append(append([]byte{}, set...), get...)
+	for i := 1; i < len(buf); i++ {
+		res := parseReads(t, buf[:i], buf[i:])
+		assert.Equal(t, 2, len(res), i)
+		assert.Equal(t, "a\r\n", res[0].Array[2].BulkStr, i)
+		assert.Equal(t, "GET", res[1].Array[0].BulkStr, i)
+	}
+
+	t.Log("Test parsing frames larger than a read")
+	value := bytes.Repeat([]byte("x"), 40*1024)
+	buf = append([]byte("*2\r\n$3\r\nSET\r\n$40960\r\n"), value...)
+	buf = append(append(buf, "\r\n"...), get...)
+	for _, readSize := range []int{1, 7, 16 * 1024} {
+		reads := [][]byte{}
+		for start := 0; start < len(buf); start += readSize {
+			reads = append(reads, buf[start:min(start+readSize, len(buf))])
+		}
+		res := parseReads(t, reads...)
+		assert.Equal(t, 2, len(res), readSize)
+		assert.Equal(t, string(value), res[0].Array[1].BulkStr, readSize)
+		assert.Equal(t, "GET", res[1].Array[0].BulkStr, readSize)
+	}
+}
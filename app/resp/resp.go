@@ -18,6 +18,7 @@ type RespValue struct {
 	SimpleStr     string
 	BulkStr       string
 	IsNullBulkStr bool
+	IsNullArray   bool
 	Int           int
 	Array         []*RespValue
 }
@@ -49,6 +50,11 @@ func (rv RespValue) writeBulkStrings(buf *bytes.Buffer) {
 }
 
 func (rv RespValue) writeArrays(buf *bytes.Buffer) {
+	if rv.IsNullArray {
+		buf.WriteString(strconv.Itoa(-1))
+		buf.WriteString("\r\n")
+		return
+	}
 	buf.WriteString(strconv.Itoa(len(rv.Array)))
 	buf.WriteString("\r\n")
 
@@ -83,6 +89,10 @@ func MakeInt(v int) *RespValue {
 	return &RespValue{DataType: TypeIntegers, Int: v}
 }
 
+func MakeSimpleString(msg string) *RespValue {
+	return &RespValue{DataType: TypeSimpleStrings, SimpleStr: msg}
+}
+
 func MakeBulkString(msg string) *RespValue {
 	return &RespValue{DataType: TypeBulkStrings, BulkStr: msg}
 }
@@ -91,6 +101,14 @@ func MakeNilBulkString() *RespValue {
 	return &RespValue{DataType: TypeBulkStrings, IsNullBulkStr: true}
 }
 
+func MakeArray(arr []*RespValue) *RespValue {
+	return &RespValue{DataType: TypeArrays, Array: arr}
+}
+
+func MakeNilArray() *RespValue {
+	return &RespValue{DataType: TypeArrays, IsNullArray: true}
+}
+
 func MakeErorr(msg string) *RespValue {
 	return &RespValue{DataType: TypeSimpleErrors, SimpleStr: msg}
 }
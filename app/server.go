@@ -1,32 +1,55 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/stanleygy/toy-redis/app/cmdexec"
+	"github.com/stanleygy/toy-redis/app/config"
 	"github.com/stanleygy/toy-redis/app/resp"
 	"golang.org/x/sys/unix"
 )
 
+var errMaxClients = []byte("-ERR max number of clients reached\r\n")
+
 func initListeners() *Epoller {
 	epoller, err := MakeEpoller()
 	if err != nil {
 		panic(err)
 	}
 
-	serverFd, err := createServerFd()
-	if err != nil {
-		panic(err)
-	}
+	// Same as Redis, the server fails to start if a bind address cannot be bound, unless it is optional
+	for _, bindAddr := range config.Current.Bind {
+		addr, ok, _ := config.ParseBindAddr(bindAddr)
+		if !ok {
+			log.Println("Skipping unsupported bind address:", bindAddr)
+			continue
+		}
+		serverFd, err := createServerFd(addr, config.Current.Port, config.Current.TcpBacklog)
+		if err != nil {
+			if strings.HasPrefix(bindAddr, "-") {
+				log.Println("Skipping bind address", bindAddr, "-", err.Error())
+				continue
+			}
+			panic(fmt.Errorf("binding %s:%d: %w", bindAddr, config.Current.Port, err))
+		}
 
-	err = epoller.AddListener(serverFd)
-	if err != nil {
-		panic(err)
+		err = epoller.AddListener(serverFd)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(epoller.ServerFds) == 0 {
+		panic("no bind address could be bound")
 	}
 	return epoller
 }
 
-func createServerFd() (int, error) {
+func createServerFd(addr [4]byte, port int, backlog int) (int, error) {
 	// Create a non-blocking fd for requests
 	serverFd, err := unix.Socket(unix.AF_INET, unix.O_NONBLOCK|unix.SOCK_STREAM, 0)
 	if err != nil {
@@ -39,31 +62,71 @@ func createServerFd() (int, error) {
 
 	// Bind server fd to addr and port
 	serverAddr := &unix.SockaddrInet4{
-		Port: 6379,
-		Addr: [4]byte{0, 0, 0, 0},
+		Port: port,
+		Addr: addr,
 	}
 	err = unix.Bind(serverFd, serverAddr)
 	if err != nil {
 		return 0, err
 	}
-	err = unix.Listen(serverFd, 1024)
+	err = unix.Listen(serverFd, backlog)
 	if err != nil {
 		return 0, err
 	}
 	return serverFd, nil
 }
 
-func processConnAcceptRequest(epoller *Epoller) {
-	connfd, _, err := unix.Accept(epoller.ServerFd)
+func processConnAcceptRequest(serverFd int, epoller *Epoller) {
+	connfd, _, err := unix.Accept(serverFd)
 	if err != nil {
 		log.Println(err.Error())
 		return
 	}
+	if len(epoller.Conns) >= config.Current.MaxClients {
+		unix.Write(connfd, errMaxClients)
+		unix.Close(connfd)
+		return
+	}
 	epoller.AddConn(connfd)
+	connLastActive[connfd] = time.Now()
+}
+
+// Requests might span multiple reads, so bytes that are not parsed yet are kept per connection
+var connReadBuffers = make(map[int][]byte)
+
+// The time of the last request of each connection, for closing idle connections
+var connLastActive = make(map[int]time.Time)
+
+func closeConn(connfd int, epoller *Epoller) error {
+	delete(connReadBuffers, connfd)
+	delete(connLastActive, connfd)
+	cmdexec.RemoveGeofenceSubscriber(connfd)
+	cmdexec.RemoveBlockedClient(connfd)
+	return epoller.RemoveConn(connfd)
+}
+
+// closeIdleConns closes connections idle for longer than the `timeout` config. Blocked clients and geofence
+// subscribers wait for the server, so they are not idle.
+func closeIdleConns(epoller *Epoller) {
+	if config.Current.Timeout == 0 {
+		return
+	}
+	deadline := time.Now().Add(-time.Duration(config.Current.Timeout) * time.Second)
+	for connfd, lastActive := range connLastActive {
+		if lastActive.After(deadline) || cmdexec.IsClientBlocked(connfd) || cmdexec.IsGeofenceSubscriber(connfd) {
+			continue
+		}
+		err := closeConn(connfd, epoller)
+		if err != nil {
+			log.Println("Error closing connection: ", err.Error())
+		} else {
+			log.Println("Closing idle client:", connfd)
+		}
+	}
 }
 
 func processConnReadRequest(connfd int, epoller *Epoller) {
-	buf := make([]byte, 1024)
+	buf := make([]byte, 16*1024)
 
 	numRead, err := unix.Read(connfd, buf)
 	if err != nil {
@@ -72,7 +135,7 @@ func processConnReadRequest(connfd int, epoller *Epoller) {
 	}
 	if numRead == 0 {
 		// Connection closed for this socket
-		err := epoller.RemoveConn(connfd)
+		err := closeConn(connfd, epoller)
 		if err != nil {
 			log.Println("Error closing connection: ", err.Error())
 		} else {
@@ -80,13 +143,36 @@ func processConnReadRequest(connfd int, epoller *Epoller) {
 		}
 		return
 	}
+	connLastActive[connfd] = time.Now()
+
+	pending := append(connReadBuffers[connfd], buf[:numRead]...)
+	for len(pending) > 0 {
+		clientRequest, numParsed, err := resp.ParsePrefix(pending)
+		if err == resp.ErrIncomplete {
+			break
+		}
+		if err != nil {
+			log.Println("Error parsing request: ", err.Error())
+			pending = nil
+			break
+		}
+		pending = pending[numParsed:]
+		if clientRequest.DataType != resp.TypeArrays || len(clientRequest.Array) == 0 {
+			continue
+		}
+
+		c := &cmdexec.ClientInfo{
+			ConnFd:        connfd,
+			ClientRequest: clientRequest,
+		}
+		cmdexec.Execute(c, clientRequest)
+	}
 
-	clientRequest := resp.Parse(buf)
-	c := &cmdexec.ClientInfo{
-		ConnFd:        connfd,
-		ClientRequest: clientRequest,
+	if len(pending) == 0 {
+		delete(connReadBuffers, connfd)
+	} else {
+		connReadBuffers[connfd] = pending
 	}
-	cmdexec.Execute(c, clientRequest)
 }
 
 func processPostCmdExecutionEvents() {
@@ -108,11 +194,12 @@ func startServer() {
 	epoller := initListeners()
 	cmdexec.InitRedisDb()
 	cmdexec.MakeBlockList()
+	cmdexec.MakeGeofenceList()
 
 	for {
 		// Calculate time elapsed before next client timeout event
 		nextClientTimeout := cmdexec.GetEarliestTimeoutUnix()
-		epollTimeout := EpollTimeoutDefault
+		epollTimeout := 1000 / config.Current.Hz
 		if nextClientTimeout != -1 && nextClientTimeout < epollTimeout {
 			epollTimeout = nextClientTimeout
 		}
@@ -124,10 +211,11 @@ func startServer() {
 			continue
 		}
 		cmdexec.HandleBlockedClientsTimeout()
+		closeIdleConns(epoller)
 
 		for _, ev := range events {
-			if ev.Fd == int32(epoller.ServerFd) {
-				processConnAcceptRequest(epoller)
+			if epoller.ServerFds[int(ev.Fd)] {
+				processConnAcceptRequest(int(ev.Fd), epoller)
 			} else {
 				processConnReadRequest(int(ev.Fd), epoller)
 			}
@@ -138,6 +226,33 @@ func startServer() {
 	}
 }
 
+// initLogger writes logs to the `logfile` config, or to stderr if it is empty, and discards them at the "nothing" level
+func initLogger() {
+	if config.Current.LogLevel == "nothing" {
+		log.SetOutput(io.Discard)
+		return
+	}
+	if config.Current.LogFile == "" {
+		return
+	}
+	f, err := os.OpenFile(config.Current.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		panic(err)
+	}
+	log.SetOutput(f)
+}
+
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "*** FATAL CONFIG ERROR ***")
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	config.Current = cfg
+	initLogger()
+	for _, warning := range cfg.Warnings {
+		log.Println("Warning:", warning)
+	}
 	startServer()
 }